@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// baseProfile is loaded before every named profile.
+const baseProfile = "base"
+
+type Fixtures struct {
+	Permissions []PermissionFixture `json:"permissions" yaml:"permissions"`
+	Roles       []RoleFixture       `json:"roles" yaml:"roles"`
+	Users       []UserFixture       `json:"users" yaml:"users"`
+	Content     []ContentFixture    `json:"content" yaml:"content"`
+}
+
+type PermissionFixture struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+}
+
+type RoleFixture struct {
+	Name        string   `json:"name" yaml:"name"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+}
+
+// UserFixture describes a user keyed by email. Give either Password, which
+// is hashed when seeding, or a bcrypt PasswordHash; with neither, a random
+// password is generated and printed once when the user is created.
+type UserFixture struct {
+	Username     string `json:"username" yaml:"username"`
+	Email        string `json:"email" yaml:"email"`
+	Name         string `json:"name" yaml:"name"`
+	Role         string `json:"role" yaml:"role"`
+	Password     string `json:"password" yaml:"password"`
+	PasswordHash string `json:"password_hash" yaml:"password_hash"`
+	PhoneNumber  string `json:"phone_number" yaml:"phone_number"`
+	Address      string `json:"address" yaml:"address"`
+}
+
+// ContentFixture upserts rows into a CMS content table by the Key columns.
+// String values of the form "@user:<email>" or "@role:<name>" are replaced
+// with the ID of that user or role.
+type ContentFixture struct {
+	Table string                   `json:"table" yaml:"table"`
+	Key   []string                 `json:"key" yaml:"key"`
+	Rows  []map[string]interface{} `json:"rows" yaml:"rows"`
+}
+
+// LoadProfile reads every .yaml, .yml and .json file from dir/base and
+// then dir/<profile>, in name order. Later entries override earlier ones
+// with the same natural key.
+func LoadProfile(dir, profile string) (*Fixtures, []string, error) {
+	profileDir := filepath.Join(dir, profile)
+	if info, err := os.Stat(profileDir); err != nil || !info.IsDir() {
+		return nil, nil, fmt.Errorf("unknown profile %q: %s is not a directory", profile, profileDir)
+	}
+
+	merged := &Fixtures{}
+	var loaded []string
+	for _, name := range []string{baseProfile, profile} {
+		if name == baseProfile && profile == baseProfile {
+			continue
+		}
+		files, err := fixtureFiles(filepath.Join(dir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, file := range files {
+			f, err := decodeFile(file)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", file, err)
+			}
+			merged.merge(f)
+			loaded = append(loaded, file)
+		}
+	}
+	if err := merged.validate(); err != nil {
+		return nil, nil, err
+	}
+	return merged, loaded, nil
+}
+
+func fixtureFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func decodeFile(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixtures
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (f *Fixtures) merge(other *Fixtures) {
+	for _, p := range other.Permissions {
+		f.Permissions = upsertBy(f.Permissions, p, func(x PermissionFixture) string { return x.Name })
+	}
+	for _, r := range other.Roles {
+		f.Roles = upsertBy(f.Roles, r, func(x RoleFixture) string { return x.Name })
+	}
+	for _, u := range other.Users {
+		f.Users = upsertBy(f.Users, u, func(x UserFixture) string { return strings.ToLower(x.Email) })
+	}
+	f.Content = append(f.Content, other.Content...)
+}
+
+func upsertBy[T any](items []T, item T, key func(T) string) []T {
+	for i := range items {
+		if key(items[i]) == key(item) {
+			items[i] = item
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+func (f *Fixtures) validate() error {
+	var errs []error
+	for _, p := range f.Permissions {
+		if p.Name == "" {
+			errs = append(errs, errors.New("permission without a name"))
+		}
+	}
+	for _, r := range f.Roles {
+		if r.Name == "" {
+			errs = append(errs, errors.New("role without a name"))
+		}
+	}
+	for _, u := range f.Users {
+		switch {
+		case u.Email == "" || u.Username == "" || u.Role == "":
+			errs = append(errs, fmt.Errorf("user %q needs an email, username and role", u.Email))
+		case u.Password != "" && u.PasswordHash != "":
+			errs = append(errs, fmt.Errorf("user %s sets both password and password_hash", u.Email))
+		case u.PasswordHash != "":
+			if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+				errs = append(errs, fmt.Errorf("user %s: password_hash is not a bcrypt hash", u.Email))
+			}
+		}
+	}
+	for _, c := range f.Content {
+		if !identifier.MatchString(c.Table) || len(c.Key) == 0 {
+			errs = append(errs, fmt.Errorf("content for table %q needs a valid table name and key", c.Table))
+			continue
+		}
+		for _, col := range c.Key {
+			if !identifier.MatchString(col) {
+				errs = append(errs, fmt.Errorf("content for table %s has an invalid key column %q", c.Table, col))
+			}
+		}
+		for i, row := range c.Rows {
+			for _, col := range c.Key {
+				if _, ok := row[col]; !ok {
+					errs = append(errs, fmt.Errorf("content row %d for table %s is missing key column %s", i, c.Table, col))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
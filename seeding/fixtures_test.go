@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProfileMergesBaseAndProfile(t *testing.T) {
+	for _, profile := range []string{"dev", "demo", "test"} {
+		f, files, err := LoadProfile("fixtures", profile)
+		require.NoError(t, err, profile)
+		require.NotEmpty(t, files)
+		require.NotEmpty(t, f.Roles)
+		require.NotEmpty(t, f.Users)
+	}
+}
+
+func TestLoadProfileOverridesByNaturalKey(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	}
+	write("base/roles.yaml", "roles:\n  - name: Editor\n    permissions: [a]\n")
+	write("qa/roles.json", `{"roles": [{"name": "Editor", "permissions": ["a", "b"]}]}`)
+
+	f, _, err := LoadProfile(dir, "qa")
+	require.NoError(t, err)
+	require.Len(t, f.Roles, 1)
+	require.Equal(t, []string{"a", "b"}, f.Roles[0].Permissions)
+}
+
+func TestLoadProfileRejectsInvalidFixtures(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "qa"), 0o755))
+
+	_, _, err := LoadProfile(dir, "missing")
+	require.Error(t, err)
+
+	cases := map[string]string{
+		"unknown field": "users:\n  - emial: a@b.c\n",
+		"bad hash":      "users:\n  - {username: a, email: a@b.c, role: R, password_hash: plain}\n",
+		"bad table":     "content:\n  - {table: \"x; drop\", key: [id], rows: [{id: 1}]}\n",
+		"missing key":   "content:\n  - {table: articles, key: [slug], rows: [{title: t}]}\n",
+	}
+	for name, body := range cases {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "qa", "f.yaml"), []byte(body), 0o644))
+		_, _, err := LoadProfile(dir, "qa")
+		require.Error(t, err, name)
+	}
+}
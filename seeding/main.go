@@ -1,53 +1,20 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/google/uuid"
+	"io/fs"
+	"log"
+	"os"
+	"time"
+
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"log"
-	"os"
-	"time"
 )
 
-type Role struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
-
-type User struct {
-	ID               uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Username         string    `gorm:"type:varchar(255);not null;unique" json:"username"`
-	Password         string    `gorm:"type:varchar(255);not null" json:"-"`
-	Email            string    `gorm:"type:varchar(255);not null;unique" json:"email"`
-	Name             string    `gorm:"type:varchar(255)" json:"name"`
-	RoleID           uuid.UUID `gorm:"type:uuid;not null" json:"role_id"`
-	RegistrationDate time.Time `json:"registration_date"`
-	Address          string    `gorm:"type:text" json:"address"`
-	PhoneNumber      string    `gorm:"type:varchar(255)" json:"phone_number"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
-
-	Role Role `gorm:"foreignKey:RoleID" json:"role"`
-}
-
-func (r *Role) BeforeCreate(tx *gorm.DB) (err error) {
-	if r.ID == uuid.Nil {
-		r.ID = uuid.New()
-	}
-	return
-}
-
-func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
-	if u.ID == uuid.Nil {
-		u.ID = uuid.New()
-	}
-	return
-}
 func databaseConnection() (*gorm.DB, error) {
 	host := os.Getenv("DB_HOST")
 	port := os.Getenv("DB_PORT")
@@ -66,7 +33,7 @@ func databaseConnection() (*gorm.DB, error) {
 	)
 
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.Default.LogMode(logger.Warn),
 	}
 
 	conn, err := gorm.Open(postgres.Open(dsn), gormConfig)
@@ -79,8 +46,8 @@ func databaseConnection() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
 	}
 
-	sqlDB.SetMaxOpenConns(25)
-	sqlDB.SetMaxIdleConns(25)
+	sqlDB.SetMaxOpenConns(5)
+	sqlDB.SetMaxIdleConns(5)
 	sqlDB.SetConnMaxLifetime(5 * time.Minute)
 
 	if err := sqlDB.Ping(); err != nil {
@@ -92,37 +59,58 @@ func databaseConnection() (*gorm.DB, error) {
 }
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+	defaultProfile := os.Getenv("SEED_PROFILE")
+	if defaultProfile == "" {
+		defaultProfile = "dev"
 	}
-	db, err := databaseConnection()
-	if err != nil {
-		panic(err.Error())
+	profile := flag.String("profile", defaultProfile, "fixture profile to load (dev, demo, test)")
+	fixturesDir := flag.String("fixtures", "fixtures", "directory holding the fixture profiles")
+	dryRun := flag.Bool("dry-run", false, "print the planned changes without committing them")
+	envFile := flag.String("env-file", ".env", "optional file to load environment variables from")
+	flag.Parse()
+
+	// The environment may come from the shell or the container instead.
+	if err := godotenv.Load(*envFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("Error loading %s: %v", *envFile, err)
 	}
 
-	// Drop existing tables first
-	err = db.Migrator().DropTable(&User{}, &Role{})
+	fixtures, files, err := LoadProfile(*fixturesDir, *profile)
 	if err != nil {
-		log.Printf("Warning: Could not drop tables: %v", err)
+		log.Fatalf("Invalid fixtures: %v", err)
+	}
+	for _, file := range files {
+		log.Printf("Loaded %s", file)
 	}
 
-	err = db.AutoMigrate(&Role{}, &User{})
+	db, err := databaseConnection()
 	if err != nil {
-		panic(err.Error())
+		log.Fatal(err)
 	}
 
-	// Seed data
-	roles := []Role{
-		{Name: "Administrator"},
-		{Name: "Customer"},
+	// The schema is owned by the auth-service migrations
+	// (`server migrate up`); seeding only upserts rows.
+	changes, err := NewSeeder(db, *dryRun).Run(fixtures)
+	if err != nil {
+		log.Fatalf("Seeding failed, nothing was committed: %v", err)
 	}
 
-	for _, role := range roles {
-		err = db.Create(&role).Error
-		if err != nil {
-			panic(err.Error())
+	counts := map[Action]int{}
+	for _, c := range changes {
+		counts[c.Action]++
+		if c.Action == ActionUnchanged {
+			continue
 		}
+		line := fmt.Sprintf("%-9s %-10s %s", c.Action, c.Kind, c.Key)
+		if c.Note != "" {
+			line += " (" + c.Note + ")"
+		}
+		fmt.Println(line)
 	}
 
-	log.Println("Successfully created")
+	summary := fmt.Sprintf("profile %s: %d created, %d updated, %d unchanged, %d skipped",
+		*profile, counts[ActionCreate], counts[ActionUpdate], counts[ActionUnchanged], counts[ActionSkip])
+	if *dryRun {
+		summary = "dry run, no changes committed; " + summary
+	}
+	log.Println(summary)
 }
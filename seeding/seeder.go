@@ -0,0 +1,378 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/content-management-system/auth-service/pkg/model"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+	ActionSkip      Action = "skip"
+)
+
+var (
+	identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	errDryRun  = errors.New("dry run")
+)
+
+type Change struct {
+	Kind   string
+	Key    string
+	Action Action
+	Note   string
+}
+
+// Seeder upserts fixtures by natural key. Everything runs in a single
+// transaction; a dry run performs the same work and then rolls it back,
+// so the reported plan accounts for dependencies between fixtures.
+type Seeder struct {
+	db      *gorm.DB
+	dryRun  bool
+	changes []Change
+}
+
+func NewSeeder(db *gorm.DB, dryRun bool) *Seeder {
+	return &Seeder{db: db, dryRun: dryRun}
+}
+
+func (s *Seeder) Run(f *Fixtures) ([]Change, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.seedPermissions(tx, f.Permissions); err != nil {
+			return err
+		}
+		if err := s.seedRoles(tx, f.Roles); err != nil {
+			return err
+		}
+		if err := s.seedUsers(tx, f.Users); err != nil {
+			return err
+		}
+		if err := s.seedContent(tx, f.Content); err != nil {
+			return err
+		}
+		if s.dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return s.changes, err
+}
+
+func (s *Seeder) record(kind, key string, action Action, note string) {
+	s.changes = append(s.changes, Change{Kind: kind, Key: key, Action: action, Note: note})
+}
+
+func (s *Seeder) seedPermissions(tx *gorm.DB, fixtures []PermissionFixture) error {
+	for _, f := range fixtures {
+		var p model.Permission
+		err := tx.Where("name = ?", f.Name).First(&p).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			p = model.Permission{Name: f.Name, Description: f.Description}
+			if err := tx.Create(&p).Error; err != nil {
+				return fmt.Errorf("create permission %s: %w", f.Name, err)
+			}
+			s.record("permission", f.Name, ActionCreate, "")
+		case err != nil:
+			return err
+		case p.Description != f.Description:
+			if err := tx.Model(&p).Update("description", f.Description).Error; err != nil {
+				return err
+			}
+			s.record("permission", f.Name, ActionUpdate, "description")
+		default:
+			s.record("permission", f.Name, ActionUnchanged, "")
+		}
+	}
+	return nil
+}
+
+func (s *Seeder) seedRoles(tx *gorm.DB, fixtures []RoleFixture) error {
+	for _, f := range fixtures {
+		var role model.Role
+		action := ActionUnchanged
+		err := tx.Preload("Permissions").Where("name = ?", f.Name).First(&role).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			role = model.Role{Name: f.Name}
+			if err := tx.Omit("Permissions").Create(&role).Error; err != nil {
+				return fmt.Errorf("create role %s: %w", f.Name, err)
+			}
+			action = ActionCreate
+		} else if err != nil {
+			return err
+		}
+
+		var perms []model.Permission
+		if len(f.Permissions) > 0 {
+			if err := tx.Where("name IN ?", f.Permissions).Find(&perms).Error; err != nil {
+				return err
+			}
+		}
+		if len(perms) != len(uniqueStrings(f.Permissions)) {
+			return fmt.Errorf("role %s references an unknown permission in %v", f.Name, f.Permissions)
+		}
+		current := make([]string, 0, len(role.Permissions))
+		for _, p := range role.Permissions {
+			current = append(current, p.Name)
+		}
+		note := ""
+		if !sameSet(current, f.Permissions) {
+			if err := tx.Model(&role).Association("Permissions").Replace(perms); err != nil {
+				return fmt.Errorf("set permissions of role %s: %w", f.Name, err)
+			}
+			note = "permissions"
+			if action == ActionUnchanged {
+				action = ActionUpdate
+			}
+		}
+		s.record("role", f.Name, action, note)
+	}
+	return nil
+}
+
+func (s *Seeder) seedUsers(tx *gorm.DB, fixtures []UserFixture) error {
+	for _, f := range fixtures {
+		email := strings.ToLower(strings.TrimSpace(f.Email))
+		var role model.Role
+		if err := tx.Where("name = ?", f.Role).First(&role).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("user %s references unknown role %q", email, f.Role)
+			}
+			return err
+		}
+
+		var user model.User
+		err := tx.Unscoped().Where("email_normalized = ?", email).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hash, note, err := s.newPassword(f)
+			if err != nil {
+				return fmt.Errorf("user %s: %w", email, err)
+			}
+			user = model.User{
+				Username:    f.Username,
+				Email:       email,
+				Name:        f.Name,
+				Password:    hash,
+				RoleID:      role.ID,
+				PhoneNumber: f.PhoneNumber,
+				Address:     f.Address,
+				Status:      "active",
+			}
+			if err := tx.Omit("Role").Create(&user).Error; err != nil {
+				return fmt.Errorf("create user %s: %w", email, err)
+			}
+			s.record("user", email, ActionCreate, note)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if user.Username != f.Username {
+			updates["username"] = f.Username
+		}
+		if user.Name != f.Name {
+			updates["name"] = f.Name
+		}
+		if user.RoleID != role.ID {
+			updates["role_id"] = role.ID
+		}
+		if user.PhoneNumber != f.PhoneNumber {
+			updates["phone_number"] = f.PhoneNumber
+		}
+		if user.Address != f.Address {
+			updates["address"] = f.Address
+		}
+		switch {
+		case f.PasswordHash != "" && f.PasswordHash != user.Password:
+			updates["password"] = f.PasswordHash
+		case f.Password != "" && bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(f.Password)) != nil:
+			hash, err := bcrypt.GenerateFromPassword([]byte(f.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return err
+			}
+			updates["password"] = string(hash)
+		}
+		if len(updates) == 0 {
+			s.record("user", email, ActionUnchanged, "")
+			continue
+		}
+		if err := tx.Unscoped().Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("update user %s: %w", email, err)
+		}
+		s.record("user", email, ActionUpdate, strings.Join(sortedKeys(updates), ", "))
+	}
+	return nil
+}
+
+// newPassword returns the hash to store for a new user, and a note that
+// carries a generated password so it can be handed to whoever needs it.
+func (s *Seeder) newPassword(f UserFixture) (string, string, error) {
+	if f.PasswordHash != "" {
+		return f.PasswordHash, "", nil
+	}
+	password, note := f.Password, ""
+	if password == "" {
+		if s.dryRun {
+			return "dry-run", "password will be generated", nil
+		}
+		buf := make([]byte, 12)
+		if _, err := rand.Read(buf); err != nil {
+			return "", "", err
+		}
+		password = base64.RawURLEncoding.EncodeToString(buf)
+		note = "generated password: " + password
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return string(hash), note, nil
+}
+
+func (s *Seeder) seedContent(tx *gorm.DB, fixtures []ContentFixture) error {
+	for _, f := range fixtures {
+		if !tx.Migrator().HasTable(f.Table) {
+			s.record("content", f.Table, ActionSkip, "table does not exist")
+			continue
+		}
+		for _, fixtureRow := range f.Rows {
+			row, err := s.resolveRow(tx, fixtureRow)
+			if err != nil {
+				return fmt.Errorf("content for %s: %w", f.Table, err)
+			}
+
+			where := map[string]interface{}{}
+			keyParts := make([]string, 0, len(f.Key))
+			for _, col := range f.Key {
+				where[col] = row[col]
+				keyParts = append(keyParts, fmt.Sprintf("%s=%v", col, row[col]))
+			}
+			key := f.Table + "(" + strings.Join(keyParts, ",") + ")"
+
+			var existing int64
+			if err := tx.Table(f.Table).Where(where).Count(&existing).Error; err != nil {
+				return err
+			}
+
+			keyCols := make([]clause.Column, 0, len(f.Key))
+			for _, col := range f.Key {
+				keyCols = append(keyCols, clause.Column{Name: col})
+			}
+			var updateCols []string
+			for col := range row {
+				if _, isKey := where[col]; !isKey {
+					updateCols = append(updateCols, col)
+				}
+			}
+			sort.Strings(updateCols)
+			conflict := clause.OnConflict{Columns: keyCols, DoNothing: len(updateCols) == 0}
+			if len(updateCols) > 0 {
+				conflict.DoUpdates = clause.AssignmentColumns(updateCols)
+			}
+			if err := tx.Table(f.Table).Clauses(conflict).Create(row).Error; err != nil {
+				return fmt.Errorf("upsert %s: %w", key, err)
+			}
+
+			action := ActionCreate
+			if existing > 0 {
+				action = ActionUpdate
+			}
+			s.record("content", key, action, "")
+		}
+	}
+	return nil
+}
+
+func (s *Seeder) resolveRow(tx *gorm.DB, in map[string]interface{}) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(in))
+	for col, value := range in {
+		if !identifier.MatchString(col) {
+			return nil, fmt.Errorf("invalid column name %q", col)
+		}
+		str, ok := value.(string)
+		if !ok {
+			out[col] = value
+			continue
+		}
+		switch {
+		case strings.HasPrefix(str, "@user:"):
+			var id uuid.UUID
+			email := strings.ToLower(strings.TrimPrefix(str, "@user:"))
+			if err := tx.Model(&model.User{}).Unscoped().Select("id").Where("email_normalized = ?", email).Scan(&id).Error; err != nil {
+				return nil, err
+			}
+			if id == uuid.Nil {
+				return nil, fmt.Errorf("column %s references unknown user %s", col, email)
+			}
+			out[col] = id
+		case strings.HasPrefix(str, "@role:"):
+			var id uuid.UUID
+			name := strings.TrimPrefix(str, "@role:")
+			if err := tx.Model(&model.Role{}).Select("id").Where("name = ?", name).Scan(&id).Error; err != nil {
+				return nil, err
+			}
+			if id == uuid.Nil {
+				return nil, fmt.Errorf("column %s references unknown role %s", col, name)
+			}
+			out[col] = id
+		default:
+			out[col] = str
+		}
+	}
+	return out, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sameSet(a, b []string) bool {
+	a, b = uniqueStrings(a), uniqueStrings(b)
+	if len(a) != len(b) {
+		return false
+	}
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/content-management-system/auth-service/pkg/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to the database in TEST_DATABASE_DSN, which must already
+// be migrated by the auth service. Tests are skipped when it is not set.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := conn.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	require.True(t, conn.Migrator().HasTable("users"), "run the auth service migrations against TEST_DATABASE_DSN first")
+	return conn
+}
+
+// testFixtures returns fixtures with names unique to this test, and removes
+// whatever they seeded when the test ends.
+func testFixtures(t *testing.T, conn *gorm.DB) *Fixtures {
+	suffix := uuid.NewString()[:8]
+	f := &Fixtures{
+		Permissions: []PermissionFixture{{Name: "seedtest." + suffix, Description: "seeder test"}},
+		Roles:       []RoleFixture{{Name: "SeedTest-" + suffix, Permissions: []string{"seedtest." + suffix}}},
+		Users: []UserFixture{
+			{Username: "seed-" + suffix, Email: "Seed-" + suffix + "@Example.com", Role: "SeedTest-" + suffix, Password: "Secret123!"},
+			{Username: "seed-gen-" + suffix, Email: "seed-gen-" + suffix + "@example.com", Role: "SeedTest-" + suffix},
+		},
+		Content: []ContentFixture{{Table: "seedtest_missing_" + suffix, Key: []string{"id"}, Rows: []map[string]interface{}{{"id": 1}}}},
+	}
+	t.Cleanup(func() {
+		var role model.Role
+		if conn.Where("name = ?", f.Roles[0].Name).First(&role).Error == nil {
+			conn.Unscoped().Where("role_id = ?", role.ID).Delete(&model.User{})
+			_ = conn.Model(&role).Association("Permissions").Clear()
+			conn.Delete(&role)
+		}
+		conn.Where("name = ?", f.Permissions[0].Name).Delete(&model.Permission{})
+	})
+	return f
+}
+
+func actions(changes []Change) map[string]Action {
+	out := map[string]Action{}
+	for _, c := range changes {
+		out[c.Kind+" "+c.Key] = c.Action
+	}
+	return out
+}
+
+func allActions(t *testing.T, changes []Change, want Action) {
+	t.Helper()
+	for key, got := range actions(changes) {
+		if strings.HasPrefix(key, "content ") {
+			require.Equal(t, ActionSkip, got, key)
+			continue
+		}
+		require.Equal(t, want, got, key)
+	}
+}
+
+func TestSeederIsIdempotent(t *testing.T) {
+	conn := testDB(t)
+	f := testFixtures(t, conn)
+
+	changes, err := NewSeeder(conn, false).Run(f)
+	require.NoError(t, err)
+	require.Len(t, changes, 5)
+	allActions(t, changes, ActionCreate)
+
+	changes, err = NewSeeder(conn, false).Run(f)
+	require.NoError(t, err)
+	allActions(t, changes, ActionUnchanged)
+
+	f.Permissions[0].Description = "changed"
+	f.Users[0].Name = "Seed Test"
+	changes, err = NewSeeder(conn, false).Run(f)
+	require.NoError(t, err)
+	got := actions(changes)
+	require.Equal(t, ActionUpdate, got["permission "+f.Permissions[0].Name])
+	require.Equal(t, ActionUpdate, got["user "+strings.ToLower(f.Users[0].Email)])
+	require.Equal(t, ActionUnchanged, got["role "+f.Roles[0].Name])
+}
+
+func TestSeederDryRunCommitsNothing(t *testing.T) {
+	conn := testDB(t)
+	f := testFixtures(t, conn)
+
+	planned, err := NewSeeder(conn, true).Run(f)
+	require.NoError(t, err)
+	allActions(t, planned, ActionCreate)
+	for _, c := range planned {
+		if c.Key == f.Users[1].Email {
+			require.Equal(t, "password will be generated", c.Note)
+		}
+	}
+
+	var count int64
+	require.NoError(t, conn.Model(&model.Role{}).Where("name = ?", f.Roles[0].Name).Count(&count).Error)
+	require.Zero(t, count)
+	require.NoError(t, conn.Model(&model.User{}).Unscoped().Where("email_normalized = ?", strings.ToLower(f.Users[0].Email)).Count(&count).Error)
+	require.Zero(t, count)
+
+	applied, err := NewSeeder(conn, false).Run(f)
+	require.NoError(t, err)
+	require.Equal(t, actions(planned), actions(applied), "a dry run reports the plan a real run applies")
+}
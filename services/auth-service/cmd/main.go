@@ -2,15 +2,27 @@ package main
 
 import (
 	"context"
+	"github.com/content-management-system/auth-service/internal/config"
 	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/internal/service/cognito"
 	"log"
+	"os"
 
 	"github.com/content-management-system/auth-service/internal/handler/rest/provider"
+	"github.com/content-management-system/auth-service/internal/repository"
+	awsConfig "github.com/content-management-system/auth-service/pkg/aws"
 	"github.com/content-management-system/auth-service/pkg/db"
 	"github.com/content-management-system/auth-service/pkg/fiber_app"
 	"github.com/content-management-system/auth-service/pkg/fx_app"
+	"github.com/content-management-system/auth-service/pkg/health"
 	"github.com/content-management-system/auth-service/pkg/logger"
-	"github.com/joho/godotenv"
+	"github.com/content-management-system/auth-service/pkg/mailer"
+	"github.com/content-management-system/auth-service/pkg/metrics"
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/content-management-system/auth-service/pkg/sms"
+	"github.com/content-management-system/auth-service/pkg/tracing"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/content-management-system/auth-service/pkg/validation"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/fx"
 )
@@ -28,15 +40,48 @@ func NewApp(
 	}
 }
 
+// httpOptions is provided here rather than by config.Module because the
+// HTTP server depends on the handlers, which depend on config.
+func httpOptions(cfg *config.Config) fiber_app.Options {
+	return fiber_app.Options{
+		Port:              cfg.HTTP.Port,
+		ShutdownDelay:     cfg.HTTP.ShutdownDelay,
+		DrainTimeout:      cfg.HTTP.DrainTimeout,
+		GraphQLProduction: cfg.GraphQL.Production,
+	}
+}
+
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using default environment variables")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
-	app := fx.New(
-		fx.Provide(logger.NewLogger),
+	options := []fx.Option{
+		fx.StopTimeout(cfg.HTTP.StopTimeout()),
+		fx.Supply(cfg),
+		config.Module,
+		fx.Provide(httpOptions),
+		logger.Module,
+		fx.Invoke(func(logger *logrus.Logger) {
+			logger.WithFields(logrus.Fields(cfg.LogFields())).Info("Configuration loaded")
+		}),
+		awsConfig.Module,
+		secrets.Module(cfg.Secrets.Provider),
+		metrics.Module,
+		health.Module,
+		utils.Module,
 		db.Module,
+		tracing.Module,
+		mailer.Module,
+		sms.Module,
+		validation.Module,
 		provider.Module,
+		repository.Module,
 		service.Module,
 		fiber_app.Module,
 		fx.Provide(NewApp),
@@ -49,15 +94,24 @@ func main() {
 				app.Logger.Info("Database connection verified, result:", result)
 			}
 		}),
-	)
+	}
+	if cognito.Enabled(cfg) {
+		options = append(options, cognito.Module)
+	}
+
+	app := fx.New(options...)
 
 	if err := app.Start(context.Background()); err != nil {
 		log.Fatal(err)
 	}
 
-	<-app.Done()
+	signal := <-app.Wait()
 
-	if err := app.Stop(context.Background()); err != nil {
+	stopCtx, cancel := context.WithTimeout(context.Background(), app.StopTimeout())
+	err = app.Stop(stopCtx)
+	cancel()
+	if err != nil {
 		log.Fatal(err)
 	}
+	os.Exit(signal.ExitCode)
 }
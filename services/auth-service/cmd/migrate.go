@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/service"
+	awsConfig "github.com/content-management-system/auth-service/pkg/aws"
+	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/content-management-system/auth-service/pkg/logger"
+	"github.com/content-management-system/auth-service/pkg/migrate"
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+const migrateUsage = `usage: server migrate <command> [-to version]
+
+commands:
+  status    list embedded migrations and whether they are applied
+  up        apply pending migrations, up to -to if given
+  down      roll back the newest migration, or everything above -to`
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	command := args[0]
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	to := flags.Int64("to", -1, "target version")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Read()
+	if err == nil {
+		err = cfg.DB.Validate()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	log := logrus.New()
+	log.SetOutput(os.Stderr)
+
+	var runErr error
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(log, cfg, logger.NewRegistryFor(log)),
+		config.Module,
+		awsConfig.Module,
+		secrets.Module(cfg.Secrets.Provider),
+		db.Module,
+		fx.Provide(service.NewMigrator),
+		fx.Invoke(func(m *migrate.Migrator) {
+			runErr = migrateCommand(context.Background(), m, command, *to)
+		}),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := app.Stop(context.Background()); err != nil {
+		log.WithError(err).Warn("Failed to close database connection")
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, runErr)
+		return 1
+	}
+	return 0
+}
+
+func migrateCommand(ctx context.Context, m *migrate.Migrator, command string, to int64) error {
+	switch command {
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+		for _, s := range statuses {
+			applied, note := "pending", ""
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				note = "checksum mismatch"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, applied, note)
+		}
+		return w.Flush()
+	case "up":
+		if to < 0 {
+			to = 0
+		}
+		return m.Up(ctx, to)
+	case "down":
+		if to < 0 {
+			previous, err := m.Previous(ctx)
+			if err != nil {
+				return err
+			}
+			to = previous
+		}
+		return m.Down(ctx, to)
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,434 @@
+// Package config loads the service settings once at startup. Values come
+// from field defaults, then an optional YAML file, then the environment
+// (including a .env file), each layer overriding the one before. The
+// result is validated and supplied to every module through fx.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is read when CONFIG_FILE is not set and the file exists.
+const DefaultFile = "config.yaml"
+
+const redacted = "[redacted]"
+
+// Config holds every setting. Each leaf field names its environment
+// variable in the env tag and its fallback in the default tag; fields
+// tagged secret are never logged.
+type Config struct {
+	HTTP         HTTPConfig         `yaml:"http"`
+	Log          LogConfig          `yaml:"log"`
+	DB           DBConfig           `yaml:"db"`
+	Secrets      SecretsConfig      `yaml:"secrets"`
+	AWS          AWSConfig          `yaml:"aws"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	GraphQL      GraphQLConfig      `yaml:"graphql"`
+	Cognito      CognitoConfig      `yaml:"cognito"`
+	SMS          SMSConfig          `yaml:"sms"`
+	Registration RegistrationConfig `yaml:"registration"`
+	Lifecycle    LifecycleConfig    `yaml:"lifecycle"`
+	Privacy      PrivacyConfig      `yaml:"privacy"`
+	Profile      ProfileConfig      `yaml:"profile"`
+	Invitation   InvitationConfig   `yaml:"invitation"`
+}
+
+type HTTPConfig struct {
+	Port int `yaml:"port" env:"PORT" default:"8080"`
+	// ShutdownDelay is how long /readyz reports failure before the server
+	// stops accepting connections, giving load balancers time to drain.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"HTTP_SHUTDOWN_DELAY" default:"5s"`
+	// DrainTimeout bounds how long in-flight requests may take to finish
+	// once the server stops accepting connections.
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"HTTP_DRAIN_TIMEOUT" default:"15s"`
+}
+
+// StopTimeout is the whole shutdown budget: the readiness delay, the
+// request drain, and time to flush and close everything else.
+func (c HTTPConfig) StopTimeout() time.Duration {
+	return c.ShutdownDelay + c.DrainTimeout + 15*time.Second
+}
+
+// LogConfig controls where logs go and how verbose they are. Levels
+// overrides Level for individual modules, e.g. "db=warn,cognito=debug".
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	Levels string `yaml:"levels" env:"LOG_LEVELS"`
+	Output string `yaml:"output" env:"LOG_OUTPUT" default:"file"`
+	File   string `yaml:"file" env:"LOG_FILE" default:"logs/app.log"`
+}
+
+// ModuleLevels parses Levels into module names and level names.
+func (c LogConfig) ModuleLevels() (map[string]string, error) {
+	levels := map[string]string{}
+	for _, pair := range strings.Split(c.Levels, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		module, level, ok := strings.Cut(pair, "=")
+		module, level = strings.TrimSpace(module), strings.TrimSpace(level)
+		if !ok || module == "" {
+			return nil, fmt.Errorf("LOG_LEVELS entry %q must look like module=level", strings.TrimSpace(pair))
+		}
+		if _, err := logrus.ParseLevel(level); err != nil {
+			return nil, fmt.Errorf("LOG_LEVELS entry %q: %w", strings.TrimSpace(pair), err)
+		}
+		levels[module] = level
+	}
+	return levels, nil
+}
+
+type DBConfig struct {
+	Host        string `yaml:"host" env:"DB_HOST" default:"localhost"`
+	Port        int    `yaml:"port" env:"DB_PORT" default:"5432"`
+	User        string `yaml:"user" env:"DB_USER" default:"postgres"`
+	Name        string `yaml:"name" env:"DB_NAME" default:"mydb"`
+	SSLMode     string `yaml:"sslmode" env:"DB_SSLMODE" default:"disable"`
+	AutoMigrate bool   `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+	// SlowQueryThreshold logs statements that take longer as warnings.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD" default:"200ms"`
+}
+
+// DSN is the libpq connection string for the database. It carries no
+// password; that is fetched from the secrets provider for each connection.
+func (c DBConfig) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Name, c.SSLMode,
+	)
+}
+
+// SecretsConfig selects where DB_PASSWORD, JWT_SECRET and
+// COGNITO_CLIENT_SECRET are read from: the environment, files in Dir, or
+// AWS Secrets Manager or SSM under Prefix.
+type SecretsConfig struct {
+	Provider string        `yaml:"provider" env:"SECRETS_PROVIDER" default:"env"`
+	Dir      string        `yaml:"dir" env:"SECRETS_DIR" default:"/run/secrets"`
+	Prefix   string        `yaml:"prefix" env:"SECRETS_PREFIX"`
+	TTL      time.Duration `yaml:"ttl" env:"SECRETS_TTL" default:"5m"`
+}
+
+// AWSConfig narrows the SDK's default credential chain. Everything is
+// optional: with no settings the chain and region are resolved the way the
+// AWS CLI resolves them.
+type AWSConfig struct {
+	Region                string `yaml:"region" env:"AWS_REGION"`
+	Profile               string `yaml:"profile" env:"AWS_PROFILE"`
+	AccessKeyID           string `yaml:"access_key_id" env:"AWS_ACCESS_KEY_ID"`
+	SecretAccessKey       string `yaml:"secret_access_key" env:"AWS_SECRET_ACCESS_KEY" secret:"true"`
+	SessionToken          string `yaml:"session_token" env:"AWS_SESSION_TOKEN" secret:"true"`
+	AssumeRoleARN         string `yaml:"assume_role_arn" env:"AWS_ASSUME_ROLE_ARN"`
+	AssumeRoleExternalID  string `yaml:"assume_role_external_id" env:"AWS_ASSUME_ROLE_EXTERNAL_ID" secret:"true"`
+	AssumeRoleSessionName string `yaml:"assume_role_session_name" env:"AWS_ASSUME_ROLE_SESSION_NAME" default:"auth-service"`
+	EndpointURL           string `yaml:"endpoint_url" env:"AWS_ENDPOINT_URL"`
+}
+
+// TracingConfig selects where OpenTelemetry spans go. With "none" spans
+// are still created, so trace IDs reach logs and downstream services, but
+// nothing is exported.
+type TracingConfig struct {
+	Exporter     string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" default:"none"`
+	OTLPEndpoint string `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName  string `yaml:"service_name" env:"OTEL_SERVICE_NAME" default:"auth-service"`
+}
+
+// GraphQLConfig bounds what one GraphQL operation may cost. Production
+// turns off introspection and the /playground route.
+type GraphQLConfig struct {
+	Production    bool `yaml:"production" env:"GRAPHQL_PRODUCTION" default:"false"`
+	MaxDepth      int  `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH" default:"10"`
+	MaxComplexity int  `yaml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" default:"1000"`
+	// APQCacheSize is how many persisted queries are kept, least recently
+	// used first out.
+	APQCacheSize int `yaml:"apq_cache_size" env:"GRAPHQL_APQ_CACHE_SIZE" default:"1000"`
+}
+
+type CognitoConfig struct {
+	UserPoolID string `yaml:"user_pool_id" env:"USER_POOL_ID"`
+	ClientID   string `yaml:"client_id" env:"CLIENT_ID"`
+}
+
+// Enabled reports whether the Cognito integration is configured.
+func (c CognitoConfig) Enabled() bool {
+	return c.UserPoolID != "" && c.ClientID != ""
+}
+
+type SMSConfig struct {
+	Sender   string `yaml:"sender" env:"SMS_SENDER" default:"log"`
+	FilePath string `yaml:"file_path" env:"SMS_FILE_PATH" default:"logs/sms.log"`
+}
+
+type RegistrationConfig struct {
+	DefaultRole string `yaml:"default_role" env:"REGISTRATION_DEFAULT_ROLE" default:"Customer"`
+	// DomainRoles is a comma separated list of domain=Role pairs, e.g.
+	// "acme.com=Editor,partner.io=Staff".
+	DomainRoles string `yaml:"domain_roles" env:"REGISTRATION_DOMAIN_ROLES"`
+}
+
+type LifecycleConfig struct {
+	DeletionGrace  time.Duration `yaml:"deletion_grace" env:"ACCOUNT_DELETION_GRACE" default:"720h"`
+	PurgeRetention time.Duration `yaml:"purge_retention" env:"ACCOUNT_PURGE_RETENTION" default:"720h"`
+	SweepInterval  time.Duration `yaml:"sweep_interval" env:"ACCOUNT_SWEEP_INTERVAL" default:"1h"`
+}
+
+type PrivacyConfig struct {
+	ExportDir string        `yaml:"export_dir" env:"DATA_EXPORT_DIR" default:"exports"`
+	ExportTTL time.Duration `yaml:"export_ttl" env:"DATA_EXPORT_TTL" default:"168h"`
+}
+
+type ProfileConfig struct {
+	AvatarDir       string `yaml:"avatar_dir" env:"AVATAR_DIR" default:"uploads/avatars"`
+	EmailConfirmURL string `yaml:"email_confirm_url" env:"EMAIL_CONFIRM_URL" default:"http://localhost:8080/auth/email/confirm"`
+}
+
+type InvitationConfig struct {
+	AcceptURL string `yaml:"accept_url" env:"INVITATION_ACCEPT_URL" default:"http://localhost:8080/invitations/accept"`
+}
+
+// Load reads and validates the configuration.
+func Load() (*Config, error) {
+	cfg, err := Read()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Read assembles the configuration without validating it, for commands
+// that only need part of it. A malformed value is still an error.
+func Read() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := walk(cfg, func(f leaf) error { return f.set(f.def, "default") }); err != nil {
+		return nil, err
+	}
+
+	path, required := os.LookupEnv("CONFIG_FILE")
+	if !required {
+		path = DefaultFile
+	}
+	if err := cfg.readFile(path, required); err != nil {
+		return nil, err
+	}
+
+	err := walk(cfg, func(f leaf) error {
+		if value, ok := os.LookupEnv(f.env); ok {
+			return f.set(value, f.env)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once, naming the environment
+// variable that controls it.
+func (c *Config) Validate() error {
+	errs := []error{
+		c.DB.Validate(),
+	}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Port > 0 && c.HTTP.Port < 65536, "PORT must be between 1 and 65535, got %d", c.HTTP.Port)
+	_, err := logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL %q is not a valid level", c.Log.Level)
+	if _, err := c.Log.ModuleLevels(); err != nil {
+		errs = append(errs, err)
+	}
+	switch c.Log.Output {
+	case "stdout", "file", "both":
+	default:
+		check(false, "LOG_OUTPUT must be stdout, file or both, got %q", c.Log.Output)
+	}
+	check(c.Log.Output == "stdout" || c.Log.File != "", "LOG_FILE is required when LOG_OUTPUT is %q", c.Log.Output)
+	switch c.Secrets.Provider {
+	case "env", "file", "secretsmanager", "ssm":
+	default:
+		check(false, "SECRETS_PROVIDER must be env, file, secretsmanager or ssm, got %q", c.Secrets.Provider)
+	}
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		check(false, "OTEL_TRACES_EXPORTER must be none, otlp or stdout, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.OTLPEndpoint != "" {
+		u, err := url.Parse(c.Tracing.OTLPEndpoint)
+		check(err == nil && u.Scheme != "" && u.Host != "", "OTEL_EXPORTER_OTLP_ENDPOINT must be an absolute URL, got %q", c.Tracing.OTLPEndpoint)
+	}
+	check(c.GraphQL.MaxDepth > 0, "GRAPHQL_MAX_DEPTH must be positive, got %d", c.GraphQL.MaxDepth)
+	check(c.GraphQL.MaxComplexity > 0, "GRAPHQL_MAX_COMPLEXITY must be positive, got %d", c.GraphQL.MaxComplexity)
+	check(c.GraphQL.APQCacheSize > 0, "GRAPHQL_APQ_CACHE_SIZE must be positive, got %d", c.GraphQL.APQCacheSize)
+	check((c.Cognito.UserPoolID == "") == (c.Cognito.ClientID == ""), "USER_POOL_ID and CLIENT_ID must be set together")
+	check((c.AWS.AccessKeyID == "") == (c.AWS.SecretAccessKey == ""), "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set together")
+	check(c.AWS.AccessKeyID == "" || c.AWS.Profile == "", "AWS_PROFILE cannot be combined with static AWS_ACCESS_KEY_ID credentials")
+	check(c.AWS.AssumeRoleExternalID == "" || c.AWS.AssumeRoleARN != "", "AWS_ASSUME_ROLE_EXTERNAL_ID requires AWS_ASSUME_ROLE_ARN")
+	if c.AWS.EndpointURL != "" {
+		u, err := url.Parse(c.AWS.EndpointURL)
+		check(err == nil && u.Scheme != "" && u.Host != "", "AWS_ENDPOINT_URL must be an absolute URL, got %q", c.AWS.EndpointURL)
+	}
+	check(c.SMS.Sender == "log" || c.SMS.Sender == "file", "SMS_SENDER must be \"log\" or \"file\", got %q", c.SMS.Sender)
+	check(c.SMS.Sender != "file" || c.SMS.FilePath != "", "SMS_FILE_PATH is required when SMS_SENDER is \"file\"")
+	check(strings.TrimSpace(c.Registration.DefaultRole) != "", "REGISTRATION_DEFAULT_ROLE must not be empty")
+	for _, pair := range strings.Split(c.Registration.DomainRoles, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		domain, role, ok := strings.Cut(pair, "=")
+		check(ok && strings.TrimSpace(domain) != "" && strings.TrimSpace(role) != "",
+			"REGISTRATION_DOMAIN_ROLES entry %q must look like domain=Role", strings.TrimSpace(pair))
+	}
+	_ = walk(c, func(f leaf) error {
+		if d, ok := f.value.Interface().(time.Duration); ok {
+			check(d > 0, "%s must be a positive duration, got %s", f.env, d)
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+func (c DBConfig) Validate() error {
+	var errs []error
+	if c.Host == "" || c.Name == "" || c.User == "" {
+		errs = append(errs, errors.New("DB_HOST, DB_NAME and DB_USER must not be empty"))
+	}
+	if c.Port <= 0 || c.Port >= 65536 {
+		errs = append(errs, fmt.Errorf("DB_PORT must be between 1 and 65535, got %d", c.Port))
+	}
+	switch c.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("DB_SSLMODE %q is not a valid sslmode", c.SSLMode))
+	}
+	return errors.Join(errs...)
+}
+
+// LogFields flattens the configuration for logging, keyed by YAML path.
+// Secrets only show whether they are set.
+func (c *Config) LogFields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	_ = walk(c, func(f leaf) error {
+		value := f.value.Interface()
+		if f.secret {
+			value = ""
+			if !f.value.IsZero() {
+				value = redacted
+			}
+		} else if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		fields[f.path] = value
+		return nil
+	})
+	return fields
+}
+
+type leaf struct {
+	path   string
+	env    string
+	def    string
+	secret bool
+	value  reflect.Value
+}
+
+func (f leaf) set(raw, source string) error {
+	if raw == "" && source == "default" {
+		return nil
+	}
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(raw)
+	case bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", source, raw)
+		}
+		f.value.SetBool(v)
+	case int:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number, got %q", source, raw)
+		}
+		f.value.SetInt(int64(v))
+	case time.Duration:
+		v, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be a duration such as 30s or 24h, got %q", source, raw)
+		}
+		f.value.SetInt(int64(v))
+	default:
+		return fmt.Errorf("config field %s has an unsupported type %s", f.path, f.value.Type())
+	}
+	return nil
+}
+
+// walk calls fn for every leaf setting of cfg.
+func walk(cfg *Config, fn func(leaf) error) error {
+	return walkStruct(reflect.ValueOf(cfg).Elem(), "", fn)
+}
+
+func walkStruct(v reflect.Value, prefix string, fn func(leaf) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := strings.TrimPrefix(prefix+"."+field.Tag.Get("yaml"), ".")
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			if err := walkStruct(v.Field(i), path, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		err := fn(leaf{
+			path:   path,
+			env:    field.Tag.Get("env"),
+			def:    field.Tag.Get("default"),
+			secret: field.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
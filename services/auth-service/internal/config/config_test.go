@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLayersFileAndEnvironmentOverDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("http:\n  port: 9090\ndb:\n  host: db.internal\n  name: cms\n"), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_NAME", "cms_test")
+	t.Setenv("ACCOUNT_SWEEP_INTERVAL", "5m")
+
+	cfg, err := Read()
+	require.NoError(t, err)
+
+	require.Equal(t, 9090, cfg.HTTP.Port)
+	require.Equal(t, "db.internal", cfg.DB.Host)
+	require.Equal(t, "cms_test", cfg.DB.Name)
+	require.Equal(t, 5432, cfg.DB.Port)
+	require.Equal(t, 5*time.Minute, cfg.Lifecycle.SweepInterval)
+	require.Equal(t, 720*time.Hour, cfg.Lifecycle.DeletionGrace)
+}
+
+func TestReadRejectsMalformedValues(t *testing.T) {
+	t.Setenv("DB_PORT", "five")
+
+	_, err := Read()
+	require.ErrorContains(t, err, "DB_PORT must be a whole number")
+}
+
+func TestReadRejectsUnknownFileKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("http:\n  prot: 9090\n"), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+
+	_, err := Read()
+	require.ErrorContains(t, err, "prot")
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg, err := Read()
+	require.NoError(t, err)
+	cfg.Secrets.Provider = "vault"
+	cfg.Cognito.UserPoolID = "pool"
+	cfg.SMS.Sender = "carrier-pigeon"
+	cfg.Registration.DomainRoles = "acme.com=Editor,broken"
+	cfg.Log.Levels = "db=warn,cognito=chatty"
+
+	err = cfg.Validate()
+	require.ErrorContains(t, err, "SECRETS_PROVIDER")
+	require.ErrorContains(t, err, "USER_POOL_ID and CLIENT_ID")
+	require.ErrorContains(t, err, "SMS_SENDER")
+	require.ErrorContains(t, err, `"broken"`)
+	require.ErrorContains(t, err, `LOG_LEVELS entry "cognito=chatty"`)
+
+	cfg.Secrets.Provider = "file"
+	cfg.Log.Levels = "db=warn, cognito=debug"
+	cfg.Cognito.UserPoolID = ""
+	cfg.SMS.Sender = "log"
+	cfg.Registration.DomainRoles = "acme.com=Editor"
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateChecksAWSSettings(t *testing.T) {
+	cfg, err := Read()
+	require.NoError(t, err)
+	cfg.AWS.AccessKeyID = "AKIAEXAMPLE"
+	cfg.AWS.Profile = "dev"
+	cfg.AWS.AssumeRoleExternalID = "external"
+	cfg.AWS.EndpointURL = "localhost:4566"
+
+	err = cfg.Validate()
+	require.ErrorContains(t, err, "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	require.ErrorContains(t, err, "AWS_PROFILE cannot be combined")
+	require.ErrorContains(t, err, "AWS_ASSUME_ROLE_EXTERNAL_ID requires AWS_ASSUME_ROLE_ARN")
+	require.ErrorContains(t, err, "AWS_ENDPOINT_URL")
+
+	cfg.AWS = AWSConfig{
+		Profile:              "dev",
+		AssumeRoleARN:        "arn:aws:iam::123456789012:role/auth-service",
+		AssumeRoleExternalID: "external",
+		EndpointURL:          "http://localhost:4566",
+	}
+	require.NoError(t, cfg.Validate())
+}
+
+func TestLogFieldsRedactsSecrets(t *testing.T) {
+	cfg := &Config{}
+	cfg.DB.Host = "localhost"
+	cfg.AWS.AccessKeyID = "AKIAEXAMPLE"
+	cfg.AWS.SecretAccessKey = "hunter2"
+
+	fields := cfg.LogFields()
+	require.Equal(t, "localhost", fields["db.host"])
+	require.Equal(t, "AKIAEXAMPLE", fields["aws.access_key_id"])
+	require.Equal(t, redacted, fields["aws.secret_access_key"])
+	require.Equal(t, "", fields["aws.session_token"])
+	for _, value := range fields {
+		require.NotEqual(t, "hunter2", value)
+	}
+}
+
+func TestOptionsCarryTheirSettings(t *testing.T) {
+	cfg, err := Read()
+	require.NoError(t, err)
+	cfg.AWS.Region = "eu-west-1"
+	cfg.Log.Levels = "db=warn"
+
+	require.Equal(t, "eu-west-1", cfg.AWSOptions().Region)
+	require.Equal(t, cfg.DB.DSN(), cfg.DBOptions().DSN)
+	require.Equal(t, 5*time.Minute, cfg.SecretsOptions().TTL)
+	log, err := cfg.LogOptions()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"db": "warn"}, log.ModuleLevels)
+
+	cfg.Log.Levels = "db"
+	_, err = cfg.LogOptions()
+	require.Error(t, err)
+}
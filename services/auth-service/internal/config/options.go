@@ -0,0 +1,79 @@
+package config
+
+import (
+	"github.com/content-management-system/auth-service/pkg/aws"
+	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/content-management-system/auth-service/pkg/logger"
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/content-management-system/auth-service/pkg/sms"
+	"github.com/content-management-system/auth-service/pkg/tracing"
+	"go.uber.org/fx"
+)
+
+// Module derives the options of the shared pkg packages from the supplied
+// Config, so those packages do not depend on this one.
+var Module = fx.Provide(
+	(*Config).AWSOptions,
+	(*Config).DBOptions,
+	(*Config).LogOptions,
+	(*Config).SecretsOptions,
+	(*Config).SMSOptions,
+	(*Config).TracingOptions,
+)
+
+func (c *Config) AWSOptions() aws.Options {
+	return aws.Options{
+		Region:                c.AWS.Region,
+		Profile:               c.AWS.Profile,
+		AccessKeyID:           c.AWS.AccessKeyID,
+		SecretAccessKey:       c.AWS.SecretAccessKey,
+		SessionToken:          c.AWS.SessionToken,
+		AssumeRoleARN:         c.AWS.AssumeRoleARN,
+		AssumeRoleExternalID:  c.AWS.AssumeRoleExternalID,
+		AssumeRoleSessionName: c.AWS.AssumeRoleSessionName,
+		EndpointURL:           c.AWS.EndpointURL,
+	}
+}
+
+func (c *Config) DBOptions() db.Options {
+	return db.Options{
+		DSN:                c.DB.DSN(),
+		SlowQueryThreshold: c.DB.SlowQueryThreshold,
+	}
+}
+
+func (c *Config) LogOptions() (logger.Options, error) {
+	levels, err := c.Log.ModuleLevels()
+	if err != nil {
+		return logger.Options{}, err
+	}
+	return logger.Options{
+		Level:        c.Log.Level,
+		ModuleLevels: levels,
+		Output:       c.Log.Output,
+		File:         c.Log.File,
+	}, nil
+}
+
+func (c *Config) SecretsOptions() secrets.Options {
+	return secrets.Options{
+		Dir:    c.Secrets.Dir,
+		Prefix: c.Secrets.Prefix,
+		TTL:    c.Secrets.TTL,
+	}
+}
+
+func (c *Config) SMSOptions() sms.Options {
+	return sms.Options{
+		Sender:   c.SMS.Sender,
+		FilePath: c.SMS.FilePath,
+	}
+}
+
+func (c *Config) TracingOptions() tracing.Options {
+	return tracing.Options{
+		Exporter:     c.Tracing.Exporter,
+		OTLPEndpoint: c.Tracing.OTLPEndpoint,
+		ServiceName:  c.Tracing.ServiceName,
+	}
+}
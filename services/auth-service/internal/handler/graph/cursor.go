@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+const (
+	usersPageDefault = 20
+	usersPageMax     = 100
+)
+
+// Cursors are opaque to clients: the base64 of the user's creation time in
+// Unix nanoseconds and ID, which is the listing's sort key.
+func encodeCursor(u *types.User) string {
+	raw := strconv.FormatInt(u.CreatedAt.UnixNano(), 10) + ":" + u.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*repository.UserCursor, error) {
+	invalid := apperror.New(apperror.CodeBadRequest, "after is not a valid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, invalid
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, invalid
+	}
+	return &repository.UserCursor{CreatedAt: time.Unix(0, n), ID: parsed}, nil
+}
+
+// pageSize applies the default and the cap to a connection's first
+// argument.
+func pageSize(first *int) (int, error) {
+	if first == nil {
+		return usersPageDefault, nil
+	}
+	if *first < 0 {
+		return 0, apperror.New(apperror.CodeBadRequest, "first must not be negative")
+	}
+	if *first > usersPageMax {
+		return usersPageMax, nil
+	}
+	return *first, nil
+}
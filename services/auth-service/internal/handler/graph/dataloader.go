@@ -0,0 +1,169 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/google/uuid"
+)
+
+const (
+	// loaderWait is how long a loader collects keys before fetching them.
+	// gqlgen resolves list elements concurrently, so the lookups for one
+	// list arrive well within it.
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 500
+)
+
+type loadersKey struct{}
+
+// loaders batch the lookups made while resolving one operation. They also
+// cache what they load for the operation's lifetime, so no result outlives
+// the request that fetched it.
+type loaders struct {
+	// users serves every field that resolves a user by ID, including
+	// content authors.
+	users *loader[uuid.UUID, *types.User]
+	roles *loader[uuid.UUID, *types.Role]
+}
+
+func (r *Resolver) newLoaders() *loaders {
+	return &loaders{
+		users: newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*types.User, error) {
+			users, err := r.userService.GetUsersByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			out := make(map[uuid.UUID]*types.User, len(users))
+			for i := range users {
+				out[users[i].ID] = &users[i]
+			}
+			return out, nil
+		}),
+		roles: newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*types.Role, error) {
+			roles, err := r.roleService.GetRolesByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			out := make(map[uuid.UUID]*types.Role, len(roles))
+			for i := range roles {
+				out[roles[i].ID] = &roles[i]
+			}
+			return out, nil
+		}),
+	}
+}
+
+// withLoaders gives every operation its own loaders.
+func (r *Resolver) withLoaders(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	return next(context.WithValue(ctx, loadersKey{}, r.newLoaders()))
+}
+
+// loadersFor returns the operation's loaders. Outside an operation, as in
+// resolvers called directly, it returns fresh ones that batch nothing
+// across calls.
+func (r *Resolver) loadersFor(ctx context.Context) *loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return l
+	}
+	return r.newLoaders()
+}
+
+// loadUser resolves a user ID through the operation's loader. It returns
+// apperror.ErrUserNotFound when no such user exists.
+func (r *Resolver) loadUser(ctx context.Context, id uuid.UUID) (*types.User, error) {
+	user, err := r.loadersFor(ctx).users.Load(ctx, id)
+	if err == nil && user == nil {
+		err = apperror.ErrUserNotFound
+	}
+	return user, err
+}
+
+// loadRole is loadUser for roles.
+func (r *Resolver) loadRole(ctx context.Context, id uuid.UUID) (*types.Role, error) {
+	role, err := r.loadersFor(ctx).roles.Load(ctx, id)
+	if err == nil && role == nil {
+		err = apperror.ErrRoleNotFound
+	}
+	return role, err
+}
+
+// loader collects the keys requested within wait of the first into one
+// fetch, or fewer if maxBatch keys arrive sooner. Keys the fetch does not
+// return load as the zero value.
+type loader[K comparable, V any] struct {
+	fetch    func(ctx context.Context, keys []K) (map[K]V, error)
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	cache   map[K]*loaded[V]
+	pending map[K]*loaded[V]
+	timer   *time.Timer
+}
+
+type loaded[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, wait: loaderWait, maxBatch: loaderMaxBatch, cache: map[K]*loaded[V]{}}
+}
+
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	result, ok := l.cache[key]
+	if !ok {
+		result = &loaded[V]{done: make(chan struct{})}
+		l.cache[key] = result
+		if l.pending == nil {
+			l.pending = map[K]*loaded[V]{}
+			l.timer = time.AfterFunc(l.wait, func() { l.dispatch(ctx) })
+		}
+		l.pending[key] = result
+		if len(l.pending) >= l.maxBatch {
+			go l.dispatch(ctx)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-result.done:
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch fetches whatever is pending. A batch flushed early for being
+// full stops its timer, so the timer cannot flush the next batch early.
+func (l *loader[K, V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	keys := make([]K, 0, len(batch))
+	for key := range batch {
+		keys = append(keys, key)
+	}
+	values, err := l.fetch(ctx, keys)
+	for key, result := range batch {
+		result.value, result.err = values[key], err
+		close(result.done)
+	}
+}
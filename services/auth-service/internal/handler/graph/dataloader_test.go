@@ -0,0 +1,193 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type countingRoles struct {
+	repository.RoleRepository
+	calls atomic.Int64
+}
+
+func (r *countingRoles) FindByID(ctx context.Context, id uuid.UUID) (*types.Role, error) {
+	r.calls.Add(1)
+	return r.RoleRepository.FindByID(ctx, id)
+}
+
+func (r *countingRoles) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]types.Role, error) {
+	r.calls.Add(1)
+	return r.RoleRepository.FindByIDs(ctx, ids)
+}
+
+// seedUsers adds n users spread over a few roles, bypassing registration
+// so that large lists are cheap to build.
+func (f *graphFixture) seedUsers(t testing.TB, n int) {
+	t.Helper()
+	roles := []uuid.UUID{f.admin.ID}
+	for i := 0; i < 4; i++ {
+		roles = append(roles, f.store.AddRole(types.Role{Name: fmt.Sprintf("role%d", i)}).ID)
+	}
+	repos := f.store.Repositories()
+	for i := 0; i < n; i++ {
+		require.NoError(t, repos.Users.Create(context.Background(), &types.User{
+			Username: fmt.Sprintf("member%04d", i),
+			Email:    fmt.Sprintf("member%04d@example.com", i),
+			Password: "-",
+			RoleID:   roles[i%len(roles)],
+			Status:   types.UserStatusActive,
+		}))
+	}
+}
+
+// listUsers pages through every user matching "member" and returns how
+// many it saw and how many pages that took.
+func (f *graphFixture) listUsers(t testing.TB, token string) (users, pages int) {
+	t.Helper()
+	query := `query($after: String) {
+		users(first: 100, after: $after, filter: {search: "member"}) {
+			edges { node { username role { name } } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+	var after interface{}
+	for {
+		resp := f.do(t, token, query, map[string]interface{}{"after": after})
+		require.Empty(t, resp.Errors)
+		var p struct {
+			Users struct {
+				Edges []struct {
+					Node struct {
+						Role struct {
+							Name string `json:"name"`
+						} `json:"role"`
+					} `json:"node"`
+				} `json:"edges"`
+				PageInfo struct {
+					HasNextPage bool    `json:"hasNextPage"`
+					EndCursor   *string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"users"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Data, &p))
+		for _, e := range p.Users.Edges {
+			require.NotEmpty(t, e.Node.Role.Name)
+		}
+		users += len(p.Users.Edges)
+		pages++
+		if !p.Users.PageInfo.HasNextPage {
+			return users, pages
+		}
+		after = *p.Users.PageInfo.EndCursor
+	}
+}
+
+func (f *graphFixture) adminToken(t testing.TB) string {
+	t.Helper()
+	admin, token := f.register(t, "admin")
+	_, _, err := service.NewRoleService(f.store.Repositories(), f.store, logrus.New(), service.RegistrationPolicy{}).AssignRole(context.Background(), admin.ID, f.admin.ID)
+	require.NoError(t, err)
+	return token
+}
+
+func TestUsersBatchRoleLookups(t *testing.T) {
+	f := newGraphFixture(t)
+	token := f.adminToken(t)
+	f.seedUsers(t, 1000)
+
+	f.roleQueries.calls.Store(0)
+	users, pages := f.listUsers(t, token)
+	require.Equal(t, 1000, users)
+	require.Equal(t, 10, pages)
+	// One query per page, with room for a page whose lookups straddle the
+	// loader's wait; without batching this would be one per user.
+	require.LessOrEqual(t, f.roleQueries.calls.Load(), int64(2*pages))
+}
+
+func TestUserResolvesThroughLoader(t *testing.T) {
+	f := newGraphFixture(t)
+	token := f.adminToken(t)
+	user, _ := f.register(t, "someone")
+
+	resp := f.do(t, token, `query($a: UUID!, $b: UUID!) {
+		a: user(id: $a) { username }
+		b: user(id: $a) { username }
+		missing: user(id: $b) { username }
+	}`, map[string]interface{}{"a": user.ID, "b": uuid.New()})
+	require.Empty(t, resp.Errors)
+	require.JSONEq(t, `{"a":{"username":"someone"},"b":{"username":"someone"},"missing":null}`, string(resp.Data))
+}
+
+func TestLoaderBatchesAndCaches(t *testing.T) {
+	var fetches [][]int
+	var mu sync.Mutex
+	l := newLoader(func(_ context.Context, keys []int) (map[int]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches = append(fetches, keys)
+		out := map[int]string{}
+		for _, k := range keys {
+			if k%2 == 0 {
+				out[k] = fmt.Sprint(k)
+			}
+		}
+		return out, nil
+	})
+	// The batch is flushed by its fifth distinct key, never by the timer.
+	l.wait = time.Hour
+	l.maxBatch = 5
+
+	values := make([]string, 10)
+	errs := make([]error, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], errs[i] = l.Load(context.Background(), i%5)
+		}(i)
+	}
+	wg.Wait()
+	for i := range values {
+		require.NoError(t, errs[i])
+		if k := i % 5; k%2 == 0 {
+			require.Equal(t, fmt.Sprint(k), values[i])
+		} else {
+			require.Empty(t, values[i])
+		}
+	}
+	require.Len(t, fetches, 1)
+	require.ElementsMatch(t, []int{0, 1, 2, 3, 4}, fetches[0])
+
+	v, err := l.Load(context.Background(), 4)
+	require.NoError(t, err)
+	require.Equal(t, "4", v)
+	require.Len(t, fetches, 1)
+}
+
+// BenchmarkUsersRoleLookups lists 1,000 users with their roles and reports
+// how many role queries that took.
+func BenchmarkUsersRoleLookups(b *testing.B) {
+	f := newGraphFixture(b)
+	token := f.adminToken(b)
+	f.seedUsers(b, 1000)
+	f.roleQueries.calls.Store(0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.listUsers(b, token)
+	}
+	b.ReportMetric(float64(f.roleQueries.calls.Load())/float64(b.N), "role-queries/op")
+}
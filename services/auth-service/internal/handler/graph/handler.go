@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+type userContextKey struct{}
+
+type requestKey struct{}
+
+// request carries what resolvers need to know about the HTTP request.
+type request struct {
+	ip        string
+	userAgent string
+	requestID string
+}
+
+// Handler serves h from Fiber. The adaptor hands h a fresh context, so the
+// request's user context, which holds the caller, trace span and log
+// fields, is passed through the fasthttp user values.
+func Handler(h http.Handler) fiber.Handler {
+	bridged := adaptor.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ctx, ok := r.Context().Value(userContextKey{}).(context.Context); ok {
+			r = r.WithContext(ctx)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	return func(c *fiber.Ctx) error {
+		ctx := context.WithValue(c.UserContext(), requestKey{}, request{
+			ip:        c.IP(),
+			userAgent: c.Get(fiber.HeaderUserAgent),
+			requestID: problem.RequestID(c),
+		})
+		c.Locals(userContextKey{}, ctx)
+		return bridged(c)
+	}
+}
+
+func requestFrom(ctx context.Context) request {
+	r, _ := ctx.Value(requestKey{}).(request)
+	return r
+}
+
+// actorFrom describes the caller of the current operation for auditing.
+func actorFrom(ctx context.Context) service.Actor {
+	r := requestFrom(ctx)
+	actor := service.Actor{IP: r.ip, UserAgent: r.userAgent}
+	if user := middleware.UserFrom(ctx); user != nil {
+		id := user.ID
+		actor.ID = &id
+	}
+	return actor
+}
+
+// actorAs is actorFrom for operations that authenticate userID themselves.
+func actorAs(ctx context.Context, userID uuid.UUID) service.Actor {
+	actor := actorFrom(ctx)
+	actor.ID = &userID
+	return actor
+}
+
+func (r *Resolver) recordAudit(ctx context.Context, actor service.Actor, action string, target *uuid.UUID, err error, details map[string]interface{}) {
+	r.auditor.Record(ctx, service.NewAuditEvent(actor, action, target, err, details))
+}
+
+func requireUser(ctx context.Context) (*types.User, error) {
+	user := middleware.UserFrom(ctx)
+	if user == nil {
+		return nil, apperror.New(apperror.CodeUnauthorized, "authentication required")
+	}
+	return user, nil
+}
+
+// ErrorPresenter reports service errors with the same stable code the REST
+// API puts in its problem details, under extensions.code. Unexpected
+// errors are logged and their message withheld.
+func ErrorPresenter(log *logrus.Logger) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		if gqlErr.Err == nil {
+			// Parse and validation errors raised by gqlgen itself.
+			return gqlErr
+		}
+
+		var p *problem.Problem
+		var appErr *apperror.Error
+		switch {
+		case errors.As(err, &p):
+			gqlErr.Message = p.Error()
+			gqlErr.Extensions = map[string]interface{}{"code": p.Code}
+			if len(p.Errors) > 0 {
+				gqlErr.Extensions["errors"] = p.Errors
+			}
+		case errors.As(err, &appErr) && appErr.Code != apperror.CodeInternal:
+			gqlErr.Message = appErr.Message
+			gqlErr.Extensions = map[string]interface{}{"code": appErr.Code}
+		default:
+			log.WithContext(ctx).WithError(err).WithField("request_id", requestFrom(ctx).requestID).Error("GraphQL resolver failed")
+			gqlErr.Message = "internal error"
+			gqlErr.Extensions = map[string]interface{}{"code": apperror.CodeInternal}
+		}
+		return gqlErr
+	}
+}
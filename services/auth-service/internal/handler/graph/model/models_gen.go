@@ -2,5 +2,39 @@
 
 package model
 
+import (
+	"github.com/content-management-system/auth-service/pkg/model"
+)
+
+// Tokens issued by login, verifyMfa and refresh. When mfaRequired is true no
+// tokens are issued; pass mfaSession and the texted code to verifyMfa.
+type AuthPayload struct {
+	AccessToken  *string `json:"accessToken,omitempty"`
+	RefreshToken *string `json:"refreshToken,omitempty"`
+	MfaRequired  bool    `json:"mfaRequired"`
+	MfaSession   *string `json:"mfaSession,omitempty"`
+}
+
+type Mutation struct {
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
 type Query struct {
 }
+
+type UserConnection struct {
+	Edges      []*UserEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+type UserEdge struct {
+	Cursor string      `json:"cursor"`
+	Node   *model.User `json:"node"`
+}
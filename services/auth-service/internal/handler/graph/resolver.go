@@ -1,5 +1,36 @@
 package graph
 
-// THIS CODE IS A STARTING POINT ONLY. IT WILL NOT BE UPDATED WITH SCHEMA CHANGES.
+//go:generate go run github.com/99designs/gqlgen generate
 
-type Resolver struct{}
+import (
+	"github.com/content-management-system/auth-service/internal/handler/graph/model"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/validation"
+)
+
+// Resolver serves the GraphQL schema from the same services, validation
+// rules and audit events as the REST handlers.
+type Resolver struct {
+	userService *service.UserService
+	roleService *service.RoleService
+	authService *service.AuthService
+	auditor     service.Auditor
+	binder      *validation.Binder
+}
+
+func NewResolver(us *service.UserService, rs *service.RoleService, as *service.AuthService, auditor service.Auditor, binder *validation.Binder) *Resolver {
+	return &Resolver{
+		userService: us,
+		roleService: rs,
+		authService: as,
+		auditor:     auditor,
+		binder:      binder,
+	}
+}
+
+func authPayload(result *service.LoginResult) *model.AuthPayload {
+	if result.Challenge != nil {
+		return &model.AuthPayload{MfaRequired: true, MfaSession: &result.Challenge.Session}
+	}
+	return &model.AuthPayload{AccessToken: &result.AccessToken, RefreshToken: &result.RefreshToken}
+}
@@ -0,0 +1,305 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository/memory"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/content-management-system/auth-service/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingAuditor struct {
+	mu     sync.Mutex
+	events []service.AuditEvent
+}
+
+func (a *recordingAuditor) Record(_ context.Context, event service.AuditEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, event)
+}
+
+type graphFixture struct {
+	app     *fiber.App
+	store   *memory.Store
+	users   *service.UserService
+	jwt     *utils.JWT
+	auditor *recordingAuditor
+	admin   types.Role
+	// roleQueries counts the role lookups resolvers make.
+	roleQueries *countingRoles
+}
+
+func newGraphFixture(t testing.TB) *graphFixture {
+	return newGraphFixtureWith(t, config.GraphQLConfig{MaxDepth: 10, MaxComplexity: 1000, APQCacheSize: 10})
+}
+
+func newGraphFixtureWith(t testing.TB, cfg config.GraphQLConfig) *graphFixture {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, secrets.JWTSigningKey), []byte(strings.Repeat("k", 32)), 0o600))
+	jwt, err := utils.NewJWT(secrets.NewStore(secrets.NewFileProvider(dir), time.Minute, log), log)
+	require.NoError(t, err)
+
+	store := memory.NewStore()
+	repos := store.Repositories()
+	roleQueries := &countingRoles{RoleRepository: repos.Roles}
+	repos.Roles = roleQueries
+	roles := service.NewRoleService(repos, store, log, service.RegistrationPolicy{DefaultRole: types.RoleCustomer})
+	users := service.NewUserService(repos, store, log, roles)
+	sessions := service.NewSessionService(repos, log, jwt)
+	auditor := &recordingAuditor{}
+	store.AddRole(types.Role{Name: types.RoleCustomer})
+	admin := store.AddRole(types.Role{Name: types.RoleAdministrator, Permissions: []types.Permission{
+		{Name: types.PermissionUsersRead},
+		{Name: types.PermissionUsersManage},
+	}})
+
+	auth := service.NewAuthService(users, nil, sessions, auditor, jwt)
+	resolver := NewResolver(users, roles, auth, auditor, validation.NewBinder())
+	srv := NewServer(resolver, &config.Config{GraphQL: cfg}, log)
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler(log)})
+	app.All("/graph", middleware.NewAuthMiddleware(users, jwt).OptionalAuth(), Handler(srv))
+	return &graphFixture{app: app, store: store, users: users, jwt: jwt, auditor: auditor, admin: admin, roleQueries: roleQueries}
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func (f *graphFixture) do(t testing.TB, token, query string, variables map[string]interface{}) gqlResponse {
+	t.Helper()
+	return f.send(t, token, map[string]interface{}{"query": query, "variables": variables})
+}
+
+func (f *graphFixture) send(t testing.TB, token string, payload map[string]interface{}) gqlResponse {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+	req := httptest.NewRequest("POST", "/graph", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := f.app.Test(req, -1)
+	require.NoError(t, err)
+	var out gqlResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	return out
+}
+
+func (f *graphFixture) register(t testing.TB, name string) (*types.User, string) {
+	t.Helper()
+	user, err := f.users.Register(context.Background(), name, name+"@example.com", "Secret123!", "")
+	require.NoError(t, err)
+	token, err := f.jwt.GenerateToken(user.ID)
+	require.NoError(t, err)
+	return user, token
+}
+
+func TestRegisterLoginAndMe(t *testing.T) {
+	f := newGraphFixture(t)
+
+	resp := f.do(t, "", `mutation($input: RegisterInput!) { register(input: $input) { username role { name } } }`,
+		map[string]interface{}{"input": map[string]interface{}{"username": "jane", "email": "jane@example.com", "password": "Secret123!"}})
+	require.Empty(t, resp.Errors)
+	require.JSONEq(t, `{"register":{"username":"jane","role":{"name":"Customer"}}}`, string(resp.Data))
+
+	resp = f.do(t, "", `mutation { login(input: {email: "jane@example.com", password: "Secret123!"}) { accessToken refreshToken mfaRequired } }`, nil)
+	require.Empty(t, resp.Errors)
+	var login struct {
+		Login struct {
+			AccessToken  string `json:"accessToken"`
+			RefreshToken string `json:"refreshToken"`
+		} `json:"login"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &login))
+	require.NotEmpty(t, login.Login.RefreshToken)
+
+	resp = f.do(t, login.Login.AccessToken, `{ me { email } }`, nil)
+	require.Empty(t, resp.Errors)
+	require.JSONEq(t, `{"me":{"email":"jane@example.com"}}`, string(resp.Data))
+
+	resp = f.do(t, "", `mutation($token: String!) { refresh(refreshToken: $token) { accessToken refreshToken } }`,
+		map[string]interface{}{"token": login.Login.RefreshToken})
+	require.Empty(t, resp.Errors)
+	require.Contains(t, string(resp.Data), `"refreshToken":null`)
+
+	actions := make([]string, len(f.auditor.events))
+	for i, e := range f.auditor.events {
+		actions[i] = e.Action
+	}
+	require.Equal(t, []string{service.AuditActionRegister, service.AuditActionLogin, service.AuditActionTokenRefresh}, actions)
+}
+
+func TestErrorsCarryServiceCodes(t *testing.T) {
+	f := newGraphFixture(t)
+	_, token := f.register(t, "jane")
+
+	resp := f.do(t, "", `{ me { id } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "UNAUTHORIZED", resp.Errors[0].Extensions["code"])
+
+	resp = f.do(t, "", `{ users { totalCount } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "UNAUTHORIZED", resp.Errors[0].Extensions["code"])
+
+	resp = f.do(t, token, `{ users { totalCount } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "FORBIDDEN", resp.Errors[0].Extensions["code"])
+
+	resp = f.do(t, "", `mutation { login(input: {email: "not-an-email", password: "x"}) { accessToken } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "VALIDATION_FAILED", resp.Errors[0].Extensions["code"])
+}
+
+func TestUsersFollowsConnectionSpec(t *testing.T) {
+	f := newGraphFixture(t)
+	admin, token := f.register(t, "admin")
+	_, _, err := service.NewRoleService(f.store.Repositories(), f.store, logrus.New(), service.RegistrationPolicy{}).AssignRole(context.Background(), admin.ID, f.admin.ID)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		f.register(t, fmt.Sprintf("user%d", i))
+	}
+
+	query := `query($after: String) {
+		users(first: 2, after: $after, filter: {search: "user"}) {
+			totalCount
+			edges { cursor node { username role { name } } }
+			pageInfo { hasNextPage hasPreviousPage endCursor }
+		}
+	}`
+	type page struct {
+		Users struct {
+			TotalCount int `json:"totalCount"`
+			Edges      []struct {
+				Node struct {
+					Username string `json:"username"`
+				} `json:"node"`
+			} `json:"edges"`
+			PageInfo struct {
+				HasNextPage     bool    `json:"hasNextPage"`
+				HasPreviousPage bool    `json:"hasPreviousPage"`
+				EndCursor       *string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"users"`
+	}
+
+	var seen []string
+	var after interface{}
+	for pages := 0; ; pages++ {
+		resp := f.do(t, token, query, map[string]interface{}{"after": after})
+		require.Empty(t, resp.Errors)
+		var p page
+		require.NoError(t, json.Unmarshal(resp.Data, &p))
+		require.Equal(t, 4, p.Users.TotalCount)
+		require.Equal(t, pages > 0, p.Users.PageInfo.HasPreviousPage)
+		for _, e := range p.Users.Edges {
+			seen = append(seen, e.Node.Username)
+		}
+		if !p.Users.PageInfo.HasNextPage {
+			break
+		}
+		after = *p.Users.PageInfo.EndCursor
+	}
+	require.ElementsMatch(t, []string{"user0", "user1", "user2", "user3"}, seen)
+
+	resp := f.do(t, token, `{ users(after: "bogus") { totalCount } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "BAD_REQUEST", resp.Errors[0].Extensions["code"])
+}
+
+func TestAssignRoleRejectsSelfChange(t *testing.T) {
+	f := newGraphFixture(t)
+	admin, token := f.register(t, "admin")
+	_, _, err := service.NewRoleService(f.store.Repositories(), f.store, logrus.New(), service.RegistrationPolicy{}).AssignRole(context.Background(), admin.ID, f.admin.ID)
+	require.NoError(t, err)
+	other, _ := f.register(t, "jane")
+
+	mutation := `mutation($user: UUID!, $role: UUID!) { assignRole(userId: $user, roleId: $role) { role { name } } }`
+	resp := f.do(t, token, mutation, map[string]interface{}{"user": admin.ID, "role": other.RoleID})
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "FORBIDDEN", resp.Errors[0].Extensions["code"])
+
+	resp = f.do(t, token, mutation, map[string]interface{}{"user": other.ID, "role": f.admin.ID})
+	require.Empty(t, resp.Errors)
+	require.JSONEq(t, `{"assignRole":{"role":{"name":"Administrator"}}}`, string(resp.Data))
+}
+
+func TestLimitsRejectExpensiveOperations(t *testing.T) {
+	f := newGraphFixtureWith(t, config.GraphQLConfig{MaxDepth: 4, MaxComplexity: 100, APQCacheSize: 10})
+	admin, token := f.register(t, "admin")
+	_, _, err := service.NewRoleService(f.store.Repositories(), f.store, logrus.New(), service.RegistrationPolicy{}).AssignRole(context.Background(), admin.ID, f.admin.ID)
+	require.NoError(t, err)
+
+	resp := f.do(t, token, `{ users(first: 5) { edges { node { role { permissions { name } } } } } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "DEPTH_LIMIT_EXCEEDED", resp.Errors[0].Extensions["code"])
+
+	resp = f.do(t, token, `{ users(first: 100) { edges { node { id username } } } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "COMPLEXITY_LIMIT_EXCEEDED", resp.Errors[0].Extensions["code"])
+
+	resp = f.do(t, token, `{ users(first: 10) { edges { node { id username } } } }`, nil)
+	require.Empty(t, resp.Errors)
+}
+
+func TestPersistedQueries(t *testing.T) {
+	f := newGraphFixture(t)
+	_, token := f.register(t, "jane")
+	query := `{ me { username } }`
+	sum := sha256.Sum256([]byte(query))
+	extensions := map[string]interface{}{"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hex.EncodeToString(sum[:])}}
+
+	resp := f.send(t, token, map[string]interface{}{"extensions": extensions})
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "PERSISTED_QUERY_NOT_FOUND", resp.Errors[0].Extensions["code"])
+
+	resp = f.send(t, token, map[string]interface{}{"query": query, "extensions": extensions})
+	require.Empty(t, resp.Errors)
+
+	resp = f.send(t, token, map[string]interface{}{"extensions": extensions})
+	require.Empty(t, resp.Errors)
+	require.JSONEq(t, `{"me":{"username":"jane"}}`, string(resp.Data))
+}
+
+func TestProductionDisablesIntrospection(t *testing.T) {
+	query := `{ __schema { queryType { name } } }`
+
+	resp := newGraphFixture(t).do(t, "", query, nil)
+	require.Empty(t, resp.Errors)
+
+	resp = newGraphFixtureWith(t, config.GraphQLConfig{Production: true, MaxDepth: 10, MaxComplexity: 1000, APQCacheSize: 10}).do(t, "", query, nil)
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, "INTROSPECTION_DISABLED", resp.Errors[0].Extensions["code"])
+}
@@ -1,28 +0,0 @@
-package graph
-
-//
-//import (
-//	"fmt"
-//	"io"
-//	"strconv"
-//
-//	"github.com/99designs/gqlgen/graphql"
-//	"github.com/google/uuid"
-//)
-//
-//func MarshalUUID(u uuid.UUID) graphql.Marshaler {
-//	return graphql.WriterFunc(func(w io.Writer) {
-//		_, _ = io.WriteString(w, strconv.Quote(u.String()))
-//	})
-//}
-//
-//func UnmarshalUUID(v interface{}) (uuid.UUID, error) {
-//	switch v := v.(type) {
-//	case string:
-//		return uuid.Parse(v)
-//	case []byte:
-//		return uuid.Parse(string(v))
-//	default:
-//		return uuid.UUID{}, fmt.Errorf("cannot unmarshal %T into UUID", v)
-//	}
-//}
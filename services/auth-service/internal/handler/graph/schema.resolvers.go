@@ -0,0 +1,182 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+	"errors"
+
+	"github.com/content-management-system/auth-service/internal/handler/graph/model"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	model1 "github.com/content-management-system/auth-service/pkg/model"
+	"github.com/google/uuid"
+)
+
+// Register is the resolver for the register field.
+func (r *mutationResolver) Register(ctx context.Context, input dto.RegisterUserDto) (*model1.User, error) {
+	if err := r.binder.Validate(&input); err != nil {
+		return nil, err
+	}
+
+	user, err := r.userService.Register(ctx, input.Username, input.Email, input.Password, input.InviteCode)
+	if err != nil {
+		r.recordAudit(ctx, actorFrom(ctx), service.AuditActionRegister, nil, err, service.EmailAuditDetails(input.Email))
+		return nil, err
+	}
+	r.recordAudit(ctx, actorAs(ctx, user.ID), service.AuditActionRegister, &user.ID, nil, map[string]interface{}{
+		"role_id":          user.RoleID,
+		"used_invite_code": input.InviteCode != "",
+	})
+	return user, nil
+}
+
+// Login is the resolver for the login field.
+func (r *mutationResolver) Login(ctx context.Context, input dto.LoginDto) (*model.AuthPayload, error) {
+	if err := r.binder.Validate(&input); err != nil {
+		return nil, err
+	}
+
+	result, err := r.authService.Login(ctx, actorFrom(ctx), input.Email, input.Password)
+	if err != nil {
+		return nil, err
+	}
+	return authPayload(result), nil
+}
+
+// VerifyMfa is the resolver for the verifyMfa field.
+func (r *mutationResolver) VerifyMfa(ctx context.Context, session string, code string) (*model.AuthPayload, error) {
+	if err := r.binder.Validate(&dto.VerifyMFADto{Session: session, Code: code}); err != nil {
+		return nil, err
+	}
+
+	result, err := r.authService.VerifyMFA(ctx, actorFrom(ctx), session, code)
+	if err != nil {
+		return nil, err
+	}
+	return authPayload(result), nil
+}
+
+// Refresh is the resolver for the refresh field.
+func (r *mutationResolver) Refresh(ctx context.Context, refreshToken string) (*model.AuthPayload, error) {
+	if err := r.binder.Validate(&dto.RefreshTokenDto{RefreshToken: refreshToken}); err != nil {
+		return nil, err
+	}
+
+	token, err := r.authService.Refresh(ctx, actorFrom(ctx), refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuthPayload{AccessToken: &token}, nil
+}
+
+// AssignRole is the resolver for the assignRole field.
+func (r *mutationResolver) AssignRole(ctx context.Context, userID uuid.UUID, roleID uuid.UUID) (*model1.User, error) {
+	caller, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if caller.ID == userID {
+		return nil, apperror.New(apperror.CodeForbidden, "you cannot change your own role")
+	}
+
+	user, previous, err := r.roleService.AssignRole(ctx, userID, roleID)
+	details := map[string]interface{}{"role_id": roleID}
+	if err == nil {
+		details["previous_role_id"] = previous
+	}
+	r.recordAudit(ctx, actorFrom(ctx), service.AuditActionRoleChange, &userID, err, details)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model1.User, error) {
+	return requireUser(ctx)
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id uuid.UUID) (*model1.User, error) {
+	caller, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if caller.ID != id && !caller.Role.HasPermission(types.PermissionUsersRead) {
+		return nil, apperror.New(apperror.CodeForbidden, "missing permission "+types.PermissionUsersRead)
+	}
+
+	user, err := r.loadUser(ctx, id)
+	if errors.Is(err, apperror.ErrUserNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, filter *repository.UserFilter, first *int, after *string) (*model.UserConnection, error) {
+	limit, err := pageSize(first)
+	if err != nil {
+		return nil, err
+	}
+	var cursor *repository.UserCursor
+	if after != nil {
+		if cursor, err = decodeCursor(*after); err != nil {
+			return nil, err
+		}
+	}
+	if filter == nil {
+		filter = &repository.UserFilter{}
+	}
+
+	page, err := r.userService.ListUsers(ctx, *filter, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := r.userService.CountUsers(ctx, *filter)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &model.UserConnection{
+		Edges:      make([]*model.UserEdge, len(page.Users)),
+		PageInfo:   &model.PageInfo{HasNextPage: page.HasMore, HasPreviousPage: cursor != nil},
+		TotalCount: int(total),
+	}
+	for i := range page.Users {
+		conn.Edges[i] = &model.UserEdge{Cursor: encodeCursor(&page.Users[i]), Node: &page.Users[i]}
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	return conn, nil
+}
+
+// Roles is the resolver for the roles field.
+func (r *queryResolver) Roles(ctx context.Context) ([]*model1.Role, error) {
+	roles, err := r.roleService.GetAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model1.Role, len(roles))
+	for i := range roles {
+		out[i] = &roles[i]
+	}
+	return out, nil
+}
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
@@ -0,0 +1,183 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// NewServer builds the /graph handler: the schema with its directives and
+// connection costs, and the limits and caches from GRAPHQL_*.
+func NewServer(resolver *Resolver, cfg *config.Config, log *logrus.Logger) *handler.Server {
+	c := Config{Resolvers: resolver}
+	c.Directives.Auth = Auth
+	c.Directives.HasPermission = HasPermission
+	c.Complexity.Query.Users = func(childComplexity int, _ *repository.UserFilter, first *int, _ *string) int {
+		return connectionComplexity(childComplexity, first)
+	}
+
+	srv := handler.New(NewExecutableSchema(c))
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New(1000))
+	srv.SetErrorPresenter(ErrorPresenter(log))
+	srv.AroundOperations(resolver.withLoaders)
+
+	if cfg.GraphQL.Production {
+		srv.Use(rejectIntrospection{})
+	} else {
+		srv.Use(extension.Introspection{})
+	}
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New(cfg.GraphQL.APQCacheSize)})
+	srv.Use(extension.FixedComplexityLimit(cfg.GraphQL.MaxComplexity))
+	srv.Use(DepthLimit{Max: cfg.GraphQL.MaxDepth})
+	return srv
+}
+
+// Auth implements @auth.
+func Auth(ctx context.Context, _ interface{}, next graphql.Resolver) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// HasPermission implements @hasPermission.
+func HasPermission(ctx context.Context, _ interface{}, next graphql.Resolver, perm string) (interface{}, error) {
+	user, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Role.HasPermission(perm) {
+		return nil, apperror.New(apperror.CodeForbidden, "missing permission "+perm)
+	}
+	return next(ctx)
+}
+
+// connectionComplexity charges a connection for every node it may return,
+// so a query's cost does not depend on how much data happens to exist.
+func connectionComplexity(childComplexity int, first *int) int {
+	n, err := pageSize(first)
+	if err != nil {
+		n = 0
+	}
+	return 1 + n*childComplexity
+}
+
+// DepthLimit rejects operations whose fields nest deeper than Max.
+// Introspection fields are not counted, so tools can still load the schema.
+type DepthLimit struct {
+	Max int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = DepthLimit{}
+
+func (DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d DepthLimit) Validate(graphql.ExecutableSchema) error {
+	if d.Max < 1 {
+		return fmt.Errorf("depth limit must be positive, got %d", d.Max)
+	}
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(_ context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if depth := selectionDepth(rc.Operation.SelectionSet); depth > d.Max {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.Max)
+		errcode.Set(err, "DEPTH_LIMIT_EXCEEDED")
+		return err
+	}
+	return nil
+}
+
+// selectionDepth follows fragment spreads; validation has already
+// rejected fragment cycles.
+func selectionDepth(set ast.SelectionSet) int {
+	deepest := 0
+	for _, selection := range set {
+		depth := 0
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			depth = 1 + selectionDepth(s.SelectionSet)
+		case *ast.InlineFragment:
+			depth = selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				depth = selectionDepth(s.Definition.SelectionSet)
+			}
+		}
+		deepest = max(deepest, depth)
+	}
+	return deepest
+}
+
+// rejectIntrospection turns away __schema and __type queries up front.
+// Without it they reach the generated resolvers, whose failure would be
+// reported as an internal error.
+type rejectIntrospection struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = rejectIntrospection{}
+
+func (rejectIntrospection) ExtensionName() string {
+	return "RejectIntrospection"
+}
+
+func (rejectIntrospection) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (rejectIntrospection) MutateOperationContext(_ context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if selectsIntrospection(rc.Operation.SelectionSet) {
+		err := gqlerror.Errorf("introspection is disabled")
+		errcode.Set(err, "INTROSPECTION_DISABLED")
+		return err
+	}
+	return nil
+}
+
+// selectsIntrospection reports whether a root selection set asks for
+// __schema or __type. __typename stays allowed.
+func selectsIntrospection(set ast.SelectionSet) bool {
+	for _, selection := range set {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if s.Name == "__schema" || s.Name == "__type" {
+				return true
+			}
+		case *ast.InlineFragment:
+			if selectsIntrospection(s.SelectionSet) {
+				return true
+			}
+		case *ast.FragmentSpread:
+			if s.Definition != nil && selectsIntrospection(s.Definition.SelectionSet) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+
+	"github.com/content-management-system/auth-service/pkg/model"
+)
+
+// Role is the resolver for the role field.
+func (r *userResolver) Role(ctx context.Context, obj *model.User) (*model.Role, error) {
+	if obj.Role.ID == obj.RoleID {
+		return &obj.Role, nil
+	}
+	return r.loadRole(ctx, obj.RoleID)
+}
+
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
+type userResolver struct{ *Resolver }
@@ -0,0 +1,70 @@
+package auth_service
+
+import (
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AdminHandler struct {
+	roleService *service.RoleService
+	binder      *validation.Binder
+}
+
+func NewAdminHandler(rs *service.RoleService, binder *validation.Binder) *AdminHandler {
+	return &AdminHandler{roleService: rs, binder: binder}
+}
+
+func (h *AdminHandler) ListRoles(c *fiber.Ctx) error {
+	roles, err := h.roleService.GetAllRoles(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(roles)
+}
+
+func (h *AdminHandler) CreateInviteCode(c *fiber.Ctx) error {
+	var req dto.CreateInviteCodeDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+
+	admin := middleware.CurrentUser(c)
+	invite, err := h.roleService.CreateInviteCode(
+		c.UserContext(),
+		admin.ID,
+		req.RoleID,
+		req.Email,
+		req.MaxUses,
+		time.Duration(req.ExpiresInHours)*time.Hour,
+	)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(invite)
+}
+
+func (h *AdminHandler) ListInviteCodes(c *fiber.Ctx) error {
+	invites, err := h.roleService.ListInviteCodes(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(invites)
+}
+
+func (h *AdminHandler) RevokeInviteCode(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return apperror.New(apperror.CodeBadRequest, "invalid invite code id")
+	}
+	if err := h.roleService.RevokeInviteCode(c.UserContext(), id); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -1,89 +1,104 @@
 package auth_service
 
 import (
+	"github.com/content-management-system/auth-service/internal/model/dto"
 	"github.com/content-management-system/auth-service/internal/service"
-	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/content-management-system/auth-service/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 )
 
 type AuthHandler struct {
-	userService *service.UserService
+	userService    *service.UserService
+	authService    *service.AuthService
+	sessionService *service.SessionService
+	auditor        service.Auditor
+	binder         *validation.Binder
 }
 
-func NewAuthHandler(us *service.UserService) *AuthHandler {
-	return &AuthHandler{userService: us}
+func NewAuthHandler(us *service.UserService, as *service.AuthService, ss *service.SessionService, auditor service.Auditor, binder *validation.Binder) *AuthHandler {
+	return &AuthHandler{userService: us, authService: as, sessionService: ss, auditor: auditor, binder: binder}
 }
 
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
-	var req struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-		RoleID   uint64 `json:"role_id"`
-	}
-	if err := c.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	var req dto.RegisterUserDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
 	}
 
-	user, err := h.userService.Register(req.Username, req.Email, req.Password, req.RoleID)
+	user, err := h.userService.Register(c.UserContext(), req.Username, req.Email, req.Password, req.InviteCode)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		recordAudit(c.UserContext(), h.auditor, actorFrom(c), service.AuditActionRegister, nil, err, service.EmailAuditDetails(req.Email))
+		return err
 	}
+	recordAudit(c.UserContext(), h.auditor, actorAs(c, user.ID), service.AuditActionRegister, &user.ID, nil, map[string]interface{}{
+		"role_id":          user.RoleID,
+		"used_invite_code": req.InviteCode != "",
+	})
 
 	return c.JSON(user)
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-	if err := c.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request")
+	var req dto.LoginDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
 	}
 
-	user, err := h.userService.Login(req.Email, req.Password)
+	result, err := h.authService.Login(c.UserContext(), actorFrom(c), req.Email, req.Password)
 	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		return err
+	}
+	if result.Challenge != nil {
+		return c.JSON(result.Challenge)
 	}
+	return sendTokens(c, result)
+}
 
-	token, err := utils.GenerateToken(user.ID)
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "could not generate token")
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	var req dto.VerifyMFADto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(user.ID)
+	result, err := h.authService.VerifyMFA(c.UserContext(), actorFrom(c), req.Session, req.Code)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "could not generate refresh token")
+		return err
 	}
+	return sendTokens(c, result)
+}
 
+func sendTokens(c *fiber.Ctx, result *service.LoginResult) error {
 	return c.JSON(fiber.Map{
-		"access_token":  token,
-		"refresh_token": refreshToken,
+		"access_token":  result.AccessToken,
+		"refresh_token": result.RefreshToken,
 	})
 }
 
 func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
-	var req struct {
-		Token string `json:"refresh_token"`
-	}
-	if err := c.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid refresh token request")
+	var req dto.RefreshTokenDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
 	}
 
-	claims, err := utils.ValidateToken(req.Token)
+	token, err := h.authService.Refresh(c.UserContext(), actorFrom(c), req.RefreshToken)
 	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "invalid refresh token")
+		return err
 	}
+	return c.JSON(fiber.Map{"access_token": token})
+}
 
-	newToken, err := utils.GenerateToken(claims.UserID)
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to generate new token")
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req dto.RefreshTokenDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
 	}
 
-	return c.JSON(fiber.Map{"access_token": newToken})
-}
+	session, err := h.sessionService.Revoke(c.UserContext(), req.RefreshToken)
+	if err != nil {
+		recordAudit(c.UserContext(), h.auditor, actorFrom(c), service.AuditActionLogout, nil, err, nil)
+		return err
+	}
+	recordAudit(c.UserContext(), h.auditor, actorAs(c, session.UserID), service.AuditActionLogout, &session.UserID, nil, map[string]interface{}{"session_id": session.ID})
 
-func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Logged out successfully"})
 }
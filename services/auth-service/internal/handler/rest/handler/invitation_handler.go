@@ -0,0 +1,84 @@
+package auth_service
+
+import (
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type InvitationHandler struct {
+	invitationService *service.InvitationService
+	binder            *validation.Binder
+}
+
+func NewInvitationHandler(is *service.InvitationService, binder *validation.Binder) *InvitationHandler {
+	return &InvitationHandler{invitationService: is, binder: binder}
+}
+
+func (h *InvitationHandler) Create(c *fiber.Ctx) error {
+	var req dto.CreateInvitationDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+
+	admin := middleware.CurrentUser(c)
+	inv, err := h.invitationService.Invite(c.UserContext(), admin.ID, req.Email, req.RoleID, time.Duration(req.ExpiresInHours)*time.Hour)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(inv.ToResponse())
+}
+
+func (h *InvitationHandler) List(c *fiber.Ctx) error {
+	invitations, err := h.invitationService.List(c.UserContext(), c.Query("status"))
+	if err != nil {
+		return err
+	}
+	resp := make([]*types.InvitationResponse, 0, len(invitations))
+	for i := range invitations {
+		resp = append(resp, invitations[i].ToResponse())
+	}
+	return c.JSON(resp)
+}
+
+func (h *InvitationHandler) Resend(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return apperror.New(apperror.CodeBadRequest, "invalid invitation id")
+	}
+	inv, err := h.invitationService.Resend(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(inv.ToResponse())
+}
+
+func (h *InvitationHandler) Revoke(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return apperror.New(apperror.CodeBadRequest, "invalid invitation id")
+	}
+	if err := h.invitationService.Revoke(c.UserContext(), id); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *InvitationHandler) Accept(c *fiber.Ctx) error {
+	var req dto.AcceptInvitationDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	user, err := h.invitationService.Accept(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(user.ToResponse())
+}
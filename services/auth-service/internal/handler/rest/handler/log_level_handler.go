@@ -0,0 +1,47 @@
+package auth_service
+
+import (
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/logger"
+	"github.com/content-management-system/auth-service/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LogLevelHandler lets administrators change module log levels at runtime.
+type LogLevelHandler struct {
+	loggers *logger.Registry
+	auditor service.Auditor
+	binder  *validation.Binder
+}
+
+func NewLogLevelHandler(loggers *logger.Registry, auditor service.Auditor, binder *validation.Binder) *LogLevelHandler {
+	return &LogLevelHandler{loggers: loggers, auditor: auditor, binder: binder}
+}
+
+func (h *LogLevelHandler) List(c *fiber.Ctx) error {
+	return c.JSON(h.loggers.Levels())
+}
+
+// Set changes the level of the :module logger; "root" changes every module
+// without a level of its own.
+func (h *LogLevelHandler) Set(c *fiber.Ctx) error {
+	var req dto.SetLogLevelDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	module := c.Params("module")
+	if _, ok := h.loggers.Levels()[module]; !ok {
+		return apperror.New(apperror.CodeNotFound, "unknown log module")
+	}
+	err := h.loggers.SetLevel(module, req.Level)
+	recordAudit(c.UserContext(), h.auditor, actorFrom(c), service.AuditActionLogLevelChange, nil, err, map[string]interface{}{
+		"module": module,
+		"level":  req.Level,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(h.loggers.Levels())
+}
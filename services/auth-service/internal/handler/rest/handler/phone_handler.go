@@ -0,0 +1,72 @@
+package auth_service
+
+import (
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PhoneHandler struct {
+	phoneService *service.PhoneService
+	binder       *validation.Binder
+}
+
+func NewPhoneHandler(ps *service.PhoneService, binder *validation.Binder) *PhoneHandler {
+	return &PhoneHandler{phoneService: ps, binder: binder}
+}
+
+func (h *PhoneHandler) StartVerification(c *fiber.Ctx) error {
+	if err := h.phoneService.StartVerification(c.UserContext(), middleware.CurrentUser(c).ID); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Verification code sent"})
+}
+
+func (h *PhoneHandler) ConfirmVerification(c *fiber.Ctx) error {
+	var req dto.VerifyCodeDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	user, err := h.phoneService.ConfirmVerification(c.UserContext(), middleware.CurrentUser(c).ID, req.Code)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user.ToProfileResponse())
+}
+
+func (h *PhoneHandler) UpdateMFA(c *fiber.Ctx) error {
+	var req dto.UpdateMFADto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	if err := h.phoneService.SetMFA(c.UserContext(), middleware.CurrentUser(c).ID, *req.Enabled, req.Password); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"mfa_enabled": *req.Enabled})
+}
+
+func (h *PhoneHandler) StartRecovery(c *fiber.Ctx) error {
+	var req dto.StartRecoveryDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	if err := h.phoneService.StartRecovery(c.UserContext(), req.Email); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the account has a verified phone number, a recovery code has been sent",
+	})
+}
+
+func (h *PhoneHandler) CompleteRecovery(c *fiber.Ctx) error {
+	var req dto.CompleteRecoveryDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	if err := h.phoneService.CompleteRecovery(c.UserContext(), req.Email, req.Code, req.NewPassword); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
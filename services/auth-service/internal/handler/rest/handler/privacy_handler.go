@@ -0,0 +1,122 @@
+package auth_service
+
+import (
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PrivacyHandler struct {
+	privacyService *service.PrivacyService
+	binder         *validation.Binder
+}
+
+func NewPrivacyHandler(ps *service.PrivacyService, binder *validation.Binder) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: ps, binder: binder}
+}
+
+func (h *PrivacyHandler) RequestOwnExport(c *fiber.Ctx) error {
+	export, err := h.privacyService.RequestExport(c.UserContext(), actorFrom(c), middleware.CurrentUser(c).ID)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(export)
+}
+
+func (h *PrivacyHandler) ListOwnExports(c *fiber.Ctx) error {
+	exports, err := h.privacyService.ListExports(c.UserContext(), middleware.CurrentUser(c).ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(exports)
+}
+
+func (h *PrivacyHandler) GetOwnExport(c *fiber.Ctx) error {
+	id, err := exportIDParam(c)
+	if err != nil {
+		return err
+	}
+	owner := middleware.CurrentUser(c).ID
+	export, err := h.privacyService.GetExport(c.UserContext(), id, &owner)
+	if err != nil {
+		return err
+	}
+	return c.JSON(export)
+}
+
+func (h *PrivacyHandler) DownloadOwnExport(c *fiber.Ctx) error {
+	id, err := exportIDParam(c)
+	if err != nil {
+		return err
+	}
+	owner := middleware.CurrentUser(c).ID
+	path, err := h.privacyService.OpenExport(c.UserContext(), actorFrom(c), id, &owner)
+	if err != nil {
+		return err
+	}
+	return c.Download(path, "data-export-"+id.String()+".zip")
+}
+
+func (h *PrivacyHandler) RequestExport(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	export, err := h.privacyService.RequestExport(c.UserContext(), actorFrom(c), userID)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(export)
+}
+
+func (h *PrivacyHandler) GetExport(c *fiber.Ctx) error {
+	id, err := exportIDParam(c)
+	if err != nil {
+		return err
+	}
+	export, err := h.privacyService.GetExport(c.UserContext(), id, nil)
+	if err != nil {
+		return err
+	}
+	return c.JSON(export)
+}
+
+func (h *PrivacyHandler) DownloadExport(c *fiber.Ctx) error {
+	id, err := exportIDParam(c)
+	if err != nil {
+		return err
+	}
+	path, err := h.privacyService.OpenExport(c.UserContext(), actorFrom(c), id, nil)
+	if err != nil {
+		return err
+	}
+	return c.Download(path, "data-export-"+id.String()+".zip")
+}
+
+func (h *PrivacyHandler) Erase(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req dto.StatusReasonDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	user, err := h.privacyService.Erase(c.UserContext(), actorFrom(c), userID, req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user)
+}
+
+func exportIDParam(c *fiber.Ctx) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, apperror.New(apperror.CodeBadRequest, "invalid export id")
+	}
+	return id, nil
+}
@@ -0,0 +1,114 @@
+package auth_service
+
+import (
+	"io"
+
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProfileHandler struct {
+	profileService *service.ProfileService
+	auditor        service.Auditor
+	binder         *validation.Binder
+}
+
+func NewProfileHandler(ps *service.ProfileService, auditor service.Auditor, binder *validation.Binder) *ProfileHandler {
+	return &ProfileHandler{profileService: ps, auditor: auditor, binder: binder}
+}
+
+func (h *ProfileHandler) AvatarDir() string {
+	return h.profileService.AvatarDir()
+}
+
+func (h *ProfileHandler) Get(c *fiber.Ctx) error {
+	user, err := h.profileService.GetProfile(c.UserContext(), middleware.CurrentUser(c).ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user.ToProfileResponse())
+}
+
+func (h *ProfileHandler) Update(c *fiber.Ctx) error {
+	var req dto.UpdateProfileDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	user, err := h.profileService.UpdateProfile(c.UserContext(), middleware.CurrentUser(c).ID, req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user.ToProfileResponse())
+}
+
+func (h *ProfileHandler) ChangeEmail(c *fiber.Ctx) error {
+	var req dto.ChangeEmailDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	userID := middleware.CurrentUser(c).ID
+	err := h.profileService.RequestEmailChange(c.UserContext(), userID, req.NewEmail, req.Password)
+	recordAudit(c.UserContext(), h.auditor, actorFrom(c), service.AuditActionEmailChange, &userID, err, map[string]interface{}{"stage": "requested"})
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Confirmation sent to the new email address"})
+}
+
+func (h *ProfileHandler) ConfirmEmail(c *fiber.Ctx) error {
+	var req dto.ConfirmEmailDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	user, err := h.profileService.ConfirmEmailChange(c.UserContext(), req.Token)
+	if err != nil {
+		recordAudit(c.UserContext(), h.auditor, actorFrom(c), service.AuditActionEmailChange, nil, err, map[string]interface{}{"stage": "confirmed"})
+		return err
+	}
+	recordAudit(c.UserContext(), h.auditor, actorAs(c, user.ID), service.AuditActionEmailChange, &user.ID, nil, map[string]interface{}{"stage": "confirmed"})
+	return c.JSON(user.ToResponse())
+}
+
+func (h *ProfileHandler) ChangePassword(c *fiber.Ctx) error {
+	var req dto.ChangePasswordDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	userID := middleware.CurrentUser(c).ID
+	err := h.profileService.ChangePassword(c.UserContext(), userID, req.CurrentPassword, req.NewPassword)
+	recordAudit(c.UserContext(), h.auditor, actorFrom(c), service.AuditActionPasswordChange, &userID, err, nil)
+	if err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *ProfileHandler) UploadAvatar(c *fiber.Ctx) error {
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		return apperror.New(apperror.CodeBadRequest, "multipart field \"avatar\" is required")
+	}
+	if file.Size > service.MaxAvatarSize {
+		return apperror.New(apperror.CodeBadRequest, "avatar exceeds the maximum size of 2 MB")
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, service.MaxAvatarSize+1))
+	if err != nil {
+		return err
+	}
+
+	user, err := h.profileService.UploadAvatar(c.UserContext(), middleware.CurrentUser(c).ID, data)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user.ToProfileResponse())
+}
@@ -0,0 +1,149 @@
+package auth_service
+
+import (
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type UserAdminHandler struct {
+	lifecycleService *service.LifecycleService
+	userService      *service.UserService
+	roleService      *service.RoleService
+	auditor          service.Auditor
+	binder           *validation.Binder
+}
+
+func NewUserAdminHandler(ls *service.LifecycleService, us *service.UserService, rs *service.RoleService, auditor service.Auditor, binder *validation.Binder) *UserAdminHandler {
+	return &UserAdminHandler{lifecycleService: ls, userService: us, roleService: rs, auditor: auditor, binder: binder}
+}
+
+func (h *UserAdminHandler) List(c *fiber.Ctx) error {
+	users, err := h.lifecycleService.ListUsers(c.UserContext(), c.Query("status"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(users)
+}
+
+func (h *UserAdminHandler) ChangeStatus(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req dto.ChangeUserStatusDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	user, err := h.lifecycleService.ChangeStatus(c.UserContext(), actorFrom(c), id, req.Status, req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user)
+}
+
+func (h *UserAdminHandler) ChangeRole(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req dto.ChangeUserRoleDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	if middleware.CurrentUser(c).ID == id {
+		return apperror.New(apperror.CodeForbidden, "you cannot change your own role")
+	}
+	user, previous, err := h.roleService.AssignRole(c.UserContext(), id, req.RoleID)
+	details := map[string]interface{}{"role_id": req.RoleID}
+	if err == nil {
+		details["previous_role_id"] = previous
+	}
+	recordAudit(c.UserContext(), h.auditor, actorFrom(c), service.AuditActionRoleChange, &id, err, details)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user.ToResponse())
+}
+
+func (h *UserAdminHandler) Delete(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req dto.StatusReasonDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	if middleware.CurrentUser(c).ID == id {
+		return apperror.New(apperror.CodeForbidden, "use DELETE /me to delete your own account")
+	}
+	user, err := h.lifecycleService.ChangeStatus(c.UserContext(), actorFrom(c), id, types.UserStatusPendingDeletion, req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user)
+}
+
+func (h *UserAdminHandler) Restore(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req dto.StatusReasonDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	user, err := h.lifecycleService.Restore(c.UserContext(), actorFrom(c), id, req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.JSON(user)
+}
+
+func (h *UserAdminHandler) History(c *fiber.Ctx) error {
+	id, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	changes, err := h.lifecycleService.History(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(changes)
+}
+
+// DeleteSelf schedules the caller's own account for deletion.
+func (h *UserAdminHandler) DeleteSelf(c *fiber.Ctx) error {
+	var req dto.DeleteAccountDto
+	if err := h.binder.Bind(c, &req); err != nil {
+		return err
+	}
+	current := middleware.CurrentUser(c)
+	details := map[string]interface{}{"status": types.UserStatusPendingDeletion, "self_service": true}
+	if _, err := h.userService.ValidatePassword(c.UserContext(), current.Email, req.Password); err != nil {
+		recordAudit(c.UserContext(), h.auditor, actorFrom(c), service.AuditActionStatusChange, &current.ID, err, details)
+		return err
+	}
+	user, err := h.lifecycleService.ChangeStatus(c.UserContext(), actorFrom(c), current.ID, types.UserStatusPendingDeletion, "requested by user")
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status":      user.Status,
+		"purge_after": user.PurgeAfter,
+	})
+}
+
+func userIDParam(c *fiber.Ctx) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, apperror.New(apperror.CodeBadRequest, "invalid user id")
+	}
+	return id, nil
+}
@@ -1,10 +1,23 @@
 package provider
 
 import (
+	"github.com/content-management-system/auth-service/internal/handler/graph"
 	authHandle "github.com/content-management-system/auth-service/internal/handler/rest/handler"
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
 	"go.uber.org/fx"
 )
 
 var Module = fx.Module("handler_module", fx.Provide(
 	authHandle.NewAuthHandler,
+	authHandle.NewAdminHandler,
+	authHandle.NewInvitationHandler,
+	authHandle.NewProfileHandler,
+	authHandle.NewPhoneHandler,
+	authHandle.NewUserAdminHandler,
+	authHandle.NewPrivacyHandler,
+	authHandle.NewAuditHandler,
+	authHandle.NewLogLevelHandler,
+	middleware.NewAuthMiddleware,
+	graph.NewResolver,
+	graph.NewServer,
 ))
@@ -1,9 +1,127 @@
 package dto
 
+import "github.com/google/uuid"
+
 type (
 	CreateUserDto struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Username string `json:"username" validate:"required,min=3,max=255"`
+		Email    string `json:"email" validate:"required,email,max=255"`
+		Password string `json:"password" validate:"required,min=8,max=72"`
+	}
+
+	RegisterUserDto struct {
+		Username   string `json:"username" validate:"required,min=3,max=255"`
+		Email      string `json:"email" validate:"required,email,max=255"`
+		Password   string `json:"password" validate:"required,min=8,max=72"`
+		InviteCode string `json:"invite_code" validate:"omitempty,max=128"`
+	}
+
+	LoginDto struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	RefreshTokenDto struct {
+		RefreshToken string `json:"refresh_token" validate:"required,jwt"`
+	}
+
+	CreateInviteCodeDto struct {
+		RoleID         uuid.UUID `json:"role_id" validate:"required"`
+		Email          string    `json:"email" validate:"omitempty,email,max=255"`
+		MaxUses        int       `json:"max_uses" validate:"gte=0,lte=10000"`
+		ExpiresInHours int       `json:"expires_in_hours" validate:"gte=0,lte=8760"`
+	}
+
+	CreateInvitationDto struct {
+		Email          string    `json:"email" validate:"required,email,max=255"`
+		RoleID         uuid.UUID `json:"role_id" validate:"required"`
+		ExpiresInHours int       `json:"expires_in_hours" validate:"gte=0,lte=720"`
+	}
+
+	AcceptInvitationDto struct {
+		Email            string `json:"email" validate:"required,email,max=255"`
+		Token            string `json:"token" validate:"required,max=256"`
+		Username         string `json:"username" validate:"required,min=3,max=255"`
+		Password         string `json:"password" validate:"required_without=IdentityToken,omitempty,min=8,max=72"`
+		IdentityProvider string `json:"identity_provider" validate:"required_with=IdentityToken,omitempty,max=64"`
+		IdentityToken    string `json:"identity_token" validate:"omitempty,max=4096"`
+	}
+
+	UpdateProfileDto struct {
+		Username    *string `json:"username" validate:"omitnil,min=3,max=255"`
+		Name        *string `json:"name" validate:"omitnil,max=255"`
+		Address     *string `json:"address" validate:"omitnil,max=2048"`
+		PhoneNumber *string `json:"phone_number" validate:"omitempty,e164"`
+	}
+
+	ChangeEmailDto struct {
+		NewEmail string `json:"new_email" validate:"required,email,max=255"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	ConfirmEmailDto struct {
+		Token string `json:"token" validate:"required,max=256"`
+	}
+
+	ChangePasswordDto struct {
+		CurrentPassword string `json:"current_password" validate:"required"`
+		NewPassword     string `json:"new_password" validate:"required,min=8,max=72,nefield=CurrentPassword"`
+	}
+
+	VerifyCodeDto struct {
+		Code string `json:"code" validate:"required,numeric,len=6"`
+	}
+
+	UpdateMFADto struct {
+		Enabled  *bool  `json:"enabled" validate:"required"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	VerifyMFADto struct {
+		Session string `json:"session" validate:"required,jwt"`
+		Code    string `json:"code" validate:"required,numeric,len=6"`
+	}
+
+	StartRecoveryDto struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	CompleteRecoveryDto struct {
+		Email       string `json:"email" validate:"required,email"`
+		Code        string `json:"code" validate:"required,numeric,len=6"`
+		NewPassword string `json:"new_password" validate:"required,min=8,max=72"`
+	}
+
+	ChangeUserStatusDto struct {
+		Status string `json:"status" validate:"required,oneof=active suspended disabled"`
+		Reason string `json:"reason" validate:"required,max=1000"`
+	}
+
+	StatusReasonDto struct {
+		Reason string `json:"reason" validate:"required,max=1000"`
+	}
+
+	DeleteAccountDto struct {
+		Password string `json:"password" validate:"required"`
+	}
+
+	ChangeUserRoleDto struct {
+		RoleID uuid.UUID `json:"role_id" validate:"required"`
+	}
+
+	AuditQueryDto struct {
+		ActorID  string `query:"actor_id" json:"actor_id" validate:"omitempty,uuid"`
+		TargetID string `query:"target_id" json:"target_id" validate:"omitempty,uuid"`
+		Action   string `query:"action" json:"action" validate:"omitempty,max=64"`
+		Outcome  string `query:"outcome" json:"outcome" validate:"omitempty,oneof=success failure"`
+		From     string `query:"from" json:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		To       string `query:"to" json:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		Page     int    `query:"page" json:"page" validate:"gte=0"`
+		PageSize int    `query:"page_size" json:"page_size" validate:"gte=0,lte=500"`
+		Format   string `query:"format" json:"format" validate:"omitempty,oneof=csv ndjson"`
+	}
+
+	SetLogLevelDto struct {
+		Level string `json:"level" validate:"required,oneof=panic fatal error warn warning info debug trace"`
 	}
 )
@@ -0,0 +1,38 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntry is one row of the append-only security audit log. Each entry
+// carries the hash of its predecessor, so removing or altering an entry
+// breaks the chain from that point on.
+type AuditEntry struct {
+	ID        uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	ActorID   *uuid.UUID      `gorm:"type:uuid;index" json:"actor_id,omitempty"`
+	Action    string          `gorm:"type:varchar(64);not null;index" json:"action"`
+	TargetID  *uuid.UUID      `gorm:"type:uuid;index" json:"target_id,omitempty"`
+	IPAddress string          `gorm:"type:varchar(64)" json:"ip_address"`
+	UserAgent string          `gorm:"type:varchar(512)" json:"user_agent"`
+	Outcome   string          `gorm:"type:varchar(16);not null" json:"outcome"`
+	Details   json.RawMessage `gorm:"type:jsonb" json:"details,omitempty"`
+	PrevHash  string          `gorm:"type:varchar(64);not null" json:"prev_hash"`
+	Hash      string          `gorm:"type:varchar(64);not null;uniqueIndex" json:"hash"`
+	CreatedAt time.Time       `gorm:"not null;index" json:"created_at"`
+}
+
+type AuditPage struct {
+	Items    []AuditEntry `json:"items"`
+	Total    int64        `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
+
+type AuditVerification struct {
+	Valid    bool    `json:"valid"`
+	Checked  int     `json:"checked"`
+	BrokenAt *uint64 `json:"broken_at,omitempty"`
+}
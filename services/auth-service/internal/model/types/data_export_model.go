@@ -0,0 +1,28 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport tracks a request for an archive of everything held about a
+// user. The archive is built in the background and kept until ExpiresAt.
+type DataExport struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RequestedByID uuid.UUID  `gorm:"type:uuid;not null" json:"requested_by_id"`
+	Status        string     `gorm:"type:varchar(16);not null;index" json:"status"`
+	FilePath      string     `gorm:"type:varchar(512)" json:"-"`
+	SizeBytes     int64      `json:"size_bytes,omitempty"`
+	Error         string     `gorm:"type:text" json:"error,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
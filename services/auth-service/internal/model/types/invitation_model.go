@@ -0,0 +1,62 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+type Invitation struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Email          string     `gorm:"type:varchar(255);not null;index" json:"email"`
+	RoleID         uuid.UUID  `gorm:"type:uuid;not null" json:"role_id"`
+	InvitedByID    uuid.UUID  `gorm:"type:uuid;not null" json:"invited_by_id"`
+	TokenHash      string     `gorm:"type:char(64)" json:"-"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	SentCount      int        `gorm:"not null;default:0" json:"sent_count"`
+	LastSentAt     *time.Time `json:"last_sent_at,omitempty"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedUserID *uuid.UUID `gorm:"type:uuid" json:"accepted_user_id,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (i *Invitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case now.After(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+type InvitationResponse struct {
+	Invitation
+	Status string `json:"status"`
+}
+
+func (i *Invitation) ToResponse() *InvitationResponse {
+	return &InvitationResponse{Invitation: *i, Status: i.Status(time.Now())}
+}
+
+// UserIdentity links a local user to an account at an external identity
+// provider such as Cognito.
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Provider  string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject   string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_provider_subject" json:"subject"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InviteCode grants a specific role to whoever registers with it. When
+// Email is set, only that address may redeem the code.
+type InviteCode struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	CodeHash    string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	RoleID      uuid.UUID  `gorm:"type:uuid;not null" json:"role_id"`
+	Email       string     `gorm:"type:varchar(255)" json:"email,omitempty"`
+	MaxUses     int        `gorm:"not null;default:1" json:"max_uses"`
+	Uses        int        `gorm:"not null;default:0" json:"uses"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedByID uuid.UUID  `gorm:"type:uuid;not null" json:"created_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type InviteCodeResponse struct {
+	InviteCode
+	Code string `json:"code"`
+}
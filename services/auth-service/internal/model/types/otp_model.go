@@ -0,0 +1,27 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OTPPurposePhoneVerification = "phone_verification"
+	OTPPurposeMFA               = "mfa"
+	OTPPurposeRecovery          = "recovery"
+)
+
+// PhoneOTP is a one-time code sent by SMS. Only a salted hash of the code
+// is stored.
+type PhoneOTP struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index:idx_phone_otp_user_purpose" json:"user_id"`
+	Purpose     string     `gorm:"type:varchar(32);not null;index:idx_phone_otp_user_purpose" json:"purpose"`
+	PhoneNumber string     `gorm:"type:varchar(255);not null" json:"phone_number"`
+	CodeHash    string     `gorm:"type:char(64);not null" json:"-"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	ConsumedAt  *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
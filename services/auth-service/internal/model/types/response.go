@@ -1,17 +0,0 @@
-package types
-
-import "github.com/google/uuid"
-
-type UserResponse struct {
-	Id       uuid.UUID `json:"id"`
-	Username string    `json:"username"`
-	Email    string    `json:"email"`
-}
-
-func (u *User) ToResponse() *UserResponse {
-	return &UserResponse{
-		Id:       u.ID,
-		Username: u.Username,
-		Email:    u.Email,
-	}
-}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const TokenPurposeEmailChange = "email_change"
+
+// VerificationToken is a single-use secret mailed to a user to confirm an
+// action. NewValue carries the value being confirmed, e.g. the new email.
+type VerificationToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Purpose    string     `gorm:"type:varchar(32);not null" json:"purpose"`
+	TokenHash  string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	NewValue   string     `gorm:"type:varchar(255)" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
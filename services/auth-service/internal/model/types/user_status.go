@@ -0,0 +1,46 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	UserStatusActive          = "active"
+	UserStatusSuspended       = "suspended"
+	UserStatusDisabled        = "disabled"
+	UserStatusPendingDeletion = "pending_deletion"
+	UserStatusDeleted         = "deleted"
+	UserStatusErased          = "erased"
+)
+
+var userStatusTransitions = map[string][]string{
+	UserStatusActive:          {UserStatusSuspended, UserStatusDisabled, UserStatusPendingDeletion, UserStatusErased},
+	UserStatusSuspended:       {UserStatusActive, UserStatusDisabled, UserStatusPendingDeletion, UserStatusErased},
+	UserStatusDisabled:        {UserStatusActive, UserStatusPendingDeletion, UserStatusErased},
+	UserStatusPendingDeletion: {UserStatusActive, UserStatusDeleted, UserStatusErased},
+	UserStatusDeleted:         {UserStatusActive, UserStatusErased},
+	UserStatusErased:          {},
+}
+
+func CanTransitionUserStatus(from, to string) bool {
+	for _, allowed := range userStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// UserStatusChange records every lifecycle transition. ChangedByID is nil
+// for transitions made by the system, e.g. the purge job.
+type UserStatusChange struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	FromStatus  string     `gorm:"type:varchar(32);not null" json:"from_status"`
+	ToStatus    string     `gorm:"type:varchar(32);not null" json:"to_status"`
+	Reason      string     `gorm:"type:text;not null" json:"reason"`
+	ChangedByID *uuid.UUID `gorm:"type:uuid" json:"changed_by_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,599 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NewRepositories returns repositories that run each call on its own.
+func NewRepositories(db *db.DB) Repositories {
+	return WithTx(db.Conn)
+}
+
+// WithTx returns repositories bound to tx, for code that already manages
+// a GORM transaction itself.
+func WithTx(tx *gorm.DB) Repositories {
+	return Repositories{
+		Users:         &gormUsers{db: tx},
+		Roles:         &gormRoles{db: tx},
+		Sessions:      &gormSessions{db: tx},
+		Tokens:        &gormTokens{db: tx},
+		InviteCodes:   &gormInviteCodes{db: tx},
+		Invitations:   &gormInvitations{db: tx},
+		Identities:    &gormIdentities{db: tx},
+		OTPs:          &gormOTPs{db: tx},
+		StatusChanges: &gormStatusChanges{db: tx},
+		DataExports:   &gormDataExports{db: tx},
+		Audit:         &gormAudit{db: tx},
+	}
+}
+
+type gormUnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *db.DB) UnitOfWork {
+	return &gormUnitOfWork{db: db.Conn}
+}
+
+func (u *gormUnitOfWork) Do(ctx context.Context, fn func(r Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(WithTx(tx))
+	})
+}
+
+func first[T any](query *gorm.DB) (*T, error) {
+	var out T
+	if err := query.First(&out).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func exists(query *gorm.DB) (bool, error) {
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+type gormUsers struct {
+	db *gorm.DB
+}
+
+func (r *gormUsers) List(ctx context.Context) ([]types.User, error) {
+	var users []types.User
+	if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// likeEscaper escapes the LIKE wildcards, using Postgres' default escape
+// character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *gormUsers) filtered(ctx context.Context, filter UserFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&types.User{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.RoleID != nil {
+		query = query.Where("role_id = ?", *filter.RoleID)
+	}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(filter.Search)) + "%"
+		query = query.Where("(lower(username) LIKE ? OR email_normalized LIKE ?)", pattern, pattern)
+	}
+	return query
+}
+
+func (r *gormUsers) Page(ctx context.Context, filter UserFilter, after *UserCursor, limit int) ([]types.User, error) {
+	query := r.filtered(ctx, filter)
+	if after != nil {
+		query = query.Where("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+	}
+	var users []types.User
+	if err := query.Order("created_at, id").Limit(limit).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUsers) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	var count int64
+	if err := r.filtered(ctx, filter).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *gormUsers) FindByID(ctx context.Context, id uuid.UUID) (*types.User, error) {
+	return first[types.User](r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+func (r *gormUsers) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]types.User, error) {
+	var users []types.User
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUsers) FindByIDWithRole(ctx context.Context, id uuid.UUID) (*types.User, error) {
+	return first[types.User](r.db.WithContext(ctx).Preload("Role.Permissions").Where("id = ?", id))
+}
+
+func (r *gormUsers) FindByEmail(ctx context.Context, email string) (*types.User, error) {
+	return first[types.User](r.db.WithContext(ctx).Where("email_normalized = lower(?)", email))
+}
+
+func (r *gormUsers) UsernameTaken(ctx context.Context, username string, exceptID uuid.UUID) (bool, error) {
+	return exists(r.db.WithContext(ctx).Model(&types.User{}).Where("username = ? AND id <> ?", username, exceptID))
+}
+
+func (r *gormUsers) EmailTaken(ctx context.Context, email string, exceptID uuid.UUID) (bool, error) {
+	return exists(r.db.WithContext(ctx).Model(&types.User{}).Where("email_normalized = lower(?) AND id <> ?", email, exceptID))
+}
+
+func (r *gormUsers) Create(ctx context.Context, user *types.User) error {
+	return translateUserError(r.db.WithContext(ctx).Omit("Role").Create(user).Error)
+}
+
+func (r *gormUsers) Update(ctx context.Context, user *types.User, columns ...string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	err := r.db.WithContext(ctx).Unscoped().Model(&types.User{ID: user.ID}).Select(columns).Omit(clause.Associations).Updates(user).Error
+	return translateUserError(err)
+}
+
+func (r *gormUsers) FindAnyByID(ctx context.Context, id uuid.UUID) (*types.User, error) {
+	return first[types.User](r.db.WithContext(ctx).Unscoped().Preload("Role.Permissions").Where("id = ?", id))
+}
+
+func (r *gormUsers) ListWithRole(ctx context.Context, status string) ([]types.User, error) {
+	query := r.db.WithContext(ctx).Preload("Role").Order("created_at DESC")
+	if status != "" {
+		query = query.Unscoped().Where("status = ?", status)
+	}
+	var users []types.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUsers) DueForPurge(ctx context.Context, status string, now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Unscoped().Model(&types.User{}).
+		Where("status = ? AND purge_after <= ?", status, now).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *gormUsers) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Unscoped().Where("id = ?", id).Delete(&types.User{}).Error
+}
+
+// uniqueViolation is the Postgres SQLSTATE for unique_violation.
+const uniqueViolation = "23505"
+
+// userConflicts maps the unique indexes on users to the error a caller
+// sees when an insert or update violates them.
+var userConflicts = map[string]error{
+	"idx_users_email_normalized": apperror.ErrEmailTaken,
+	"idx_users_username":         apperror.ErrUsernameTaken,
+}
+
+// translateUserError turns unique violations into typed conflicts. The
+// database constraint is the only reliable check: two concurrent inserts
+// can both pass a prior lookup.
+func translateUserError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		if conflict, ok := userConflicts[pgErr.ConstraintName]; ok {
+			return conflict
+		}
+	}
+	return err
+}
+
+type gormRoles struct {
+	db *gorm.DB
+}
+
+func (r *gormRoles) List(ctx context.Context) ([]types.Role, error) {
+	var roles []types.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *gormRoles) FindByID(ctx context.Context, id uuid.UUID) (*types.Role, error) {
+	return first[types.Role](r.db.WithContext(ctx).Preload("Permissions").Where("id = ?", id))
+}
+
+func (r *gormRoles) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]types.Role, error) {
+	var roles []types.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Where("id IN ?", ids).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *gormRoles) FindByName(ctx context.Context, name string) (*types.Role, error) {
+	return first[types.Role](r.db.WithContext(ctx).Where("name = ?", name))
+}
+
+type gormSessions struct {
+	db *gorm.DB
+}
+
+func (r *gormSessions) Create(ctx context.Context, session *types.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *gormSessions) Find(ctx context.Context, id, userID uuid.UUID) (*types.Session, error) {
+	return first[types.Session](r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID))
+}
+
+func (r *gormSessions) ListForUser(ctx context.Context, userID uuid.UUID) ([]types.Session, error) {
+	var sessions []types.Session
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *gormSessions) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&types.Session{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func (r *gormSessions) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&types.Session{}).Where("id = ?", id).Update("revoked_at", at).Error
+}
+
+func (r *gormSessions) RevokeAllForUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&types.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+func (r *gormSessions) DeleteForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&types.Session{}).Error
+}
+
+type gormTokens struct {
+	db *gorm.DB
+}
+
+func (r *gormTokens) Create(ctx context.Context, token *types.VerificationToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormTokens) FindByHash(ctx context.Context, purpose, hash string) (*types.VerificationToken, error) {
+	return first[types.VerificationToken](r.db.WithContext(ctx).Where("token_hash = ? AND purpose = ?", hash, purpose))
+}
+
+func (r *gormTokens) Consume(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&types.VerificationToken{}).Where("id = ?", id).Update("consumed_at", at).Error
+}
+
+func (r *gormTokens) ConsumeAllForUser(ctx context.Context, userID uuid.UUID, purpose string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&types.VerificationToken{}).
+		Where("user_id = ? AND purpose = ? AND consumed_at IS NULL", userID, purpose).
+		Update("consumed_at", at).Error
+}
+
+func (r *gormTokens) DeleteForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&types.VerificationToken{}).Error
+}
+
+type gormInviteCodes struct {
+	db *gorm.DB
+}
+
+func (r *gormInviteCodes) Create(ctx context.Context, invite *types.InviteCode) error {
+	return r.db.WithContext(ctx).Create(invite).Error
+}
+
+func (r *gormInviteCodes) List(ctx context.Context) ([]types.InviteCode, error) {
+	var invites []types.InviteCode
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&invites).Error; err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (r *gormInviteCodes) FindByHash(ctx context.Context, hash string) (*types.InviteCode, error) {
+	return first[types.InviteCode](r.db.WithContext(ctx).Where("code_hash = ?", hash))
+}
+
+// Redeem checks and counts the use in one statement so concurrent
+// registrations cannot use a code more often than allowed.
+func (r *gormInviteCodes) Redeem(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&types.InviteCode{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Where("max_uses = 0 OR uses < max_uses").
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormInviteCodes) Revoke(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&types.InviteCode{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// openInvitation matches invitations that are neither accepted nor revoked.
+const openInvitation = "accepted_at IS NULL AND revoked_at IS NULL"
+
+type gormInvitations struct {
+	db *gorm.DB
+}
+
+func (r *gormInvitations) Save(ctx context.Context, inv *types.Invitation) error {
+	return r.db.WithContext(ctx).Save(inv).Error
+}
+
+func (r *gormInvitations) FindByID(ctx context.Context, id uuid.UUID) (*types.Invitation, error) {
+	return first[types.Invitation](r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+func (r *gormInvitations) FindOpen(ctx context.Context, email string) (*types.Invitation, error) {
+	return first[types.Invitation](r.db.WithContext(ctx).Where("email = ? AND "+openInvitation, email).Order("created_at DESC"))
+}
+
+func (r *gormInvitations) HasPending(ctx context.Context, email string, now time.Time) (bool, error) {
+	return exists(r.db.WithContext(ctx).Model(&types.Invitation{}).Where("email = ? AND "+openInvitation+" AND expires_at > ?", email, now))
+}
+
+func (r *gormInvitations) List(ctx context.Context, status string, now time.Time) ([]types.Invitation, error) {
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+	switch status {
+	case types.InvitationPending:
+		query = query.Where(openInvitation+" AND expires_at > ?", now)
+	case types.InvitationExpired:
+		query = query.Where(openInvitation+" AND expires_at <= ?", now)
+	case types.InvitationAccepted:
+		query = query.Where("accepted_at IS NOT NULL")
+	case types.InvitationRevoked:
+		query = query.Where("revoked_at IS NOT NULL")
+	}
+	var invitations []types.Invitation
+	if err := query.Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *gormInvitations) ListAcceptedBy(ctx context.Context, userID uuid.UUID) ([]types.Invitation, error) {
+	var invitations []types.Invitation
+	if err := r.db.WithContext(ctx).Where("accepted_user_id = ?", userID).Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *gormInvitations) Accept(ctx context.Context, id, userID uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&types.Invitation{}).
+		Where("id = ? AND "+openInvitation, id).
+		Updates(map[string]interface{}{"accepted_at": at, "accepted_user_id": userID})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormInvitations) Revoke(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&types.Invitation{}).
+		Where("id = ? AND "+openInvitation, id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormInvitations) ReplaceEmail(ctx context.Context, userID uuid.UUID, email, replacement string) error {
+	return r.db.WithContext(ctx).Model(&types.Invitation{}).
+		Where("accepted_user_id = ? OR email = ?", userID, email).
+		Update("email", replacement).Error
+}
+
+type gormIdentities struct {
+	db *gorm.DB
+}
+
+func (r *gormIdentities) Create(ctx context.Context, identity *types.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *gormIdentities) ListForUser(ctx context.Context, userID uuid.UUID) ([]types.UserIdentity, error) {
+	var identities []types.UserIdentity
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *gormIdentities) DeleteForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&types.UserIdentity{}).Error
+}
+
+type gormOTPs struct {
+	db *gorm.DB
+}
+
+func (r *gormOTPs) Create(ctx context.Context, otp *types.PhoneOTP) error {
+	return r.db.WithContext(ctx).Create(otp).Error
+}
+
+func (r *gormOTPs) Latest(ctx context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error) {
+	return first[types.PhoneOTP](r.db.WithContext(ctx).Where("user_id = ? AND purpose = ?", userID, purpose).Order("created_at DESC"))
+}
+
+func (r *gormOTPs) Outstanding(ctx context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error) {
+	return first[types.PhoneOTP](r.db.WithContext(ctx).
+		Where("user_id = ? AND purpose = ? AND consumed_at IS NULL", userID, purpose).
+		Order("created_at DESC"))
+}
+
+func (r *gormOTPs) CountAttempt(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&types.PhoneOTP{}).Where("id = ?", id).Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+func (r *gormOTPs) Consume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&types.PhoneOTP{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormOTPs) ConsumeAllForUser(ctx context.Context, userID uuid.UUID, purpose string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&types.PhoneOTP{}).
+		Where("user_id = ? AND purpose = ? AND consumed_at IS NULL", userID, purpose).
+		Update("consumed_at", at).Error
+}
+
+func (r *gormOTPs) DeleteForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&types.PhoneOTP{}).Error
+}
+
+type gormStatusChanges struct {
+	db *gorm.DB
+}
+
+func (r *gormStatusChanges) Create(ctx context.Context, change *types.UserStatusChange) error {
+	return r.db.WithContext(ctx).Create(change).Error
+}
+
+func (r *gormStatusChanges) ListForUser(ctx context.Context, userID uuid.UUID) ([]types.UserStatusChange, error) {
+	var changes []types.UserStatusChange
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+type gormDataExports struct {
+	db *gorm.DB
+}
+
+func (r *gormDataExports) Create(ctx context.Context, export *types.DataExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+func (r *gormDataExports) Find(ctx context.Context, id uuid.UUID, ownerID *uuid.UUID) (*types.DataExport, error) {
+	query := r.db.WithContext(ctx).Where("id = ?", id)
+	if ownerID != nil {
+		query = query.Where("user_id = ?", *ownerID)
+	}
+	return first[types.DataExport](query)
+}
+
+func (r *gormDataExports) FindPending(ctx context.Context, userID uuid.UUID) (*types.DataExport, error) {
+	return first[types.DataExport](r.db.WithContext(ctx).Where("user_id = ? AND status = ?", userID, types.DataExportPending))
+}
+
+func (r *gormDataExports) ListForUser(ctx context.Context, userID uuid.UUID) ([]types.DataExport, error) {
+	var exports []types.DataExport
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func (r *gormDataExports) ClaimPending(ctx context.Context) (*types.DataExport, error) {
+	return first[types.DataExport](r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ?", types.DataExportPending).
+		Order("created_at"))
+}
+
+func (r *gormDataExports) ListExpired(ctx context.Context, now time.Time) ([]types.DataExport, error) {
+	var exports []types.DataExport
+	if err := r.db.WithContext(ctx).Where("status = ? AND expires_at <= ?", types.DataExportReady, now).Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func (r *gormDataExports) Update(ctx context.Context, export *types.DataExport, columns ...string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&types.DataExport{ID: export.ID}).Select(columns).Updates(export).Error
+}
+
+func (r *gormDataExports) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&types.DataExport{}).Error
+}
+
+func (r *gormDataExports) DeleteForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&types.DataExport{}).Error
+}
+
+// auditChainLock is the advisory lock key that serializes appends so every
+// entry links to the one committed before it.
+const auditChainLock = 7_300_421
+
+type gormAudit struct {
+	db *gorm.DB
+}
+
+func (r *gormAudit) Append(ctx context.Context, entry *types.AuditEntry, hash func(*types.AuditEntry) (string, error)) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+		return err
+	}
+	var last types.AuditEntry
+	if err := db.Select("hash").Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+	entry.PrevHash = last.Hash
+	sum, err := hash(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = sum
+	return db.Create(entry).Error
+}
+
+func (r *gormAudit) ListForUser(ctx context.Context, userID uuid.UUID) ([]types.AuditEntry, error) {
+	var entries []types.AuditEntry
+	if err := r.db.WithContext(ctx).Where("actor_id = ? OR target_id = ?", userID, userID).Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/migrations"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/migrate"
+	"github.com/content-management-system/auth-service/pkg/tracing"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to the database in TEST_DATABASE_DSN and migrates it.
+// Tests that need Postgres are skipped when it is not set.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := conn.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	m, err := migrate.New(sqlDB, migrations.FS, log)
+	require.NoError(t, err)
+	require.NoError(t, m.Up(context.Background(), 0))
+	return conn
+}
+
+func TestConcurrentUserCreationHasOneWinner(t *testing.T) {
+	conn := testDB(t)
+	role := types.Role{Name: "test-" + uuid.NewString()}
+	require.NoError(t, conn.Omit("Users", "Permissions").Create(&role).Error)
+	suffix := uuid.NewString()[:8]
+	t.Cleanup(func() {
+		conn.Unscoped().Where("role_id = ?", role.ID).Delete(&types.User{})
+		conn.Delete(&role)
+	})
+
+	uow := &gormUnitOfWork{db: conn}
+	const attempts = 8
+	var wg sync.WaitGroup
+	errs := make([]error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			email := fmt.Sprintf("race-%s@example.com", suffix)
+			if i%2 == 0 {
+				email = strings.ToUpper(email)
+			}
+			errs[i] = uow.Do(context.Background(), func(r Repositories) error {
+				return r.Users.Create(context.Background(), &types.User{
+					Username: fmt.Sprintf("race-%s-%d", suffix, i),
+					Email:    email,
+					Password: "x",
+					RoleID:   role.ID,
+					Status:   types.UserStatusActive,
+				})
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		if err == nil {
+			require.Equal(t, -1, winner, "more than one registration won")
+			winner = i
+			continue
+		}
+		require.ErrorIs(t, err, apperror.ErrEmailTaken)
+	}
+	require.NotEqual(t, -1, winner)
+
+	err := WithTx(conn).Users.Create(context.Background(), &types.User{
+		Username: fmt.Sprintf("race-%s-%d", suffix, winner),
+		Email:    "other-" + suffix + "@example.com",
+		Password: "x",
+		RoleID:   role.ID,
+	})
+	require.ErrorIs(t, err, apperror.ErrUsernameTaken)
+}
+
+// TestUserCreationRacingPastEmailCheck interleaves two registrations so
+// that both see the address as free before either inserts. The unique
+// index, not the check, must decide the winner.
+func TestUserCreationRacingPastEmailCheck(t *testing.T) {
+	conn := testDB(t)
+	ctx := context.Background()
+	role := types.Role{Name: "test-" + uuid.NewString()}
+	require.NoError(t, conn.Omit("Users", "Permissions").Create(&role).Error)
+	suffix := uuid.NewString()[:8]
+	t.Cleanup(func() {
+		conn.Unscoped().Where("role_id = ?", role.ID).Delete(&types.User{})
+		conn.Delete(&role)
+	})
+
+	email := fmt.Sprintf("interleave-%s@example.com", suffix)
+	register := func(r Repositories, name, email string, between func()) error {
+		taken, err := r.Users.EmailTaken(ctx, email, uuid.Nil)
+		between()
+		if err != nil {
+			return err
+		}
+		if taken {
+			return apperror.ErrEmailTaken
+		}
+		return r.Users.Create(ctx, &types.User{
+			Username: fmt.Sprintf("interleave-%s-%s", suffix, name),
+			Email:    email,
+			Password: "x",
+			RoleID:   role.ID,
+			Status:   types.UserStatusActive,
+		})
+	}
+
+	uow := &gormUnitOfWork{db: conn}
+	bChecked := make(chan struct{})
+	aDone := make(chan error, 1)
+	go func() {
+		aDone <- uow.Do(ctx, func(r Repositories) error {
+			return register(r, "a", email, func() { <-bChecked })
+		})
+	}()
+	var errA error
+	errB := uow.Do(ctx, func(r Repositories) error {
+		return register(r, "b", strings.ToUpper(email), func() {
+			close(bChecked)
+			errA = <-aDone
+		})
+	})
+
+	require.NoError(t, errA)
+	require.ErrorIs(t, errB, apperror.ErrEmailTaken)
+}
+
+func TestClaimPendingSkipsExportsClaimedElsewhere(t *testing.T) {
+	conn := testDB(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	// Older than anything else in the table, so these are claimed first.
+	epoch := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []uuid.UUID
+	for i := 0; i < 2; i++ {
+		export := types.DataExport{ID: uuid.New(), UserID: userID, RequestedByID: userID, Status: types.DataExportPending, CreatedAt: epoch.Add(time.Duration(i) * time.Second)}
+		require.NoError(t, conn.Create(&export).Error)
+		ids = append(ids, export.ID)
+	}
+	t.Cleanup(func() { conn.Where("user_id = ?", userID).Delete(&types.DataExport{}) })
+
+	uow := &gormUnitOfWork{db: conn}
+	claimed := make(chan uuid.UUID)
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- uow.Do(ctx, func(r Repositories) error {
+			export, err := r.DataExports.ClaimPending(ctx)
+			if err != nil {
+				close(claimed)
+				return err
+			}
+			claimed <- export.ID
+			<-release
+			return nil
+		})
+	}()
+	first, ok := <-claimed
+	require.True(t, ok, "first claim failed")
+
+	var second *types.DataExport
+	require.NoError(t, uow.Do(ctx, func(r Repositories) error {
+		var err error
+		second, err = r.DataExports.ClaimPending(ctx)
+		return err
+	}))
+	close(release)
+	require.NoError(t, <-done)
+
+	require.Equal(t, ids[0], first)
+	require.Equal(t, ids[1], second.ID, "an export claimed by another transaction must be skipped")
+}
+
+func TestRepositoryQueriesAreParentedToCallerSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	conn, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, conn.Use(tracing.NewGormPlugin(tp)))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	repos := WithTx(conn)
+	_, err = repos.Users.FindByID(ctx, uuid.New())
+	require.NoError(t, err)
+	require.NoError(t, repos.Sessions.Touch(ctx, uuid.New(), time.Now()))
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	for _, span := range spans[:2] {
+		require.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID(), span.Name)
+		require.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID(), span.Name)
+	}
+}
@@ -0,0 +1,1102 @@
+// Package memory implements the repository interfaces in process memory
+// for unit tests. A Store enforces the same unique keys as the database
+// and rolls back every change made by a failed unit of work.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/google/uuid"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	userSchema   = mustParse(&types.User{})
+	exportSchema = mustParse(&types.DataExport{})
+)
+
+func mustParse(model interface{}) *schema.Schema {
+	s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// copyColumns copies the named columns from src to dst, which point to
+// the model s describes.
+func copyColumns(s *schema.Schema, src, dst interface{}, columns []string) error {
+	from, to := reflect.ValueOf(src).Elem(), reflect.ValueOf(dst).Elem()
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("unknown %s column %q", s.Table, column)
+		}
+		field.ReflectValueOf(context.Background(), to).Set(field.ReflectValueOf(context.Background(), from))
+	}
+	return nil
+}
+
+type state struct {
+	users       map[uuid.UUID]types.User
+	roles       map[uuid.UUID]types.Role
+	sessions    map[uuid.UUID]types.Session
+	tokens      map[uuid.UUID]types.VerificationToken
+	inviteCodes map[uuid.UUID]types.InviteCode
+	invitations map[uuid.UUID]types.Invitation
+	identities  map[uuid.UUID]types.UserIdentity
+	otps        map[uuid.UUID]types.PhoneOTP
+	changes     map[uuid.UUID]types.UserStatusChange
+	exports     map[uuid.UUID]types.DataExport
+	audit       []types.AuditEntry
+}
+
+func (s *state) clone() *state {
+	return &state{
+		users:       cloneMap(s.users),
+		roles:       cloneMap(s.roles),
+		sessions:    cloneMap(s.sessions),
+		tokens:      cloneMap(s.tokens),
+		inviteCodes: cloneMap(s.inviteCodes),
+		invitations: cloneMap(s.invitations),
+		identities:  cloneMap(s.identities),
+		otps:        cloneMap(s.otps),
+		changes:     cloneMap(s.changes),
+		exports:     cloneMap(s.exports),
+		audit:       append([]types.AuditEntry(nil), s.audit...),
+	}
+}
+
+func cloneMap[T any](m map[uuid.UUID]T) map[uuid.UUID]T {
+	out := make(map[uuid.UUID]T, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// Store holds the fake tables. Units of work run one at a time.
+type Store struct {
+	mu   sync.Mutex
+	txMu sync.Mutex
+	data *state
+}
+
+func NewStore() *Store {
+	return &Store{data: &state{
+		users:       map[uuid.UUID]types.User{},
+		roles:       map[uuid.UUID]types.Role{},
+		sessions:    map[uuid.UUID]types.Session{},
+		tokens:      map[uuid.UUID]types.VerificationToken{},
+		inviteCodes: map[uuid.UUID]types.InviteCode{},
+		invitations: map[uuid.UUID]types.Invitation{},
+		identities:  map[uuid.UUID]types.UserIdentity{},
+		otps:        map[uuid.UUID]types.PhoneOTP{},
+		changes:     map[uuid.UUID]types.UserStatusChange{},
+		exports:     map[uuid.UUID]types.DataExport{},
+	}}
+}
+
+func (s *Store) Repositories() repository.Repositories {
+	return repository.Repositories{
+		Users:         &users{s},
+		Roles:         &roles{s},
+		Sessions:      &sessions{s},
+		Tokens:        &tokens{s},
+		InviteCodes:   &inviteCodes{s},
+		Invitations:   &invitations{s},
+		Identities:    &identities{s},
+		OTPs:          &otps{s},
+		StatusChanges: &statusChanges{s},
+		DataExports:   &dataExports{s},
+		Audit:         &audit{s},
+	}
+}
+
+func (s *Store) Do(_ context.Context, fn func(r repository.Repositories) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	snapshot := s.data.clone()
+	s.mu.Unlock()
+
+	if err := fn(s.Repositories()); err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// AddRole stores a role as-is, for seeding test fixtures.
+func (s *Store) AddRole(role types.Role) types.Role {
+	if role.ID == uuid.Nil {
+		role.ID = uuid.New()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.roles[role.ID] = role
+	return role
+}
+
+func (s *Store) with(fn func(d *state) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.data)
+}
+
+type users struct{ s *Store }
+
+func (r *users) List(_ context.Context) ([]types.User, error) {
+	var out []types.User
+	err := r.s.with(func(d *state) error {
+		for _, u := range d.users {
+			if u.DeletedAt.Valid {
+				continue
+			}
+			out = append(out, u)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, err
+}
+
+func matches(u types.User, filter repository.UserFilter) bool {
+	if filter.Status != "" && u.Status != filter.Status {
+		return false
+	}
+	if filter.RoleID != nil && u.RoleID != *filter.RoleID {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		return strings.Contains(strings.ToLower(u.Username), search) || strings.Contains(u.EmailNormalized, search)
+	}
+	return true
+}
+
+// before orders users the way the database does: by creation time, then ID.
+func before(a types.User, createdAt time.Time, id uuid.UUID) bool {
+	if !a.CreatedAt.Equal(createdAt) {
+		return a.CreatedAt.Before(createdAt)
+	}
+	return strings.Compare(a.ID.String(), id.String()) < 0
+}
+
+func (r *users) Page(_ context.Context, filter repository.UserFilter, after *repository.UserCursor, limit int) ([]types.User, error) {
+	var out []types.User
+	err := r.s.with(func(d *state) error {
+		for _, u := range d.users {
+			if u.DeletedAt.Valid || !matches(u, filter) {
+				continue
+			}
+			if after != nil && !before(types.User{CreatedAt: after.CreatedAt, ID: after.ID}, u.CreatedAt, u.ID) {
+				continue
+			}
+			out = append(out, u)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return before(out[i], out[j].CreatedAt, out[j].ID) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, err
+}
+
+func (r *users) Count(_ context.Context, filter repository.UserFilter) (int64, error) {
+	var count int64
+	err := r.s.with(func(d *state) error {
+		for _, u := range d.users {
+			if !u.DeletedAt.Valid && matches(u, filter) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *users) find(match func(types.User) bool, withRole bool) (*types.User, error) {
+	var found *types.User
+	err := r.s.with(func(d *state) error {
+		for _, u := range d.users {
+			if u.DeletedAt.Valid || !match(u) {
+				continue
+			}
+			if withRole {
+				u.Role = d.roles[u.RoleID]
+			}
+			found = &u
+			return nil
+		}
+		return repository.ErrNotFound
+	})
+	return found, err
+}
+
+func (r *users) FindByID(_ context.Context, id uuid.UUID) (*types.User, error) {
+	return r.find(func(u types.User) bool { return u.ID == id }, false)
+}
+
+func (r *users) FindByIDs(_ context.Context, ids []uuid.UUID) ([]types.User, error) {
+	var out []types.User
+	err := r.s.with(func(d *state) error {
+		for _, id := range ids {
+			if u, ok := d.users[id]; ok && !u.DeletedAt.Valid {
+				out = append(out, u)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *users) FindByIDWithRole(_ context.Context, id uuid.UUID) (*types.User, error) {
+	return r.find(func(u types.User) bool { return u.ID == id }, true)
+}
+
+func (r *users) FindByEmail(_ context.Context, email string) (*types.User, error) {
+	return r.find(func(u types.User) bool { return u.EmailNormalized == strings.ToLower(email) }, false)
+}
+
+func (r *users) taken(match func(types.User) bool, exceptID uuid.UUID) (bool, error) {
+	taken := false
+	err := r.s.with(func(d *state) error {
+		for _, u := range d.users {
+			if u.ID != exceptID && !u.DeletedAt.Valid && match(u) {
+				taken = true
+			}
+		}
+		return nil
+	})
+	return taken, err
+}
+
+func (r *users) UsernameTaken(_ context.Context, username string, exceptID uuid.UUID) (bool, error) {
+	return r.taken(func(u types.User) bool { return u.Username == username }, exceptID)
+}
+
+func (r *users) EmailTaken(_ context.Context, email string, exceptID uuid.UUID) (bool, error) {
+	return r.taken(func(u types.User) bool { return u.EmailNormalized == strings.ToLower(email) }, exceptID)
+}
+
+// checkUnique mirrors the unique indexes on users, which also cover
+// soft-deleted rows. It fills EmailNormalized the way the database's
+// generated column does.
+func checkUnique(d *state, user *types.User) error {
+	user.EmailNormalized = strings.ToLower(user.Email)
+	for _, u := range d.users {
+		if u.ID == user.ID {
+			continue
+		}
+		if u.EmailNormalized == user.EmailNormalized {
+			return apperror.ErrEmailTaken
+		}
+		if u.Username == user.Username {
+			return apperror.ErrUsernameTaken
+		}
+	}
+	return nil
+}
+
+func (r *users) Create(_ context.Context, user *types.User) error {
+	if err := user.BeforeCreate(nil); err != nil {
+		return err
+	}
+	return r.s.with(func(d *state) error {
+		if _, ok := d.users[user.ID]; ok {
+			return fmt.Errorf("user %s already exists", user.ID)
+		}
+		if err := checkUnique(d, user); err != nil {
+			return err
+		}
+		now := time.Now()
+		user.CreatedAt, user.UpdatedAt = now, now
+		stored := *user
+		stored.Role = types.Role{}
+		d.users[user.ID] = stored
+		return nil
+	})
+}
+
+func (r *users) Update(_ context.Context, user *types.User, columns ...string) error {
+	return r.s.with(func(d *state) error {
+		stored, ok := d.users[user.ID]
+		if !ok {
+			return nil
+		}
+		if err := copyColumns(userSchema, user, &stored, columns); err != nil {
+			return err
+		}
+		if err := checkUnique(d, &stored); err != nil {
+			return err
+		}
+		stored.UpdatedAt = time.Now()
+		d.users[user.ID] = stored
+		return nil
+	})
+}
+
+func (r *users) FindAnyByID(_ context.Context, id uuid.UUID) (*types.User, error) {
+	var found *types.User
+	err := r.s.with(func(d *state) error {
+		u, ok := d.users[id]
+		if !ok {
+			return repository.ErrNotFound
+		}
+		u.Role = d.roles[u.RoleID]
+		found = &u
+		return nil
+	})
+	return found, err
+}
+
+func (r *users) ListWithRole(_ context.Context, status string) ([]types.User, error) {
+	var out []types.User
+	err := r.s.with(func(d *state) error {
+		for _, u := range d.users {
+			if (status == "" && u.DeletedAt.Valid) || (status != "" && u.Status != status) {
+				continue
+			}
+			u.Role = d.roles[u.RoleID]
+			out = append(out, u)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, err
+}
+
+func (r *users) DueForPurge(_ context.Context, status string, now time.Time) ([]uuid.UUID, error) {
+	var out []uuid.UUID
+	err := r.s.with(func(d *state) error {
+		for _, u := range d.users {
+			if u.Status == status && u.PurgeAfter != nil && !u.PurgeAfter.After(now) {
+				out = append(out, u.ID)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *users) Delete(_ context.Context, id uuid.UUID) error {
+	return r.s.with(func(d *state) error {
+		delete(d.users, id)
+		return nil
+	})
+}
+
+type roles struct{ s *Store }
+
+func (r *roles) List(_ context.Context) ([]types.Role, error) {
+	var out []types.Role
+	err := r.s.with(func(d *state) error {
+		for _, role := range d.roles {
+			out = append(out, role)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, err
+}
+
+func (r *roles) find(match func(types.Role) bool, withPermissions bool) (*types.Role, error) {
+	var found *types.Role
+	err := r.s.with(func(d *state) error {
+		for _, role := range d.roles {
+			if match(role) {
+				if !withPermissions {
+					role.Permissions = nil
+				}
+				found = &role
+				return nil
+			}
+		}
+		return repository.ErrNotFound
+	})
+	return found, err
+}
+
+func (r *roles) FindByID(_ context.Context, id uuid.UUID) (*types.Role, error) {
+	return r.find(func(role types.Role) bool { return role.ID == id }, true)
+}
+
+func (r *roles) FindByIDs(_ context.Context, ids []uuid.UUID) ([]types.Role, error) {
+	var out []types.Role
+	err := r.s.with(func(d *state) error {
+		for _, id := range ids {
+			if role, ok := d.roles[id]; ok {
+				out = append(out, role)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *roles) FindByName(_ context.Context, name string) (*types.Role, error) {
+	return r.find(func(role types.Role) bool { return role.Name == name }, false)
+}
+
+type sessions struct{ s *Store }
+
+func (r *sessions) Create(_ context.Context, session *types.Session) error {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	session.CreatedAt = time.Now()
+	return r.s.with(func(d *state) error {
+		d.sessions[session.ID] = *session
+		return nil
+	})
+}
+
+func (r *sessions) Find(_ context.Context, id, userID uuid.UUID) (*types.Session, error) {
+	var found *types.Session
+	err := r.s.with(func(d *state) error {
+		session, ok := d.sessions[id]
+		if !ok || session.UserID != userID {
+			return repository.ErrNotFound
+		}
+		found = &session
+		return nil
+	})
+	return found, err
+}
+
+func (r *sessions) ListForUser(_ context.Context, userID uuid.UUID) ([]types.Session, error) {
+	var out []types.Session
+	err := r.s.with(func(d *state) error {
+		for _, session := range d.sessions {
+			if session.UserID == userID {
+				out = append(out, session)
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, err
+}
+
+func (r *sessions) update(match func(types.Session) bool, apply func(*types.Session)) error {
+	return r.s.with(func(d *state) error {
+		for id, session := range d.sessions {
+			if match(session) {
+				apply(&session)
+				d.sessions[id] = session
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sessions) Touch(_ context.Context, id uuid.UUID, at time.Time) error {
+	return r.update(func(s types.Session) bool { return s.ID == id }, func(s *types.Session) { s.LastUsedAt = &at })
+}
+
+func (r *sessions) Revoke(_ context.Context, id uuid.UUID, at time.Time) error {
+	return r.update(func(s types.Session) bool { return s.ID == id }, func(s *types.Session) { s.RevokedAt = &at })
+}
+
+func (r *sessions) RevokeAllForUser(_ context.Context, userID uuid.UUID, at time.Time) error {
+	return r.update(
+		func(s types.Session) bool { return s.UserID == userID && s.RevokedAt == nil },
+		func(s *types.Session) { s.RevokedAt = &at },
+	)
+}
+
+func (r *sessions) DeleteForUser(_ context.Context, userID uuid.UUID) error {
+	return r.s.with(func(d *state) error {
+		deleteWhere(d.sessions, func(s types.Session) bool { return s.UserID == userID })
+		return nil
+	})
+}
+
+// deleteWhere removes the rows of m that match.
+func deleteWhere[T any](m map[uuid.UUID]T, match func(T) bool) {
+	for id, row := range m {
+		if match(row) {
+			delete(m, id)
+		}
+	}
+}
+
+type tokens struct{ s *Store }
+
+func (r *tokens) Create(_ context.Context, token *types.VerificationToken) error {
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	token.CreatedAt = time.Now()
+	return r.s.with(func(d *state) error {
+		for _, t := range d.tokens {
+			if t.TokenHash == token.TokenHash {
+				return fmt.Errorf("token hash already exists")
+			}
+		}
+		d.tokens[token.ID] = *token
+		return nil
+	})
+}
+
+func (r *tokens) FindByHash(_ context.Context, purpose, hash string) (*types.VerificationToken, error) {
+	var found *types.VerificationToken
+	err := r.s.with(func(d *state) error {
+		for _, t := range d.tokens {
+			if t.TokenHash == hash && t.Purpose == purpose {
+				found = &t
+				return nil
+			}
+		}
+		return repository.ErrNotFound
+	})
+	return found, err
+}
+
+func (r *tokens) Consume(_ context.Context, id uuid.UUID, at time.Time) error {
+	return r.s.with(func(d *state) error {
+		if t, ok := d.tokens[id]; ok {
+			t.ConsumedAt = &at
+			d.tokens[id] = t
+		}
+		return nil
+	})
+}
+
+func (r *tokens) ConsumeAllForUser(_ context.Context, userID uuid.UUID, purpose string, at time.Time) error {
+	return r.s.with(func(d *state) error {
+		for id, t := range d.tokens {
+			if t.UserID == userID && t.Purpose == purpose && t.ConsumedAt == nil {
+				t.ConsumedAt = &at
+				d.tokens[id] = t
+			}
+		}
+		return nil
+	})
+}
+
+func (r *tokens) DeleteForUser(_ context.Context, userID uuid.UUID) error {
+	return r.s.with(func(d *state) error {
+		deleteWhere(d.tokens, func(t types.VerificationToken) bool { return t.UserID == userID })
+		return nil
+	})
+}
+
+type inviteCodes struct{ s *Store }
+
+func (r *inviteCodes) Create(_ context.Context, invite *types.InviteCode) error {
+	if invite.ID == uuid.Nil {
+		invite.ID = uuid.New()
+	}
+	now := time.Now()
+	invite.CreatedAt, invite.UpdatedAt = now, now
+	return r.s.with(func(d *state) error {
+		d.inviteCodes[invite.ID] = *invite
+		return nil
+	})
+}
+
+func (r *inviteCodes) List(_ context.Context) ([]types.InviteCode, error) {
+	var out []types.InviteCode
+	err := r.s.with(func(d *state) error {
+		for _, invite := range d.inviteCodes {
+			out = append(out, invite)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, err
+}
+
+func (r *inviteCodes) FindByHash(_ context.Context, hash string) (*types.InviteCode, error) {
+	var found *types.InviteCode
+	err := r.s.with(func(d *state) error {
+		for _, invite := range d.inviteCodes {
+			if invite.CodeHash == hash {
+				found = &invite
+				return nil
+			}
+		}
+		return repository.ErrNotFound
+	})
+	return found, err
+}
+
+func (r *inviteCodes) Redeem(_ context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	redeemed := false
+	err := r.s.with(func(d *state) error {
+		invite, ok := d.inviteCodes[id]
+		if !ok || invite.RevokedAt != nil ||
+			(invite.MaxUses != 0 && invite.Uses >= invite.MaxUses) ||
+			(invite.ExpiresAt != nil && !invite.ExpiresAt.After(now)) {
+			return nil
+		}
+		invite.Uses++
+		d.inviteCodes[id] = invite
+		redeemed = true
+		return nil
+	})
+	return redeemed, err
+}
+
+func (r *inviteCodes) Revoke(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	revoked := false
+	err := r.s.with(func(d *state) error {
+		invite, ok := d.inviteCodes[id]
+		if !ok || invite.RevokedAt != nil {
+			return nil
+		}
+		invite.RevokedAt = &at
+		d.inviteCodes[id] = invite
+		revoked = true
+		return nil
+	})
+	return revoked, err
+}
+
+// newest returns the most recently created row that matches, or
+// repository.ErrNotFound.
+func newest[T any](m map[uuid.UUID]T, createdAt func(T) time.Time, match func(T) bool) (*T, error) {
+	var found *T
+	for _, row := range m {
+		if !match(row) {
+			continue
+		}
+		if found == nil || createdAt(row).After(createdAt(*found)) {
+			row := row
+			found = &row
+		}
+	}
+	if found == nil {
+		return nil, repository.ErrNotFound
+	}
+	return found, nil
+}
+
+type invitations struct{ s *Store }
+
+func invitationCreatedAt(inv types.Invitation) time.Time { return inv.CreatedAt }
+
+func open(inv types.Invitation) bool {
+	return inv.AcceptedAt == nil && inv.RevokedAt == nil
+}
+
+func (r *invitations) Save(_ context.Context, inv *types.Invitation) error {
+	now := time.Now()
+	if inv.CreatedAt.IsZero() {
+		inv.CreatedAt = now
+	}
+	inv.UpdatedAt = now
+	return r.s.with(func(d *state) error {
+		d.invitations[inv.ID] = *inv
+		return nil
+	})
+}
+
+func (r *invitations) FindByID(_ context.Context, id uuid.UUID) (*types.Invitation, error) {
+	var found *types.Invitation
+	err := r.s.with(func(d *state) error {
+		inv, ok := d.invitations[id]
+		if !ok {
+			return repository.ErrNotFound
+		}
+		found = &inv
+		return nil
+	})
+	return found, err
+}
+
+func (r *invitations) FindOpen(_ context.Context, email string) (*types.Invitation, error) {
+	var found *types.Invitation
+	err := r.s.with(func(d *state) (err error) {
+		found, err = newest(d.invitations, invitationCreatedAt, func(inv types.Invitation) bool {
+			return inv.Email == email && open(inv)
+		})
+		return err
+	})
+	return found, err
+}
+
+func (r *invitations) HasPending(_ context.Context, email string, now time.Time) (bool, error) {
+	pending := false
+	err := r.s.with(func(d *state) error {
+		for _, inv := range d.invitations {
+			if inv.Email == email && inv.Status(now) == types.InvitationPending {
+				pending = true
+			}
+		}
+		return nil
+	})
+	return pending, err
+}
+
+func (r *invitations) List(_ context.Context, status string, now time.Time) ([]types.Invitation, error) {
+	var out []types.Invitation
+	err := r.s.with(func(d *state) error {
+		for _, inv := range d.invitations {
+			if status == "" || inv.Status(now) == status {
+				out = append(out, inv)
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, err
+}
+
+func (r *invitations) ListAcceptedBy(_ context.Context, userID uuid.UUID) ([]types.Invitation, error) {
+	var out []types.Invitation
+	err := r.s.with(func(d *state) error {
+		for _, inv := range d.invitations {
+			if inv.AcceptedUserID != nil && *inv.AcceptedUserID == userID {
+				out = append(out, inv)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *invitations) Accept(_ context.Context, id, userID uuid.UUID, at time.Time) (bool, error) {
+	accepted := false
+	err := r.s.with(func(d *state) error {
+		inv, ok := d.invitations[id]
+		if !ok || !open(inv) {
+			return nil
+		}
+		inv.AcceptedAt, inv.AcceptedUserID = &at, &userID
+		d.invitations[id] = inv
+		accepted = true
+		return nil
+	})
+	return accepted, err
+}
+
+func (r *invitations) Revoke(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	revoked := false
+	err := r.s.with(func(d *state) error {
+		inv, ok := d.invitations[id]
+		if !ok || !open(inv) {
+			return nil
+		}
+		inv.RevokedAt = &at
+		d.invitations[id] = inv
+		revoked = true
+		return nil
+	})
+	return revoked, err
+}
+
+func (r *invitations) ReplaceEmail(_ context.Context, userID uuid.UUID, email, replacement string) error {
+	return r.s.with(func(d *state) error {
+		for id, inv := range d.invitations {
+			if inv.Email == email || (inv.AcceptedUserID != nil && *inv.AcceptedUserID == userID) {
+				inv.Email = replacement
+				d.invitations[id] = inv
+			}
+		}
+		return nil
+	})
+}
+
+type identities struct{ s *Store }
+
+func (r *identities) Create(_ context.Context, identity *types.UserIdentity) error {
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+	identity.CreatedAt = time.Now()
+	return r.s.with(func(d *state) error {
+		for _, existing := range d.identities {
+			if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+				return fmt.Errorf("identity %s/%s already linked", identity.Provider, identity.Subject)
+			}
+		}
+		d.identities[identity.ID] = *identity
+		return nil
+	})
+}
+
+func (r *identities) ListForUser(_ context.Context, userID uuid.UUID) ([]types.UserIdentity, error) {
+	var out []types.UserIdentity
+	err := r.s.with(func(d *state) error {
+		for _, identity := range d.identities {
+			if identity.UserID == userID {
+				out = append(out, identity)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *identities) DeleteForUser(_ context.Context, userID uuid.UUID) error {
+	return r.s.with(func(d *state) error {
+		deleteWhere(d.identities, func(i types.UserIdentity) bool { return i.UserID == userID })
+		return nil
+	})
+}
+
+type otps struct{ s *Store }
+
+func otpCreatedAt(otp types.PhoneOTP) time.Time { return otp.CreatedAt }
+
+func (r *otps) Create(_ context.Context, otp *types.PhoneOTP) error {
+	if otp.ID == uuid.Nil {
+		otp.ID = uuid.New()
+	}
+	otp.CreatedAt = time.Now()
+	return r.s.with(func(d *state) error {
+		d.otps[otp.ID] = *otp
+		return nil
+	})
+}
+
+func (r *otps) Latest(_ context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error) {
+	var found *types.PhoneOTP
+	err := r.s.with(func(d *state) (err error) {
+		found, err = newest(d.otps, otpCreatedAt, func(otp types.PhoneOTP) bool {
+			return otp.UserID == userID && otp.Purpose == purpose
+		})
+		return err
+	})
+	return found, err
+}
+
+func (r *otps) Outstanding(_ context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error) {
+	var found *types.PhoneOTP
+	err := r.s.with(func(d *state) (err error) {
+		found, err = newest(d.otps, otpCreatedAt, func(otp types.PhoneOTP) bool {
+			return otp.UserID == userID && otp.Purpose == purpose && otp.ConsumedAt == nil
+		})
+		return err
+	})
+	return found, err
+}
+
+func (r *otps) CountAttempt(_ context.Context, id uuid.UUID) error {
+	return r.s.with(func(d *state) error {
+		if otp, ok := d.otps[id]; ok {
+			otp.Attempts++
+			d.otps[id] = otp
+		}
+		return nil
+	})
+}
+
+func (r *otps) Consume(_ context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	consumed := false
+	err := r.s.with(func(d *state) error {
+		otp, ok := d.otps[id]
+		if !ok || otp.ConsumedAt != nil {
+			return nil
+		}
+		otp.ConsumedAt = &at
+		d.otps[id] = otp
+		consumed = true
+		return nil
+	})
+	return consumed, err
+}
+
+func (r *otps) ConsumeAllForUser(_ context.Context, userID uuid.UUID, purpose string, at time.Time) error {
+	return r.s.with(func(d *state) error {
+		for id, otp := range d.otps {
+			if otp.UserID == userID && otp.Purpose == purpose && otp.ConsumedAt == nil {
+				otp.ConsumedAt = &at
+				d.otps[id] = otp
+			}
+		}
+		return nil
+	})
+}
+
+func (r *otps) DeleteForUser(_ context.Context, userID uuid.UUID) error {
+	return r.s.with(func(d *state) error {
+		deleteWhere(d.otps, func(otp types.PhoneOTP) bool { return otp.UserID == userID })
+		return nil
+	})
+}
+
+type statusChanges struct{ s *Store }
+
+func (r *statusChanges) Create(_ context.Context, change *types.UserStatusChange) error {
+	if change.ID == uuid.Nil {
+		change.ID = uuid.New()
+	}
+	change.CreatedAt = time.Now()
+	return r.s.with(func(d *state) error {
+		d.changes[change.ID] = *change
+		return nil
+	})
+}
+
+func (r *statusChanges) ListForUser(_ context.Context, userID uuid.UUID) ([]types.UserStatusChange, error) {
+	var out []types.UserStatusChange
+	err := r.s.with(func(d *state) error {
+		for _, change := range d.changes {
+			if change.UserID == userID {
+				out = append(out, change)
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, err
+}
+
+type dataExports struct{ s *Store }
+
+func (r *dataExports) Create(_ context.Context, export *types.DataExport) error {
+	if export.ID == uuid.Nil {
+		export.ID = uuid.New()
+	}
+	export.CreatedAt = time.Now()
+	return r.s.with(func(d *state) error {
+		d.exports[export.ID] = *export
+		return nil
+	})
+}
+
+func (r *dataExports) Find(_ context.Context, id uuid.UUID, ownerID *uuid.UUID) (*types.DataExport, error) {
+	var found *types.DataExport
+	err := r.s.with(func(d *state) error {
+		export, ok := d.exports[id]
+		if !ok || (ownerID != nil && export.UserID != *ownerID) {
+			return repository.ErrNotFound
+		}
+		found = &export
+		return nil
+	})
+	return found, err
+}
+
+func (r *dataExports) FindPending(_ context.Context, userID uuid.UUID) (*types.DataExport, error) {
+	var found *types.DataExport
+	err := r.s.with(func(d *state) error {
+		for _, export := range d.exports {
+			if export.UserID == userID && export.Status == types.DataExportPending {
+				found = &export
+				return nil
+			}
+		}
+		return repository.ErrNotFound
+	})
+	return found, err
+}
+
+func (r *dataExports) list(match func(types.DataExport) bool, newestFirst bool) ([]types.DataExport, error) {
+	var out []types.DataExport
+	err := r.s.with(func(d *state) error {
+		for _, export := range d.exports {
+			if match(export) {
+				out = append(out, export)
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool {
+		if newestFirst {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, err
+}
+
+func (r *dataExports) ListForUser(_ context.Context, userID uuid.UUID) ([]types.DataExport, error) {
+	return r.list(func(e types.DataExport) bool { return e.UserID == userID }, true)
+}
+
+// ClaimPending needs no lock of its own: units of work on the store
+// already run one at a time.
+func (r *dataExports) ClaimPending(_ context.Context) (*types.DataExport, error) {
+	pending, err := r.list(func(e types.DataExport) bool { return e.Status == types.DataExportPending }, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &pending[0], nil
+}
+
+func (r *dataExports) ListExpired(_ context.Context, now time.Time) ([]types.DataExport, error) {
+	return r.list(func(e types.DataExport) bool {
+		return e.Status == types.DataExportReady && e.ExpiresAt != nil && !e.ExpiresAt.After(now)
+	}, false)
+}
+
+func (r *dataExports) Update(_ context.Context, export *types.DataExport, columns ...string) error {
+	return r.s.with(func(d *state) error {
+		stored, ok := d.exports[export.ID]
+		if !ok {
+			return nil
+		}
+		if err := copyColumns(exportSchema, export, &stored, columns); err != nil {
+			return err
+		}
+		d.exports[export.ID] = stored
+		return nil
+	})
+}
+
+func (r *dataExports) Delete(_ context.Context, id uuid.UUID) error {
+	return r.s.with(func(d *state) error {
+		delete(d.exports, id)
+		return nil
+	})
+}
+
+func (r *dataExports) DeleteForUser(_ context.Context, userID uuid.UUID) error {
+	return r.s.with(func(d *state) error {
+		deleteWhere(d.exports, func(e types.DataExport) bool { return e.UserID == userID })
+		return nil
+	})
+}
+
+type audit struct{ s *Store }
+
+func (r *audit) Append(_ context.Context, entry *types.AuditEntry, hash func(*types.AuditEntry) (string, error)) error {
+	return r.s.with(func(d *state) error {
+		entry.PrevHash = ""
+		if n := len(d.audit); n > 0 {
+			entry.PrevHash = d.audit[n-1].Hash
+		}
+		sum, err := hash(entry)
+		if err != nil {
+			return err
+		}
+		entry.Hash = sum
+		entry.ID = uint64(len(d.audit) + 1)
+		d.audit = append(d.audit, *entry)
+		return nil
+	})
+}
+
+func (r *audit) ListForUser(_ context.Context, userID uuid.UUID) ([]types.AuditEntry, error) {
+	var out []types.AuditEntry
+	err := r.s.with(func(d *state) error {
+		for _, entry := range d.audit {
+			if (entry.ActorID != nil && *entry.ActorID == userID) || (entry.TargetID != nil && *entry.TargetID == userID) {
+				out = append(out, entry)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
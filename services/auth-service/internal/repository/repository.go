@@ -0,0 +1,218 @@
+// Package repository hides persistence behind small interfaces so services
+// can be tested without Postgres. The GORM implementations live here; the
+// memory subpackage provides in-process fakes with the same behaviour.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+)
+
+// ErrNotFound is returned when a lookup matches no record. Services map it
+// to the matching apperror for their resource.
+var ErrNotFound = errors.New("record not found")
+
+var Module = fx.Module("repository",
+	fx.Provide(
+		NewRepositories,
+		NewUnitOfWork,
+	),
+)
+
+// UserFilter narrows a user listing. Zero fields match every user.
+type UserFilter struct {
+	Status string
+	RoleID *uuid.UUID
+	// Search matches part of the username or email, ignoring case.
+	Search string
+}
+
+// UserCursor is a position in a listing ordered by creation time, then ID.
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+type UserRepository interface {
+	List(ctx context.Context) ([]types.User, error)
+	// Page returns up to limit users matching filter in cursor order,
+	// starting after the cursor when one is given.
+	Page(ctx context.Context, filter UserFilter, after *UserCursor, limit int) ([]types.User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*types.User, error)
+	// FindByIDs returns those of ids that exist, in no particular order.
+	FindByIDs(ctx context.Context, ids []uuid.UUID) ([]types.User, error)
+	// FindByIDWithRole also loads the user's role and its permissions.
+	FindByIDWithRole(ctx context.Context, id uuid.UUID) (*types.User, error)
+	FindByEmail(ctx context.Context, email string) (*types.User, error)
+	// UsernameTaken reports whether another user than exceptID holds
+	// username. Pass uuid.Nil to check against every user.
+	UsernameTaken(ctx context.Context, username string, exceptID uuid.UUID) (bool, error)
+	EmailTaken(ctx context.Context, email string, exceptID uuid.UUID) (bool, error)
+	Create(ctx context.Context, user *types.User) error
+	// Update writes only the named columns of user, including soft-deleted
+	// users.
+	Update(ctx context.Context, user *types.User, columns ...string) error
+	// FindAnyByID is FindByIDWithRole that also finds soft-deleted users.
+	FindAnyByID(ctx context.Context, id uuid.UUID) (*types.User, error)
+	// ListWithRole returns users with their roles, newest first. An empty
+	// status lists every user that is not soft-deleted; a status also
+	// matches soft-deleted users.
+	ListWithRole(ctx context.Context, status string) ([]types.User, error)
+	// DueForPurge returns the IDs of users in status whose purge_after has
+	// passed at now, including soft-deleted users.
+	DueForPurge(ctx context.Context, status string, now time.Time) ([]uuid.UUID, error)
+	// Delete removes the user row itself, not just soft-deleting it.
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type RoleRepository interface {
+	// List returns every role with its permissions, ordered by name.
+	List(ctx context.Context) ([]types.Role, error)
+	// FindByID also loads the role's permissions.
+	FindByID(ctx context.Context, id uuid.UUID) (*types.Role, error)
+	// FindByIDs is FindByID for many roles, in no particular order.
+	FindByIDs(ctx context.Context, ids []uuid.UUID) ([]types.Role, error)
+	FindByName(ctx context.Context, name string) (*types.Role, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *types.Session) error
+	// Find returns the session only if it belongs to userID.
+	Find(ctx context.Context, id, userID uuid.UUID) (*types.Session, error)
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]types.Session, error)
+	Touch(ctx context.Context, id uuid.UUID, at time.Time) error
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+	DeleteForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type TokenRepository interface {
+	Create(ctx context.Context, token *types.VerificationToken) error
+	FindByHash(ctx context.Context, purpose, hash string) (*types.VerificationToken, error)
+	Consume(ctx context.Context, id uuid.UUID, at time.Time) error
+	// ConsumeAllForUser invalidates every outstanding token of a purpose.
+	ConsumeAllForUser(ctx context.Context, userID uuid.UUID, purpose string, at time.Time) error
+	DeleteForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type InviteCodeRepository interface {
+	Create(ctx context.Context, invite *types.InviteCode) error
+	List(ctx context.Context) ([]types.InviteCode, error)
+	FindByHash(ctx context.Context, hash string) (*types.InviteCode, error)
+	// Redeem counts one use of the code and reports false when it is
+	// revoked, expired or used up.
+	Redeem(ctx context.Context, id uuid.UUID, now time.Time) (bool, error)
+	// Revoke reports false when the code does not exist or is already
+	// revoked.
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+}
+
+type InvitationRepository interface {
+	// Save inserts the invitation or updates every column of it.
+	Save(ctx context.Context, inv *types.Invitation) error
+	FindByID(ctx context.Context, id uuid.UUID) (*types.Invitation, error)
+	// FindOpen returns the newest invitation for email that has been
+	// neither accepted nor revoked, whether or not it has expired.
+	FindOpen(ctx context.Context, email string) (*types.Invitation, error)
+	// HasPending reports whether email has an open invitation that is
+	// still valid at now.
+	HasPending(ctx context.Context, email string, now time.Time) (bool, error)
+	// List returns invitations in status at now, newest first. An empty
+	// status lists every invitation.
+	List(ctx context.Context, status string, now time.Time) ([]types.Invitation, error)
+	ListAcceptedBy(ctx context.Context, userID uuid.UUID) ([]types.Invitation, error)
+	// Accept marks an open invitation as accepted by userID and reports
+	// false when it was accepted or revoked in the meantime.
+	Accept(ctx context.Context, id, userID uuid.UUID, at time.Time) (bool, error)
+	// Revoke reports false when the invitation is no longer open.
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	// ReplaceEmail rewrites the address of every invitation sent to email
+	// or accepted by userID.
+	ReplaceEmail(ctx context.Context, userID uuid.UUID, email, replacement string) error
+}
+
+type IdentityRepository interface {
+	Create(ctx context.Context, identity *types.UserIdentity) error
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]types.UserIdentity, error)
+	DeleteForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type OTPRepository interface {
+	Create(ctx context.Context, otp *types.PhoneOTP) error
+	// Latest returns the newest code for userID and purpose, consumed or
+	// not.
+	Latest(ctx context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error)
+	// Outstanding returns the newest code that has not been consumed.
+	Outstanding(ctx context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error)
+	CountAttempt(ctx context.Context, id uuid.UUID) error
+	// Consume reports false when the code was already consumed.
+	Consume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	ConsumeAllForUser(ctx context.Context, userID uuid.UUID, purpose string, at time.Time) error
+	DeleteForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type StatusChangeRepository interface {
+	Create(ctx context.Context, change *types.UserStatusChange) error
+	// ListForUser returns the user's status history, newest first.
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]types.UserStatusChange, error)
+}
+
+type DataExportRepository interface {
+	Create(ctx context.Context, export *types.DataExport) error
+	// Find returns the export, which must belong to ownerID when it is set.
+	Find(ctx context.Context, id uuid.UUID, ownerID *uuid.UUID) (*types.DataExport, error)
+	FindPending(ctx context.Context, userID uuid.UUID) (*types.DataExport, error)
+	// ListForUser returns the user's exports, newest first.
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]types.DataExport, error)
+	// ClaimPending returns the oldest export waiting to be built and locks
+	// it until the surrounding transaction ends. Exports locked by other
+	// transactions are skipped, so concurrent workers never build the same
+	// export. It returns ErrNotFound when nothing is waiting.
+	ClaimPending(ctx context.Context) (*types.DataExport, error)
+	// ListExpired returns finished exports whose expiry has passed at now.
+	ListExpired(ctx context.Context, now time.Time) ([]types.DataExport, error)
+	// Update writes only the named columns of export.
+	Update(ctx context.Context, export *types.DataExport, columns ...string) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	DeleteForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type AuditRepository interface {
+	// Append links entry to the newest entry in the chain and stores it.
+	// hash is called once PrevHash is set and returns the entry's own
+	// hash. Appends are serialized until the surrounding transaction ends,
+	// so Append must run inside a unit of work.
+	Append(ctx context.Context, entry *types.AuditEntry, hash func(*types.AuditEntry) (string, error)) error
+	// ListForUser returns every entry where userID is the actor or the
+	// target, oldest first.
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]types.AuditEntry, error)
+}
+
+// Repositories groups the repositories that share one connection or
+// transaction.
+type Repositories struct {
+	Users         UserRepository
+	Roles         RoleRepository
+	Sessions      SessionRepository
+	Tokens        TokenRepository
+	InviteCodes   InviteCodeRepository
+	Invitations   InvitationRepository
+	Identities    IdentityRepository
+	OTPs          OTPRepository
+	StatusChanges StatusChangeRepository
+	DataExports   DataExportRepository
+	Audit         AuditRepository
+}
+
+// UnitOfWork runs fn with repositories bound to a single transaction. The
+// transaction commits when fn returns nil and rolls back otherwise, so a
+// multi-step operation either happens completely or not at all.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(r Repositories) error) error
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/content-management-system/auth-service/pkg/metrics"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+
+	AuditActionLogin                = "auth.login"
+	AuditActionMFAVerify            = "auth.mfa_verify"
+	AuditActionRegister             = "auth.register"
+	AuditActionTokenRefresh         = "auth.token_refresh"
+	AuditActionLogout               = "auth.logout"
+	AuditActionPasswordChange       = "user.password_change"
+	AuditActionEmailChange          = "user.email_change"
+	AuditActionRoleChange           = "user.role_change"
+	AuditActionStatusChange         = "user.status_change"
+	AuditActionUserRestored         = "user.restored"
+	AuditActionUserPurged           = "user.purged"
+	AuditActionDataExportRequested  = "data_export.requested"
+	AuditActionDataExportCompleted  = "data_export.completed"
+	AuditActionDataExportFailed     = "data_export.failed"
+	AuditActionDataExportDownloaded = "data_export.downloaded"
+	AuditActionUserErased           = "user.erased"
+	AuditActionAuditExported        = "audit.exported"
+	AuditActionLogLevelChange       = "config.log_level_change"
+
+	auditPageSizeDefault = 50
+)
+
+// Actor identifies who performed an audited action and from where. ID is
+// nil for actions taken by the system itself.
+type Actor struct {
+	ID        *uuid.UUID
+	IP        string
+	UserAgent string
+}
+
+func SystemActor() Actor {
+	return Actor{}
+}
+
+type AuditEvent struct {
+	Actor    Actor
+	Action   string
+	TargetID *uuid.UUID
+	Outcome  string
+	Details  map[string]interface{}
+}
+
+// NewAuditEvent describes the outcome of action. A non-nil err marks it
+// failed and records the error's code.
+func NewAuditEvent(actor Actor, action string, target *uuid.UUID, err error, details map[string]interface{}) AuditEvent {
+	event := AuditEvent{
+		Actor:    actor,
+		Action:   action,
+		TargetID: target,
+		Outcome:  AuditOutcomeSuccess,
+		Details:  details,
+	}
+	if err != nil {
+		event.Outcome = AuditOutcomeFailure
+		if event.Details == nil {
+			event.Details = map[string]interface{}{}
+		}
+		event.Details["error_code"] = apperror.CodeOf(err)
+	}
+	return event
+}
+
+// EmailAuditDetails identifies an attempted account without writing the
+// email address itself into the append-only log.
+func EmailAuditDetails(email string) map[string]interface{} {
+	return map[string]interface{}{"email_hash": utils.HashToken(strings.ToLower(strings.TrimSpace(email)))}
+}
+
+type Auditor interface {
+	Record(ctx context.Context, event AuditEvent)
+}
+
+type AuditFilter struct {
+	ActorID  *uuid.UUID
+	TargetID *uuid.UUID
+	Action   string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+}
+
+var errChainBroken = errors.New("audit chain broken")
+
+// AuditService appends events to the hash-chained audit log and answers
+// queries against it. Writes are synchronous: an event is committed before
+// the call recording it returns, so nothing is buffered that shutdown
+// would need to flush.
+type AuditService struct {
+	db     *db.DB
+	repos  repository.Repositories
+	uow    repository.UnitOfWork
+	logger *logrus.Logger
+}
+
+func NewAuditService(db *db.DB, repos repository.Repositories, uow repository.UnitOfWork, logger *logrus.Logger) *AuditService {
+	return &AuditService{db: db, repos: repos, uow: uow, logger: logger}
+}
+
+// authMetricEvents names the auth_events_total event label for each
+// audited authentication action.
+var authMetricEvents = map[string]string{
+	AuditActionLogin:        "login",
+	AuditActionMFAVerify:    "mfa_verify",
+	AuditActionRegister:     "registration",
+	AuditActionTokenRefresh: "refresh",
+}
+
+// meteredAuditor counts authentication outcomes as they are audited, so
+// the metrics and the audit log always agree.
+type meteredAuditor struct {
+	Auditor
+	metrics *metrics.AuthMetrics
+}
+
+func NewAuditor(a *AuditService, m *metrics.AuthMetrics) Auditor {
+	return &meteredAuditor{Auditor: a, metrics: m}
+}
+
+func (a *meteredAuditor) Record(ctx context.Context, event AuditEvent) {
+	if name, ok := authMetricEvents[event.Action]; ok {
+		reason := ""
+		if code, ok := event.Details["error_code"]; ok {
+			reason = fmt.Sprint(code)
+		}
+		a.metrics.Observe(name, event.Outcome, reason)
+	}
+	a.Auditor.Record(ctx, event)
+}
+
+// Record appends an event in a transaction of its own. Audit failures are
+// logged rather than returned so they never fail the request being
+// audited.
+func (s *AuditService) Record(ctx context.Context, event AuditEvent) {
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		return s.Append(ctx, r, event)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("action", event.Action).Error("Failed to record audit event")
+	}
+}
+
+// Append adds an event through r, so that it commits or rolls back with
+// the change it describes.
+func (s *AuditService) Append(ctx context.Context, r repository.Repositories, event AuditEvent) error {
+	entry := types.AuditEntry{
+		ActorID:   event.Actor.ID,
+		Action:    event.Action,
+		TargetID:  event.TargetID,
+		IPAddress: truncate(event.Actor.IP, 64),
+		UserAgent: truncate(event.Actor.UserAgent, 512),
+		Outcome:   event.Outcome,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if len(event.Details) > 0 {
+		details, err := json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("encode audit details: %w", err)
+		}
+		entry.Details = details
+	}
+	if err := r.Audit.Append(ctx, &entry, auditHash); err != nil {
+		return err
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"audit_id": entry.ID,
+		"action":   entry.Action,
+		"outcome":  entry.Outcome,
+	}).Info("Audit event recorded")
+	return nil
+}
+
+func (s *AuditService) query(ctx context.Context, filter AuditFilter) *gorm.DB {
+	query := s.db.Conn.WithContext(ctx).Model(&types.AuditEntry{})
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
+
+// List returns a page of entries, newest first. Pages are numbered from 1.
+func (s *AuditService) List(ctx context.Context, filter AuditFilter, page, pageSize int) (*types.AuditPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = auditPageSizeDefault
+	}
+
+	result := &types.AuditPage{Page: page, PageSize: pageSize, Items: []types.AuditEntry{}}
+	if err := s.query(ctx, filter).Count(&result.Total).Error; err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to count audit entries")
+		return nil, err
+	}
+	if err := s.query(ctx, filter).
+		Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&result.Items).Error; err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list audit entries")
+		return nil, err
+	}
+	return result, nil
+}
+
+// Each streams every matching entry, oldest first, to fn.
+func (s *AuditService) Each(ctx context.Context, filter AuditFilter, fn func(*types.AuditEntry) error) error {
+	rows, err := s.query(ctx, filter).Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry types.AuditEntry
+		if err := s.db.Conn.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ForUser returns every entry where userID is the actor or the target.
+func (s *AuditService) ForUser(ctx context.Context, userID uuid.UUID) ([]types.AuditEntry, error) {
+	return s.repos.Audit.ListForUser(ctx, userID)
+}
+
+// Verify walks the whole chain and reports the first entry whose link or
+// hash does not match.
+func (s *AuditService) Verify(ctx context.Context) (*types.AuditVerification, error) {
+	result := &types.AuditVerification{Valid: true}
+	prev := ""
+	var entries []types.AuditEntry
+	err := s.db.Conn.WithContext(ctx).Order("id").FindInBatches(&entries, 1000, func(tx *gorm.DB, batch int) error {
+		for i := range entries {
+			entry := &entries[i]
+			hash, err := auditHash(entry)
+			if err != nil {
+				return err
+			}
+			if entry.PrevHash != prev || entry.Hash != hash {
+				result.Valid = false
+				result.BrokenAt = &entry.ID
+				return errChainBroken
+			}
+			prev = entry.Hash
+			result.Checked++
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errChainBroken) {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to verify audit log")
+		return nil, err
+	}
+	return result, nil
+}
+
+// auditHash hashes an entry together with its predecessor's hash. Details
+// are canonicalized first because Postgres normalizes jsonb on storage.
+func auditHash(e *types.AuditEntry) (string, error) {
+	details, err := canonicalJSON(e.Details)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(struct {
+		PrevHash  string     `json:"prev_hash"`
+		ActorID   *uuid.UUID `json:"actor_id"`
+		Action    string     `json:"action"`
+		TargetID  *uuid.UUID `json:"target_id"`
+		IPAddress string     `json:"ip_address"`
+		UserAgent string     `json:"user_agent"`
+		Outcome   string     `json:"outcome"`
+		Details   string     `json:"details"`
+		CreatedAt string     `json:"created_at"`
+	}{
+		PrevHash:  e.PrevHash,
+		ActorID:   e.ActorID,
+		Action:    e.Action,
+		TargetID:  e.TargetID,
+		IPAddress: e.IPAddress,
+		UserAgent: e.UserAgent,
+		Outcome:   e.Outcome,
+		Details:   details,
+		CreatedAt: e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func canonicalJSON(raw json.RawMessage) (string, error) {
+	if len(bytes.TrimSpace(raw)) == 0 || strings.TrimSpace(string(raw)) == "null" {
+		return "", nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
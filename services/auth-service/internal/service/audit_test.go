@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/migrations"
+	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/content-management-system/auth-service/pkg/migrate"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestAuditHashIgnoresJSONBNormalization(t *testing.T) {
+	actor := uuid.New()
+	entry := types.AuditEntry{
+		ActorID:   &actor,
+		Action:    AuditActionLogin,
+		Outcome:   AuditOutcomeSuccess,
+		Details:   json.RawMessage(`{"b":1,"a":"x"}`),
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC),
+	}
+	original, err := auditHash(&entry)
+	require.NoError(t, err)
+
+	entry.Details = json.RawMessage(`{"a": "x", "b": 1}`)
+	entry.CreatedAt = entry.CreatedAt.In(time.FixedZone("UTC+7", 7*3600))
+	normalized, err := auditHash(&entry)
+	require.NoError(t, err)
+	require.Equal(t, original, normalized)
+}
+
+func TestAuditHashDetectsTampering(t *testing.T) {
+	entry := types.AuditEntry{
+		Action:    AuditActionLogin,
+		Outcome:   AuditOutcomeFailure,
+		PrevHash:  "0000000000000000000000000000000000000000000000000000000000000000",
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	original, err := auditHash(&entry)
+	require.NoError(t, err)
+
+	entry.Outcome = AuditOutcomeSuccess
+	tampered, err := auditHash(&entry)
+	require.NoError(t, err)
+	require.NotEqual(t, original, tampered)
+
+	entry.Outcome = AuditOutcomeFailure
+	entry.PrevHash = ""
+	relinked, err := auditHash(&entry)
+	require.NoError(t, err)
+	require.NotEqual(t, original, relinked)
+}
+
+// testPostgres connects to the database in TEST_DATABASE_DSN and migrates
+// it. Tests are skipped when it is not set.
+func testPostgres(t *testing.T) *db.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := conn.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	m, err := migrate.New(sqlDB, migrations.FS, log)
+	require.NoError(t, err)
+	require.NoError(t, m.Up(context.Background(), 0))
+	return &db.DB{Conn: conn}
+}
+
+// The in-memory store keeps prev_hash as written; only a real column type
+// shows whether the genesis entry's empty prev_hash survives a round trip.
+func TestAuditChainVerifiesOnPostgres(t *testing.T) {
+	d := testPostgres(t)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	audit := NewAuditService(d, repository.NewRepositories(d), repository.NewUnitOfWork(d), log)
+	ctx := context.Background()
+
+	target := uuid.New()
+	for i := 0; i < 3; i++ {
+		audit.Record(ctx, NewAuditEvent(SystemActor(), AuditActionStatusChange, &target, nil, map[string]interface{}{"step": i}))
+	}
+	entries, err := audit.ForUser(ctx, target)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i := 1; i < len(entries); i++ {
+		require.Equal(t, entries[i-1].Hash, entries[i].PrevHash)
+	}
+
+	result, err := audit.Verify(ctx)
+	require.NoError(t, err)
+	require.True(t, result.Valid, "chain broken at %v", result.BrokenAt)
+	require.GreaterOrEqual(t, result.Checked, 3)
+
+	var genesis types.AuditEntry
+	require.NoError(t, d.Conn.Order("id").First(&genesis).Error)
+	require.Empty(t, genesis.PrevHash)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// AuthService runs the login, MFA and refresh flows behind both the REST
+// and the GraphQL API, so the two apply the same account checks, issue
+// the same tokens and write the same audit events.
+type AuthService struct {
+	users    *UserService
+	phone    *PhoneService
+	sessions *SessionService
+	auditor  Auditor
+	jwt      *utils.JWT
+}
+
+func NewAuthService(users *UserService, phone *PhoneService, sessions *SessionService, auditor Auditor, jwt *utils.JWT) *AuthService {
+	return &AuthService{users: users, phone: phone, sessions: sessions, auditor: auditor, jwt: jwt}
+}
+
+// LoginResult holds either a new token pair or, for users with MFA
+// enabled, the challenge to answer before tokens are issued.
+type LoginResult struct {
+	Challenge    *types.AuthResult
+	AccessToken  string
+	RefreshToken string
+}
+
+// as attributes actor's request to userID, for calls that authenticate
+// the user themselves.
+func (a Actor) as(userID uuid.UUID) Actor {
+	a.ID = &userID
+	return a
+}
+
+func (s *AuthService) record(ctx context.Context, actor Actor, action string, target *uuid.UUID, err error, details map[string]interface{}) {
+	s.auditor.Record(ctx, NewAuditEvent(actor, action, target, err, details))
+}
+
+// Login checks a user's credentials and either issues tokens or starts an
+// MFA challenge.
+func (s *AuthService) Login(ctx context.Context, actor Actor, email, password string) (*LoginResult, error) {
+	user, err := s.users.Login(ctx, email, password)
+	if err != nil {
+		s.record(ctx, actor, AuditActionLogin, nil, err, EmailAuditDetails(email))
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		challenge, err := s.phone.StartMFAChallenge(ctx, user)
+		s.record(ctx, actor.as(user.ID), AuditActionLogin, &user.ID, err, map[string]interface{}{"mfa_required": true})
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{Challenge: challenge}, nil
+	}
+	s.record(ctx, actor.as(user.ID), AuditActionLogin, &user.ID, nil, nil)
+
+	return s.issueTokens(ctx, actor, user.ID)
+}
+
+// VerifyMFA answers the challenge started by Login and issues tokens.
+func (s *AuthService) VerifyMFA(ctx context.Context, actor Actor, session, code string) (*LoginResult, error) {
+	user, err := s.phone.VerifyMFA(ctx, session, code)
+	if err != nil {
+		s.record(ctx, actor, AuditActionMFAVerify, nil, err, nil)
+		return nil, err
+	}
+	s.record(ctx, actor.as(user.ID), AuditActionMFAVerify, &user.ID, nil, nil)
+
+	return s.issueTokens(ctx, actor, user.ID)
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, actor Actor, userID uuid.UUID) (*LoginResult, error) {
+	token, err := s.jwt.GenerateToken(userID)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.CodeInternal, "could not generate token", err)
+	}
+
+	refreshToken, err := s.sessions.Create(ctx, userID, actor.UserAgent, actor.IP)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.CodeInternal, "could not generate refresh token", err)
+	}
+
+	return &LoginResult{AccessToken: token, RefreshToken: refreshToken}, nil
+}
+
+// Refresh issues a new access token for a valid refresh token whose user
+// is still active.
+func (s *AuthService) Refresh(ctx context.Context, actor Actor, refreshToken string) (string, error) {
+	session, err := s.sessions.Validate(ctx, refreshToken)
+	if err != nil {
+		s.record(ctx, actor, AuditActionTokenRefresh, nil, err, nil)
+		return "", err
+	}
+
+	user, err := s.users.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrUserNotFound) {
+			err = apperror.ErrInvalidToken
+		}
+		s.record(ctx, actor.as(session.UserID), AuditActionTokenRefresh, &session.UserID, err, nil)
+		return "", err
+	}
+	if user.Status != types.UserStatusActive {
+		s.record(ctx, actor.as(user.ID), AuditActionTokenRefresh, &user.ID, apperror.ErrAccountInactive, nil)
+		return "", apperror.ErrAccountInactive
+	}
+
+	token, err := s.jwt.GenerateToken(user.ID)
+	if err != nil {
+		return "", apperror.Wrap(apperror.CodeInternal, "failed to generate new token", err)
+	}
+	s.record(ctx, actor.as(user.ID), AuditActionTokenRefresh, &user.ID, nil, map[string]interface{}{"session_id": session.ID})
+
+	return token, nil
+}
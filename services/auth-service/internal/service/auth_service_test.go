@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginWithMFAThenRefresh(t *testing.T) {
+	f := newLifecycleFixture(t)
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("Secret123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	f.user.Password = string(hash)
+	f.user.MFAEnabled = true
+	require.NoError(t, f.store.Repositories().Users.Update(ctx, &f.user, "password", "mfa_enabled"))
+	actor := Actor{IP: "203.0.113.7", UserAgent: "test"}
+
+	_, err = f.auth.Login(ctx, actor, f.user.Email, "wrong")
+	require.ErrorIs(t, err, apperror.ErrInvalidCredentials)
+
+	result, err := f.auth.Login(ctx, actor, f.user.Email, "Secret123!")
+	require.NoError(t, err)
+	require.NotNil(t, result.Challenge)
+	require.Empty(t, result.AccessToken, "no tokens before the challenge is answered")
+
+	result, err = f.auth.VerifyMFA(ctx, actor, result.Challenge.Session, f.sms.code(t))
+	require.NoError(t, err)
+	require.NotEmpty(t, result.AccessToken)
+
+	token, err := f.auth.Refresh(ctx, actor, result.RefreshToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	_, err = f.lifecycle.ChangeStatus(ctx, f.actor(), f.user.ID, types.UserStatusSuspended, "abuse")
+	require.NoError(t, err)
+	_, err = f.auth.Refresh(ctx, actor, result.RefreshToken)
+	require.Error(t, err, "a suspended user's sessions cannot be refreshed")
+
+	entries, err := f.audit.ForUser(ctx, f.user.ID)
+	require.NoError(t, err)
+	var actions []string
+	for _, entry := range entries {
+		if entry.Action != AuditActionStatusChange {
+			actions = append(actions, entry.Action+":"+entry.Outcome)
+		}
+	}
+	require.Equal(t, []string{
+		AuditActionLogin + ":" + AuditOutcomeSuccess,
+		AuditActionMFAVerify + ":" + AuditOutcomeSuccess,
+		AuditActionTokenRefresh + ":" + AuditOutcomeSuccess,
+	}, actions)
+}
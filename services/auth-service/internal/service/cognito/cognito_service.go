@@ -2,39 +2,89 @@ package cognito
 
 import (
 	"context"
-	"os"
+	"errors"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 	cognitoTypes "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/content-management-system/auth-service/internal/config"
 	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/pkg/health"
+	"github.com/content-management-system/auth-service/pkg/logger"
+	"github.com/content-management-system/auth-service/pkg/metrics"
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/content-management-system/auth-service/pkg/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
 )
 
+const IdentityProvider = "cognito"
+
+// Module wires the Cognito integration. It is only included when Enabled
+// reports true, so consumers must depend on *CognitoService as optional.
+// The aws.Config it uses comes from the aws package's Module.
+var Module = fx.Module("cognito",
+	fx.Provide(NewCognitoService, health.AsCheck(newHealthCheck)),
+	fx.Decorate(logger.Named("cognito")),
+)
+
+func Enabled(cfg *config.Config) bool {
+	return cfg.Cognito.Enabled()
+}
+
 type CognitoService struct {
 	CognitoClientInterface
 	userPoolClient *cognitoidentityprovider.Client
 	userPoolID     string
 	clientID       string
 	identityPoolID string
+	secrets        *secrets.Store
 	log            *logrus.Logger
 }
 
-func NewCognitoService(log *logrus.Logger, cfg aws.Config) *CognitoService {
-	userPoolID := os.Getenv("USER_POOL_ID")
-	clientID := os.Getenv("CLIENT_ID")
+func NewCognitoService(log *logrus.Logger, settings *config.Config, cfg aws.Config, store *secrets.Store, m *metrics.CognitoMetrics, tp trace.TracerProvider) *CognitoService {
+	userPoolID := settings.Cognito.UserPoolID
+	clientID := settings.Cognito.ClientID
 	log.Infof("NewCognitoService: USER_POOL_ID=%s, CLIENT_ID=%s", userPoolID, clientID)
 
-	client := cognitoidentityprovider.NewFromConfig(cfg)
+	client := cognitoidentityprovider.NewFromConfig(cfg, func(o *cognitoidentityprovider.Options) {
+		o.APIOptions = append(o.APIOptions, m.AddMiddleware, tracing.AWSMiddleware(tp))
+	})
 	return &CognitoService{
 		userPoolClient: client,
 		clientID:       clientID,
 		userPoolID:     userPoolID,
+		secrets:        store,
 		log:            log,
 	}
 }
 
-func (cg *CognitoService) Login(email string, password string) (*types.AuthResult, error) {
+// Ping checks that the user pool is reachable with the configured
+// credentials.
+func (cg *CognitoService) Ping(ctx context.Context) error {
+	_, err := cg.userPoolClient.DescribeUserPool(ctx, &cognitoidentityprovider.DescribeUserPoolInput{
+		UserPoolId: aws.String(cg.userPoolID),
+	})
+	return err
+}
+
+// newHealthCheck reports Cognito reachability. It is not critical: login
+// does not go through Cognito, so an outage only degrades profile sync and
+// invitations. DescribeUserPool is rate limited per account, so the result
+// is cached rather than fetched on every probe.
+func newHealthCheck(cg *CognitoService) health.Check {
+	return health.Check{
+		Name:        "cognito",
+		Criticality: health.NonCritical,
+		Timeout:     5 * time.Second,
+		CacheFor:    time.Minute,
+		Run:         cg.Ping,
+	}
+}
+
+func (cg *CognitoService) Login(ctx context.Context, email string, password string) (*types.AuthResult, error) {
 	signInInput := cognitoidentityprovider.InitiateAuthInput{
 		AuthFlow: cognitoTypes.AuthFlowTypeUserPasswordAuth,
 		ClientId: aws.String(cg.clientID),
@@ -43,9 +93,16 @@ func (cg *CognitoService) Login(email string, password string) (*types.AuthResul
 			"PASSWORD": password,
 		},
 	}
-	authResp, err := cg.userPoolClient.InitiateAuth(context.Background(), &signInInput)
+	hash, err := cg.secretHash(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if hash != "" {
+		signInInput.AuthParameters["SECRET_HASH"] = hash
+	}
+	authResp, err := cg.userPoolClient.InitiateAuth(ctx, &signInInput)
 	if err != nil {
-		cg.log.Errorf("failed to login user: %s", err.Error())
+		cg.log.WithContext(ctx).Errorf("failed to login user: %s", err.Error())
 		return nil, err
 	}
 	if authResp.ChallengeName != "" {
@@ -65,7 +122,7 @@ func (cg *CognitoService) Login(email string, password string) (*types.AuthResul
 	}, nil
 }
 
-func (cg *CognitoService) Register(email, password string, userAttributes map[string]string) error {
+func (cg *CognitoService) Register(ctx context.Context, email, password string, userAttributes map[string]string) error {
 	var attributes []cognitoTypes.AttributeType
 
 	attributes = append(attributes, cognitoTypes.AttributeType{
@@ -78,16 +135,23 @@ func (cg *CognitoService) Register(email, password string, userAttributes map[st
 			Value: aws.String(value),
 		})
 	}
+	hash, err := cg.secretHash(ctx, email)
+	if err != nil {
+		return err
+	}
 	registerInput := cognitoidentityprovider.SignUpInput{
 		ClientId:       aws.String(cg.clientID),
 		Username:       aws.String(email),
 		Password:       aws.String(password),
 		UserAttributes: attributes,
 	}
+	if hash != "" {
+		registerInput.SecretHash = aws.String(hash)
+	}
 
-	_, err := cg.userPoolClient.SignUp(context.Background(), &registerInput)
+	_, err = cg.userPoolClient.SignUp(ctx, &registerInput)
 	if err != nil {
-		cg.log.Errorf("failed to register user: %s", err.Error())
+		cg.log.WithContext(ctx).Errorf("failed to register user: %s", err.Error())
 		return err
 	}
 
@@ -97,25 +161,35 @@ func (cg *CognitoService) Register(email, password string, userAttributes map[st
 
 }
 
-func (cg *CognitoService) ConfirmSignUp(email, code string) error {
+func (cg *CognitoService) ConfirmSignUp(ctx context.Context, email, code string) error {
+	hash, err := cg.secretHash(ctx, email)
+	if err != nil {
+		return err
+	}
 	signUpInput := cognitoidentityprovider.ConfirmSignUpInput{
 		ClientId:         aws.String(cg.clientID),
 		ConfirmationCode: aws.String(code),
 		Username:         aws.String(email),
 	}
-	up, err := cg.userPoolClient.ConfirmSignUp(context.Background(), &signUpInput)
+	if hash != "" {
+		signUpInput.SecretHash = aws.String(hash)
+	}
+	up, err := cg.userPoolClient.ConfirmSignUp(ctx, &signUpInput)
 	if err != nil {
-		cg.log.Errorf("failed to confirm user: %s", err.Error())
+		cg.log.WithContext(ctx).Errorf("failed to confirm user: %s", err.Error())
 		return err
 	}
 
 	//
-	cg.log.Infof("user %s confirmed successfully", &up.ResultMetadata)
+	cg.log.WithContext(ctx).Infof("user %s confirmed successfully", &up.ResultMetadata)
 
 	return nil
 }
 
-func (cg *CognitoService) RefreshToken(refreshToken string) (*types.AuthResult, error) {
+// RefreshToken exchanges a Cognito refresh token for new tokens. username
+// is the user's Cognito username, which this service signs users up with as
+// their email; app clients with a secret key SECRET_HASH on it.
+func (cg *CognitoService) RefreshToken(ctx context.Context, username, refreshToken string) (*types.AuthResult, error) {
 	refreshInput := cognitoidentityprovider.InitiateAuthInput{
 		AuthFlow: cognitoTypes.AuthFlowTypeRefreshTokenAuth,
 		ClientId: aws.String(cg.clientID),
@@ -123,9 +197,16 @@ func (cg *CognitoService) RefreshToken(refreshToken string) (*types.AuthResult,
 			"REFRESH_TOKEN": refreshToken,
 		},
 	}
-	authResp, err := cg.userPoolClient.InitiateAuth(context.Background(), &refreshInput)
+	hash, err := cg.secretHash(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if hash != "" {
+		refreshInput.AuthParameters["SECRET_HASH"] = hash
+	}
+	authResp, err := cg.userPoolClient.InitiateAuth(ctx, &refreshInput)
 	if err != nil {
-		cg.log.Errorf("failed to refresh token: %s", err.Error())
+		cg.log.WithContext(ctx).Errorf("failed to refresh token: %s", err.Error())
 		return nil, err
 	}
 	return &types.AuthResult{
@@ -137,14 +218,14 @@ func (cg *CognitoService) RefreshToken(refreshToken string) (*types.AuthResult,
 	}, nil
 }
 
-func (cg *CognitoService) GetUser(accessToken string) (*cognitoTypes.UserType, error) {
+func (cg *CognitoService) GetUser(ctx context.Context, accessToken string) (*cognitoTypes.UserType, error) {
 	getUserInput := cognitoidentityprovider.GetUserInput{
 		AccessToken: aws.String(accessToken),
 	}
 
-	userResp, err := cg.userPoolClient.GetUser(context.Background(), &getUserInput)
+	userResp, err := cg.userPoolClient.GetUser(ctx, &getUserInput)
 	if err != nil {
-		cg.log.Errorf("failed to get user: %s", err.Error())
+		cg.log.WithContext(ctx).Errorf("failed to get user: %s", err.Error())
 		return nil, err
 	}
 	return &cognitoTypes.UserType{
@@ -153,16 +234,160 @@ func (cg *CognitoService) GetUser(accessToken string) (*cognitoTypes.UserType, e
 	}, nil
 }
 
-func (cg *CognitoService) AddUserToGroup(username, groupName string) error {
+func (cg *CognitoService) AddUserToGroup(ctx context.Context, username, groupName string) error {
 	adminAddInput := cognitoidentityprovider.AdminAddUserToGroupInput{
 		GroupName:  aws.String(groupName),
 		UserPoolId: aws.String(cg.userPoolID),
 		Username:   aws.String(username),
 	}
-	group, err := cg.userPoolClient.AdminAddUserToGroup(context.Background(), &adminAddInput)
+	group, err := cg.userPoolClient.AdminAddUserToGroup(ctx, &adminAddInput)
+	if err != nil {
+		return err
+	}
+	cg.log.WithContext(ctx).Info("successfully added user to group", group)
+	return nil
+}
+
+// AdminCreateUser creates the user in the pool and lets Cognito deliver the
+// pool's invite message template. With resend set, the invite (and a fresh
+// temporary password) is sent again to an existing unconfirmed user.
+func (cg *CognitoService) AdminCreateUser(ctx context.Context, email string, resend bool) error {
+	input := cognitoidentityprovider.AdminCreateUserInput{
+		UserPoolId: aws.String(cg.userPoolID),
+		Username:   aws.String(email),
+		UserAttributes: []cognitoTypes.AttributeType{
+			{Name: aws.String("email"), Value: aws.String(email)},
+			{Name: aws.String("email_verified"), Value: aws.String("true")},
+		},
+		DesiredDeliveryMediums: []cognitoTypes.DeliveryMediumType{cognitoTypes.DeliveryMediumTypeEmail},
+	}
+	if resend {
+		input.MessageAction = cognitoTypes.MessageActionTypeResend
+	}
+
+	if _, err := cg.userPoolClient.AdminCreateUser(ctx, &input); err != nil {
+		cg.log.WithContext(ctx).Errorf("failed to admin create user: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (cg *CognitoService) AdminDeleteUser(ctx context.Context, username string) error {
+	input := cognitoidentityprovider.AdminDeleteUserInput{
+		UserPoolId: aws.String(cg.userPoolID),
+		Username:   aws.String(username),
+	}
+	if _, err := cg.userPoolClient.AdminDeleteUser(ctx, &input); err != nil {
+		cg.log.WithContext(ctx).Errorf("failed to admin delete user: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// CompleteNewPassword signs in with an invite's temporary password and
+// answers the NEW_PASSWORD_REQUIRED challenge with newPassword.
+func (cg *CognitoService) CompleteNewPassword(ctx context.Context, email, temporaryPassword, newPassword string) (*types.AuthResult, error) {
+	login, err := cg.Login(ctx, email, temporaryPassword)
+	if err != nil {
+		return nil, err
+	}
+	if login.ChallengeName != string(cognitoTypes.ChallengeNameTypeNewPasswordRequired) {
+		return nil, errors.New("user is not awaiting a new password")
+	}
+
+	input := cognitoidentityprovider.RespondToAuthChallengeInput{
+		ChallengeName: cognitoTypes.ChallengeNameTypeNewPasswordRequired,
+		ClientId:      aws.String(cg.clientID),
+		Session:       aws.String(login.Session),
+		ChallengeResponses: map[string]string{
+			"USERNAME":     email,
+			"NEW_PASSWORD": newPassword,
+		},
+	}
+	hash, err := cg.secretHash(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if hash != "" {
+		input.ChallengeResponses["SECRET_HASH"] = hash
+	}
+	resp, err := cg.userPoolClient.RespondToAuthChallenge(ctx, &input)
+	if err != nil {
+		cg.log.WithContext(ctx).Errorf("failed to respond to new password challenge: %s", err.Error())
+		return nil, err
+	}
+	if resp.AuthenticationResult == nil {
+		return nil, errors.New("unexpected challenge after setting new password")
+	}
+	return &types.AuthResult{
+		AccessToken:  aws.ToString(resp.AuthenticationResult.AccessToken),
+		IdToken:      aws.ToString(resp.AuthenticationResult.IdToken),
+		RefreshToken: aws.ToString(resp.AuthenticationResult.RefreshToken),
+		TokenType:    aws.ToString(resp.AuthenticationResult.TokenType),
+		ExpiresIn:    resp.AuthenticationResult.ExpiresIn,
+	}, nil
+}
+
+// VerifyIdentity resolves a Cognito access token to the user's sub and
+// email attributes.
+func (cg *CognitoService) VerifyIdentity(ctx context.Context, accessToken string) (subject string, email string, err error) {
+	user, err := cg.GetUser(ctx, accessToken)
 	if err != nil {
+		return "", "", err
+	}
+	for _, attr := range user.Attributes {
+		switch aws.ToString(attr.Name) {
+		case "sub":
+			subject = aws.ToString(attr.Value)
+		case "email":
+			email = aws.ToString(attr.Value)
+		}
+	}
+	if subject == "" {
+		return "", "", errors.New("cognito user has no sub attribute")
+	}
+	return subject, email, nil
+}
+
+// UpdateUserAttributes pushes profile changes for username to the pool.
+// The service holds no Cognito access token for the user, so this uses
+// the admin variant of the API.
+func (cg *CognitoService) UpdateUserAttributes(ctx context.Context, username string, userAttributes map[string]string) error {
+	var attributes []cognitoTypes.AttributeType
+	for key, value := range userAttributes {
+		attributes = append(attributes, cognitoTypes.AttributeType{
+			Name:  aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+	input := cognitoidentityprovider.AdminUpdateUserAttributesInput{
+		UserPoolId:     aws.String(cg.userPoolID),
+		Username:       aws.String(username),
+		UserAttributes: attributes,
+	}
+	if _, err := cg.userPoolClient.AdminUpdateUserAttributes(ctx, &input); err != nil {
+		cg.log.WithContext(ctx).Errorf("failed to update user attributes: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (cg *CognitoService) SetUserPassword(ctx context.Context, username, password string) error {
+	input := cognitoidentityprovider.AdminSetUserPasswordInput{
+		UserPoolId: aws.String(cg.userPoolID),
+		Username:   aws.String(username),
+		Password:   aws.String(password),
+		Permanent:  true,
+	}
+	if _, err := cg.userPoolClient.AdminSetUserPassword(ctx, &input); err != nil {
+		cg.log.WithContext(ctx).Errorf("failed to set user password: %s", err.Error())
 		return err
 	}
-	cg.log.Info("successfully added user to group", group)
 	return nil
 }
+
+// IsUserNotFound reports whether err is Cognito's UserNotFoundException.
+func IsUserNotFound(err error) bool {
+	var notFound *cognitoTypes.UserNotFoundException
+	return errors.As(err, &notFound)
+}
@@ -2,24 +2,48 @@ package cognito
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/pkg/metrics"
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"go.uber.org/fx"
 )
 
 func setupCognitoService(t *testing.T) *CognitoService {
 	var service *CognitoService
+	cfg, err := config.Read()
+	require.NoError(t, err)
 	app := fx.New(
 		fx.Provide(func() *logrus.Logger {
 			return logrus.New()
 		}),
 		fx.Provide(func() (aws.Config, error) {
-			return config.LoadDefaultConfig(context.TODO())
+			return awsconfig.LoadDefaultConfig(context.TODO())
+		}),
+		fx.Supply(cfg),
+		config.Module,
+		secrets.Module(cfg.Secrets.Provider),
+		fx.Provide(func() *metrics.CognitoMetrics {
+			return metrics.NewCognitoMetrics(prometheus.NewRegistry())
 		}),
+		fx.Provide(func() trace.TracerProvider { return noop.NewTracerProvider() }),
 		fx.Provide(NewCognitoService),
 		fx.Populate(&service),
 	)
@@ -34,7 +58,7 @@ func TestCognitoServiceLogin(t *testing.T) {
 	var service = setupCognitoService(t)
 	username := "swanhetaungp@gmail.com"
 	password := "TesTUSER123!"
-	login, err := service.Login(username, password)
+	login, err := service.Login(context.Background(), username, password)
 	require.NoError(t, err)
 	require.NotEmpty(t, login)
 }
@@ -47,6 +71,66 @@ func TestCogntoServiceSignUp(t *testing.T) {
 		"email": userName,
 	}
 
-	err := service.Register(userName, password, attrMap)
+	err := service.Register(context.Background(), userName, password, attrMap)
+	require.NoError(t, err)
+}
+
+type staticSecrets map[string]string
+
+func (s staticSecrets) GetSecret(_ context.Context, name string) (string, error) {
+	if v, ok := s[name]; ok {
+		return v, nil
+	}
+	return "", secrets.ErrSecretNotFound
+}
+
+// fakePool starts a server that answers InitiateAuth with fixed tokens and
+// records the auth parameters of each call.
+func fakePool(t *testing.T, clientSecret string) (*CognitoService, *[]map[string]string) {
+	var calls []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ AuthParameters map[string]string }
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		calls = append(calls, in.AuthParameters)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"AuthenticationResult":{"AccessToken":"access","IdToken":"id","TokenType":"Bearer","ExpiresIn":3600}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	provider := staticSecrets{}
+	if clientSecret != "" {
+		provider[secrets.CognitoClientSecret] = clientSecret
+	}
+	return &CognitoService{
+		userPoolClient: cognitoidentityprovider.New(cognitoidentityprovider.Options{
+			Region:       "eu-west-1",
+			BaseEndpoint: aws.String(srv.URL),
+			Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		}),
+		clientID: "client",
+		secrets:  secrets.NewStore(provider, time.Minute, logrus.New()),
+		log:      logrus.New(),
+	}, &calls
+}
+
+func TestRefreshTokenSendsSecretHash(t *testing.T) {
+	cg, calls := fakePool(t, "shh")
+	result, err := cg.RefreshToken(context.Background(), "user@example.com", "refresh")
+	require.NoError(t, err)
+	require.Equal(t, "access", result.AccessToken)
+
+	mac := hmac.New(sha256.New, []byte("shh"))
+	mac.Write([]byte("user@example.com" + "client"))
+	want := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	require.Len(t, *calls, 1)
+	require.Equal(t, "refresh", (*calls)[0]["REFRESH_TOKEN"])
+	require.Equal(t, want, (*calls)[0]["SECRET_HASH"])
+}
+
+func TestRefreshTokenWithoutClientSecret(t *testing.T) {
+	cg, calls := fakePool(t, "")
+	_, err := cg.RefreshToken(context.Background(), "user@example.com", "refresh")
 	require.NoError(t, err)
+	require.Len(t, *calls, 1)
+	require.NotContains(t, (*calls)[0], "SECRET_HASH")
 }
@@ -0,0 +1,27 @@
+package cognito
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/content-management-system/auth-service/pkg/secrets"
+)
+
+// secretHash computes the SECRET_HASH Cognito requires from app clients
+// that have a client secret. It returns "" when COGNITO_CLIENT_SECRET is
+// not configured, i.e. for public app clients.
+func (cg *CognitoService) secretHash(ctx context.Context, username string) (string, error) {
+	secret, err := cg.secrets.Get(ctx, secrets.CognitoClientSecret)
+	if errors.Is(err, secrets.ErrSecretNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(username + cg.clientID))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
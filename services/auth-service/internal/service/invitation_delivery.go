@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/service/cognito"
+	"github.com/content-management-system/auth-service/pkg/mailer"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// InvitationDelivery sends invitations and checks the secret an invitee
+// presents when accepting one.
+type InvitationDelivery interface {
+	Send(ctx context.Context, inv *types.Invitation, token string, resend bool) error
+	Revoke(ctx context.Context, inv *types.Invitation) error
+	// Verify checks secret against the invitation and returns the external
+	// identity created for the invitee, if the delivery created one.
+	Verify(ctx context.Context, inv *types.Invitation, secret, newPassword string) (*types.UserIdentity, error)
+}
+
+// mailInvitationDelivery emails a link carrying a one-time token.
+type mailInvitationDelivery struct {
+	mailer    mailer.Mailer
+	acceptURL string
+}
+
+func newMailInvitationDelivery(m mailer.Mailer, acceptURL string) *mailInvitationDelivery {
+	return &mailInvitationDelivery{mailer: m, acceptURL: acceptURL}
+}
+
+func (d *mailInvitationDelivery) Send(_ context.Context, inv *types.Invitation, token string, _ bool) error {
+	link := fmt.Sprintf("%s?email=%s&token=%s", d.acceptURL, url.QueryEscape(inv.Email), url.QueryEscape(token))
+	return d.mailer.Send(mailer.Message{
+		To:      inv.Email,
+		Subject: "You have been invited to the CMS",
+		Body: fmt.Sprintf(
+			"You have been invited to join the CMS. Accept the invitation before %s:\n\n%s",
+			inv.ExpiresAt.Format("2006-01-02 15:04 MST"), link,
+		),
+	})
+}
+
+func (d *mailInvitationDelivery) Revoke(context.Context, *types.Invitation) error {
+	return nil
+}
+
+func (d *mailInvitationDelivery) Verify(_ context.Context, inv *types.Invitation, secret, _ string) (*types.UserIdentity, error) {
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(inv.TokenHash)) != 1 {
+		return nil, errors.New("invitation token mismatch")
+	}
+	return nil, nil
+}
+
+// cognitoInvitationDelivery maps invitations onto AdminCreateUser, so the
+// invitee receives the pool's invite_message_template with a temporary
+// password. That temporary password is the secret used to accept.
+type cognitoInvitationDelivery struct {
+	cognito *cognito.CognitoService
+}
+
+func (d *cognitoInvitationDelivery) Send(ctx context.Context, inv *types.Invitation, _ string, resend bool) error {
+	return d.cognito.AdminCreateUser(ctx, inv.Email, resend)
+}
+
+func (d *cognitoInvitationDelivery) Revoke(ctx context.Context, inv *types.Invitation) error {
+	return d.cognito.AdminDeleteUser(ctx, inv.Email)
+}
+
+func (d *cognitoInvitationDelivery) Verify(ctx context.Context, inv *types.Invitation, secret, newPassword string) (*types.UserIdentity, error) {
+	if newPassword == "" {
+		return nil, errors.New("a new password is required to complete a Cognito invitation")
+	}
+	result, err := d.cognito.CompleteNewPassword(ctx, inv.Email, secret, newPassword)
+	if err != nil {
+		return nil, err
+	}
+	subject, _, err := d.cognito.VerifyIdentity(ctx, result.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	return &types.UserIdentity{
+		ID:       uuid.New(),
+		Provider: cognito.IdentityProvider,
+		Subject:  subject,
+	}, nil
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/service/cognito"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/mailer"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+// IdentityVerifier resolves a token issued by an external identity
+// provider to the subject and email it was issued for.
+type IdentityVerifier interface {
+	VerifyIdentity(ctx context.Context, token string) (subject string, email string, err error)
+}
+
+type InvitationParams struct {
+	fx.In
+	Config  *config.Config
+	Repos   repository.Repositories
+	UoW     repository.UnitOfWork
+	Logger  *logrus.Logger
+	Roles   *RoleService
+	Mailer  mailer.Mailer
+	Cognito *cognito.CognitoService `optional:"true"`
+}
+
+type InvitationService struct {
+	repos     repository.Repositories
+	uow       repository.UnitOfWork
+	logger    *logrus.Logger
+	roles     *RoleService
+	delivery  InvitationDelivery
+	verifiers map[string]IdentityVerifier
+}
+
+func NewInvitationService(p InvitationParams) *InvitationService {
+	s := &InvitationService{
+		repos:     p.Repos,
+		uow:       p.UoW,
+		logger:    p.Logger,
+		roles:     p.Roles,
+		delivery:  newMailInvitationDelivery(p.Mailer, p.Config.Invitation.AcceptURL),
+		verifiers: map[string]IdentityVerifier{},
+	}
+	if p.Cognito != nil {
+		s.delivery = &cognitoInvitationDelivery{cognito: p.Cognito}
+		s.verifiers[cognito.IdentityProvider] = p.Cognito
+	}
+	return s
+}
+
+func (s *InvitationService) Invite(ctx context.Context, invitedBy uuid.UUID, email string, roleID uuid.UUID, ttl time.Duration) (*types.Invitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if ttl <= 0 {
+		ttl = DefaultInvitationTTL
+	}
+	if _, err := s.roles.GetRoleByID(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	inv := &types.Invitation{
+		ID:          uuid.New(),
+		Email:       email,
+		RoleID:      roleID,
+		InvitedByID: invitedBy,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		taken, err := r.Users.EmailTaken(ctx, email, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return apperror.ErrEmailTaken
+		}
+		pending, err := r.Invitations.HasPending(ctx, email, now)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return apperror.ErrInvitationPending
+		}
+		return s.send(ctx, r, inv, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// send issues a fresh token, persists the invitation and hands it to the
+// delivery. It runs inside a unit of work so a failed delivery leaves no
+// record behind.
+func (s *InvitationService) send(ctx context.Context, r repository.Repositories, inv *types.Invitation, resend bool) error {
+	token, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	inv.TokenHash = hash
+	inv.SentCount++
+	inv.LastSentAt = &now
+
+	if err := r.Invitations.Save(ctx, inv); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to save invitation")
+		return err
+	}
+	if err := s.delivery.Send(ctx, inv, token, resend); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("invitation_id", inv.ID).Error("Failed to deliver invitation")
+		return apperror.Wrap(apperror.CodeInternal, "failed to deliver invitation", err)
+	}
+	return nil
+}
+
+func (s *InvitationService) List(ctx context.Context, status string) ([]types.Invitation, error) {
+	switch status {
+	case "", types.InvitationPending, types.InvitationExpired, types.InvitationAccepted, types.InvitationRevoked:
+	default:
+		return nil, apperror.New(apperror.CodeBadRequest, "unknown invitation status")
+	}
+
+	invitations, err := s.repos.Invitations.List(ctx, status, time.Now())
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list invitations")
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (s *InvitationService) get(ctx context.Context, r repository.Repositories, id uuid.UUID) (*types.Invitation, error) {
+	inv, err := r.Invitations.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperror.New(apperror.CodeNotFound, "invitation not found")
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Resend delivers a pending or expired invitation again with a new token
+// and restarts its original validity window.
+func (s *InvitationService) Resend(ctx context.Context, id uuid.UUID) (*types.Invitation, error) {
+	var inv *types.Invitation
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		if inv, err = s.get(ctx, r, id); err != nil {
+			return err
+		}
+		if inv.AcceptedAt != nil || inv.RevokedAt != nil {
+			return apperror.ErrInvalidInvitation
+		}
+		ttl := DefaultInvitationTTL
+		if inv.LastSentAt != nil && inv.ExpiresAt.After(*inv.LastSentAt) {
+			ttl = inv.ExpiresAt.Sub(*inv.LastSentAt)
+		}
+		inv.ExpiresAt = time.Now().Add(ttl)
+		return s.send(ctx, r, inv, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (s *InvitationService) Revoke(ctx context.Context, id uuid.UUID) error {
+	return s.uow.Do(ctx, func(r repository.Repositories) error {
+		inv, err := s.get(ctx, r, id)
+		if err != nil {
+			return err
+		}
+		revoked, err := r.Invitations.Revoke(ctx, inv.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return apperror.ErrInvalidInvitation
+		}
+		if err := s.delivery.Revoke(ctx, inv); err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithField("invitation_id", inv.ID).Error("Failed to revoke delivered invitation")
+			return apperror.Wrap(apperror.CodeInternal, "failed to revoke invitation", err)
+		}
+		return nil
+	})
+}
+
+// Accept turns a pending invitation into a user with the invited role. The
+// invitee either sets a password or links an external identity. The user
+// and invitation are written first and the delivery's secret is verified
+// last, inside the same transaction: a Cognito invitation's challenge is
+// only completed once everything local has succeeded, and a failed
+// challenge rolls the local writes back.
+func (s *InvitationService) Accept(ctx context.Context, req dto.AcceptInvitationDto) (*types.User, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	inv, err := s.repos.Invitations.FindOpen(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperror.ErrInvalidInvitation
+		}
+		return nil, err
+	}
+	if inv.Status(time.Now()) == types.InvitationExpired {
+		return nil, apperror.ErrInvitationExpired
+	}
+
+	var identities []types.UserIdentity
+	if req.IdentityToken != "" {
+		linked, err := s.verifyIdentity(ctx, req.IdentityProvider, req.IdentityToken, email)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, *linked)
+	}
+
+	password := req.Password
+	if password == "" {
+		// Identity-only accounts get a password nobody knows.
+		if password, _, err = utils.GenerateOpaqueToken(); err != nil {
+			return nil, err
+		}
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to hash password")
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &types.User{
+		ID:               uuid.New(),
+		Username:         req.Username,
+		Email:            inv.Email,
+		Password:         string(hashedPassword),
+		RoleID:           inv.RoleID,
+		RegistrationDate: now,
+		Status:           types.UserStatusActive,
+	}
+
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Users.Create(ctx, user); err != nil {
+			if apperror.CodeOf(err) == apperror.CodeInternal {
+				s.logger.WithContext(ctx).WithError(err).Error("Failed to create invited user")
+			}
+			return err
+		}
+		accepted, err := r.Invitations.Accept(ctx, inv.ID, user.ID, now)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return apperror.ErrInvalidInvitation
+		}
+
+		identity, err := s.delivery.Verify(ctx, inv, req.Token, req.Password)
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithField("invitation_id", inv.ID).Warn("Invitation verification failed")
+			return apperror.ErrInvalidInvitation
+		}
+		if identity != nil {
+			identities = append(identities, *identity)
+		}
+		for i := range identities {
+			identities[i].UserID = user.ID
+			if err := r.Identities.Create(ctx, &identities[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *InvitationService) verifyIdentity(ctx context.Context, provider, token, email string) (*types.UserIdentity, error) {
+	verifier, ok := s.verifiers[provider]
+	if !ok {
+		return nil, apperror.New(apperror.CodeBadRequest, "unsupported identity provider")
+	}
+	subject, identityEmail, err := verifier.VerifyIdentity(ctx, token)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("provider", provider).Warn("Identity verification failed")
+		return nil, apperror.New(apperror.CodeUnauthorized, "identity token could not be verified")
+	}
+	if !strings.EqualFold(identityEmail, email) {
+		return nil, apperror.New(apperror.CodeForbidden, "identity does not belong to the invited email")
+	}
+	return &types.UserIdentity{ID: uuid.New(), Provider: provider, Subject: subject}, nil
+}
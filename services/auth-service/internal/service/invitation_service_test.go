@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository/memory"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/mailer"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type outbox struct {
+	sent []mailer.Message
+}
+
+func (o *outbox) Send(msg mailer.Message) error {
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+var invitationToken = regexp.MustCompile(`token=(\S+)`)
+
+// token returns the token from the last invitation sent.
+func (o *outbox) token(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, o.sent)
+	match := invitationToken.FindStringSubmatch(o.sent[len(o.sent)-1].Body)
+	require.NotNil(t, match)
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return token
+}
+
+// countingDelivery records how often an invitee's secret was checked.
+type countingDelivery struct {
+	InvitationDelivery
+	verified int
+}
+
+func (d *countingDelivery) Verify(ctx context.Context, inv *types.Invitation, secret, newPassword string) (*types.UserIdentity, error) {
+	d.verified++
+	return d.InvitationDelivery.Verify(ctx, inv, secret, newPassword)
+}
+
+type invitationFixture struct {
+	store       *memory.Store
+	outbox      *outbox
+	delivery    *countingDelivery
+	invitations *InvitationService
+	role        types.Role
+}
+
+func newInvitationFixture(t *testing.T) *invitationFixture {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewStore()
+	repos := store.Repositories()
+	f := &invitationFixture{store: store, outbox: &outbox{}}
+	f.invitations = NewInvitationService(InvitationParams{
+		Config: &config.Config{Invitation: config.InvitationConfig{AcceptURL: "http://localhost/accept"}},
+		Repos:  repos,
+		UoW:    store,
+		Logger: logger,
+		Roles:  NewRoleService(repos, store, logger, RegistrationPolicy{DefaultRole: types.RoleCustomer}),
+		Mailer: f.outbox,
+	})
+	f.delivery = &countingDelivery{InvitationDelivery: f.invitations.delivery}
+	f.invitations.delivery = f.delivery
+	f.role = store.AddRole(types.Role{Name: "Editor"})
+	return f
+}
+
+func (f *invitationFixture) invite(t *testing.T, email string) *types.Invitation {
+	t.Helper()
+	inv, err := f.invitations.Invite(context.Background(), uuid.New(), email, f.role.ID, time.Hour)
+	require.NoError(t, err)
+	return inv
+}
+
+func (f *invitationFixture) accept(t *testing.T, email, username string) (*types.User, error) {
+	t.Helper()
+	return f.invitations.Accept(context.Background(), dto.AcceptInvitationDto{
+		Email:    email,
+		Token:    f.outbox.token(t),
+		Username: username,
+		Password: "Secret123!",
+	})
+}
+
+func TestAcceptInvitationCreatesUserWithInvitedRole(t *testing.T) {
+	f := newInvitationFixture(t)
+	f.invite(t, "Jane@Example.com")
+
+	user, err := f.accept(t, "jane@example.com", "jane")
+	require.NoError(t, err)
+	require.Equal(t, f.role.ID, user.RoleID)
+	require.Equal(t, "jane@example.com", user.Email)
+
+	accepted, err := f.invitations.List(context.Background(), types.InvitationAccepted)
+	require.NoError(t, err)
+	require.Len(t, accepted, 1)
+	require.Equal(t, user.ID, *accepted[0].AcceptedUserID)
+
+	_, err = f.accept(t, "jane@example.com", "jane2")
+	require.ErrorIs(t, err, apperror.ErrInvalidInvitation)
+}
+
+func TestAcceptExpiredInvitation(t *testing.T) {
+	f := newInvitationFixture(t)
+	inv := f.invite(t, "jane@example.com")
+	inv.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, f.store.Repositories().Invitations.Save(context.Background(), inv))
+
+	_, err := f.accept(t, "jane@example.com", "jane")
+	require.ErrorIs(t, err, apperror.ErrInvitationExpired)
+	require.Zero(t, f.delivery.verified)
+}
+
+func TestAcceptRevokedInvitation(t *testing.T) {
+	f := newInvitationFixture(t)
+	inv := f.invite(t, "jane@example.com")
+	require.NoError(t, f.invitations.Revoke(context.Background(), inv.ID))
+
+	_, err := f.accept(t, "jane@example.com", "jane")
+	require.ErrorIs(t, err, apperror.ErrInvalidInvitation)
+	require.Zero(t, f.delivery.verified)
+}
+
+func TestAcceptInvitationConflictLeavesChallengeOpen(t *testing.T) {
+	f := newInvitationFixture(t)
+	existing := types.User{Username: "jane", Email: "other@example.com", RoleID: f.role.ID, Status: types.UserStatusActive}
+	require.NoError(t, f.store.Repositories().Users.Create(context.Background(), &existing))
+	f.invite(t, "jane@example.com")
+
+	_, err := f.accept(t, "jane@example.com", "jane")
+	require.ErrorIs(t, err, apperror.ErrUsernameTaken)
+	require.Zero(t, f.delivery.verified, "the delivery challenge must not be completed when local writes fail")
+
+	pending, err := f.invitations.List(context.Background(), types.InvitationPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	_, err = f.accept(t, "jane@example.com", "jane2")
+	require.NoError(t, err)
+}
+
+func TestAcceptInvitationWithWrongTokenRollsBack(t *testing.T) {
+	f := newInvitationFixture(t)
+	f.invite(t, "jane@example.com")
+
+	_, err := f.invitations.Accept(context.Background(), dto.AcceptInvitationDto{
+		Email:    "jane@example.com",
+		Token:    "wrong",
+		Username: "jane",
+		Password: "Secret123!",
+	})
+	require.ErrorIs(t, err, apperror.ErrInvalidInvitation)
+
+	taken, err := f.store.Repositories().Users.EmailTaken(context.Background(), "jane@example.com", uuid.Nil)
+	require.NoError(t, err)
+	require.False(t, taken)
+	pending, err := f.invitations.List(context.Background(), types.InvitationPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+}
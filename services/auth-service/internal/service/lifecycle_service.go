@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// LifecycleConfig controls how long deleted accounts can be restored.
+// A pending_deletion account is soft-deleted once DeletionGrace elapses,
+// and a soft-deleted account is purged after a further PurgeRetention.
+type LifecycleConfig struct {
+	DeletionGrace  time.Duration
+	PurgeRetention time.Duration
+	SweepInterval  time.Duration
+}
+
+func LoadLifecycleConfig(cfg *config.Config) LifecycleConfig {
+	return LifecycleConfig{
+		DeletionGrace:  cfg.Lifecycle.DeletionGrace,
+		PurgeRetention: cfg.Lifecycle.PurgeRetention,
+		SweepInterval:  cfg.Lifecycle.SweepInterval,
+	}
+}
+
+type LifecycleService struct {
+	repos  repository.Repositories
+	uow    repository.UnitOfWork
+	audit  *AuditService
+	logger *logrus.Logger
+	cfg    LifecycleConfig
+}
+
+func NewLifecycleService(lc fx.Lifecycle, repos repository.Repositories, uow repository.UnitOfWork, audit *AuditService, logger *logrus.Logger, cfg LifecycleConfig) *LifecycleService {
+	s := &LifecycleService{
+		repos:  repos,
+		uow:    uow,
+		audit:  audit,
+		logger: logger,
+		cfg:    cfg,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go s.runSweeper(ctx, done)
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+			case <-stopCtx.Done():
+			}
+			return nil
+		},
+	})
+	return s
+}
+
+func (s *LifecycleService) runSweeper(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sweep(ctx, time.Now()); err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Account lifecycle sweep failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ChangeStatus moves a user to a new lifecycle status and records who did
+// it and why, in the status history and the audit log.
+func (s *LifecycleService) ChangeStatus(ctx context.Context, actor Actor, userID uuid.UUID, to, reason string) (*types.User, error) {
+	details := map[string]interface{}{"status": to, "reason": reason}
+	self := actor.ID != nil && *actor.ID == userID
+	if self {
+		details["self_service"] = true
+	}
+	event := AuditEvent{Actor: actor, Action: AuditActionStatusChange, TargetID: &userID, Outcome: AuditOutcomeSuccess, Details: details}
+	return s.transition(ctx, event, userID, to, reason, func(string) error {
+		if self && to != types.UserStatusPendingDeletion {
+			return apperror.New(apperror.CodeForbidden, "you cannot change the status of your own account")
+		}
+		return nil
+	})
+}
+
+// Restore reactivates an account that is pending deletion or soft-deleted
+// but not yet purged.
+func (s *LifecycleService) Restore(ctx context.Context, actor Actor, userID uuid.UUID, reason string) (*types.User, error) {
+	event := AuditEvent{
+		Actor:    actor,
+		Action:   AuditActionUserRestored,
+		TargetID: &userID,
+		Outcome:  AuditOutcomeSuccess,
+		Details:  map[string]interface{}{"reason": reason},
+	}
+	return s.transition(ctx, event, userID, types.UserStatusActive, reason, func(from string) error {
+		if from != types.UserStatusPendingDeletion && from != types.UserStatusDeleted {
+			return apperror.ErrInvalidStatusTransition
+		}
+		return nil
+	})
+}
+
+// transition moves userID to status to, after check approves the current
+// status, and appends event to the audit log in the same transaction. A
+// failed transition is audited on its own.
+func (s *LifecycleService) transition(ctx context.Context, event AuditEvent, userID uuid.UUID, to, reason string, check func(from string) error) (*types.User, error) {
+	var user *types.User
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		user, err = r.Users.FindAnyByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return apperror.ErrUserNotFound
+			}
+			return err
+		}
+		from := user.Status
+		if err := check(from); err != nil {
+			return err
+		}
+		if !types.CanTransitionUserStatus(from, to) {
+			return apperror.ErrInvalidStatusTransition
+		}
+
+		now := time.Now()
+		user.Status, user.StatusReason, user.StatusChangedAt = to, reason, &now
+		columns := []string{"status", "status_reason", "status_changed_at"}
+		switch to {
+		case types.UserStatusActive:
+			user.PurgeAfter, user.DeletedAt = nil, gorm.DeletedAt{}
+			columns = append(columns, "purge_after", "deleted_at")
+		case types.UserStatusPendingDeletion:
+			purgeAfter := now.Add(s.cfg.DeletionGrace)
+			user.PurgeAfter = &purgeAfter
+			columns = append(columns, "purge_after")
+		case types.UserStatusDeleted:
+			purgeAfter := now.Add(s.cfg.PurgeRetention)
+			user.PurgeAfter, user.DeletedAt = &purgeAfter, gorm.DeletedAt{Time: now, Valid: true}
+			columns = append(columns, "purge_after", "deleted_at")
+		}
+		if err := r.Users.Update(ctx, user, columns...); err != nil {
+			return err
+		}
+		if to != types.UserStatusActive {
+			if err := r.Sessions.RevokeAllForUser(ctx, user.ID, now); err != nil {
+				return err
+			}
+		}
+
+		if err := r.StatusChanges.Create(ctx, &types.UserStatusChange{
+			ID:          uuid.New(),
+			UserID:      user.ID,
+			FromStatus:  from,
+			ToStatus:    to,
+			Reason:      reason,
+			ChangedByID: event.Actor.ID,
+		}); err != nil {
+			return err
+		}
+		return s.audit.Append(ctx, r, event)
+	})
+	if err != nil {
+		s.audit.Record(ctx, NewAuditEvent(event.Actor, event.Action, event.TargetID, err, event.Details))
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"user_id": user.ID,
+		"status":  to,
+	}).Info("User status changed")
+	return user, nil
+}
+
+func (s *LifecycleService) ListUsers(ctx context.Context, status string) ([]types.User, error) {
+	users, err := s.repos.Users.ListWithRole(ctx, status)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list users")
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *LifecycleService) History(ctx context.Context, userID uuid.UUID) ([]types.UserStatusChange, error) {
+	return s.repos.StatusChanges.ListForUser(ctx, userID)
+}
+
+// Sweep soft-deletes accounts whose deletion grace period has elapsed and
+// permanently purges soft-deleted accounts past their retention.
+func (s *LifecycleService) Sweep(ctx context.Context, now time.Time) error {
+	due, err := s.repos.Users.DueForPurge(ctx, types.UserStatusPendingDeletion, now)
+	if err != nil {
+		return err
+	}
+	for _, id := range due {
+		if _, err := s.ChangeStatus(ctx, SystemActor(), id, types.UserStatusDeleted, "deletion grace period elapsed"); err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithField("user_id", id).Error("Failed to soft-delete user")
+		}
+	}
+
+	expired, err := s.repos.Users.DueForPurge(ctx, types.UserStatusDeleted, now)
+	if err != nil {
+		return err
+	}
+	for _, id := range expired {
+		if err := s.purge(ctx, id); err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithField("user_id", id).Error("Failed to purge user")
+			continue
+		}
+		s.logger.WithContext(ctx).WithField("user_id", id).Info("User purged")
+	}
+	return nil
+}
+
+// purgeReason is recorded for the hard delete that ends every retention.
+const purgeReason = "grace period elapsed"
+
+// purge permanently deletes a soft-deleted user and what belongs to them,
+// including data export archives. The audit entry is the only trail left,
+// so it commits with the delete.
+func (s *LifecycleService) purge(ctx context.Context, userID uuid.UUID) error {
+	event := AuditEvent{
+		Actor:    SystemActor(),
+		Action:   AuditActionUserPurged,
+		TargetID: &userID,
+		Outcome:  AuditOutcomeSuccess,
+		Details:  map[string]interface{}{"reason": purgeReason},
+	}
+	var exports []types.DataExport
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		if exports, err = r.DataExports.ListForUser(ctx, userID); err != nil {
+			return err
+		}
+		for _, deleteForUser := range []func(context.Context, uuid.UUID) error{
+			r.Sessions.DeleteForUser,
+			r.Tokens.DeleteForUser,
+			r.OTPs.DeleteForUser,
+			r.Identities.DeleteForUser,
+			r.DataExports.DeleteForUser,
+		} {
+			if err := deleteForUser(ctx, userID); err != nil {
+				return err
+			}
+		}
+		if err := r.Users.Delete(ctx, userID); err != nil {
+			return err
+		}
+		return s.audit.Append(ctx, r, event)
+	})
+	if err != nil {
+		s.audit.Record(ctx, NewAuditEvent(event.Actor, event.Action, event.TargetID, err, event.Details))
+		return err
+	}
+	for _, export := range exports {
+		if export.FilePath == "" {
+			continue
+		}
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			s.logger.WithContext(ctx).WithError(err).WithField("export_id", export.ID).Warn("Failed to remove data export")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/repository/memory"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx/fxtest"
+)
+
+type lifecycleFixture struct {
+	store     *memory.Store
+	audit     *AuditService
+	lifecycle *LifecycleService
+	phone     *PhoneService
+	auth      *AuthService
+	sms       *smsOutbox
+	admin     types.User
+	user      types.User
+}
+
+func newLifecycleFixture(t *testing.T) *lifecycleFixture {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewStore()
+	repos := store.Repositories()
+	lc := fxtest.NewLifecycle(t)
+	audit := NewAuditService(nil, repos, store, logger)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, secrets.JWTSigningKey), []byte(strings.Repeat("k", 32)), 0o600))
+	jwt, err := utils.NewJWT(secrets.NewStore(secrets.NewFileProvider(dir), time.Minute, logger), logger)
+	require.NoError(t, err)
+
+	f := &lifecycleFixture{
+		store:     store,
+		audit:     audit,
+		lifecycle: NewLifecycleService(lc, repos, store, audit, logger, LifecycleConfig{DeletionGrace: time.Hour, PurgeRetention: time.Hour}),
+		sms:       &smsOutbox{},
+	}
+	roles := NewRoleService(repos, store, logger, RegistrationPolicy{DefaultRole: types.RoleCustomer})
+	users := NewUserService(repos, store, logger, roles)
+	f.phone = NewPhoneService(PhoneParams{
+		Repos:  repos,
+		UoW:    store,
+		Logger: logger,
+		Users:  users,
+		OTP:    NewOTPService(repos, store, logger, f.sms),
+		JWT:    jwt,
+	})
+	f.auth = NewAuthService(users, f.phone, NewSessionService(repos, logger, jwt), audit, jwt)
+
+	role := store.AddRole(types.Role{Name: types.RoleCustomer})
+	for _, u := range []*types.User{&f.admin, &f.user} {
+		verified := time.Now()
+		*u = types.User{
+			Username:        uuid.NewString(),
+			Email:           uuid.NewString() + "@example.com",
+			RoleID:          role.ID,
+			Status:          types.UserStatusActive,
+			PhoneNumber:     testPhone,
+			PhoneVerifiedAt: &verified,
+		}
+		require.NoError(t, repos.Users.Create(context.Background(), u))
+	}
+	return f
+}
+
+func (f *lifecycleFixture) actor() Actor {
+	return Actor{ID: &f.admin.ID}
+}
+
+func TestChangeStatusRecordsHistoryAndAudit(t *testing.T) {
+	f := newLifecycleFixture(t)
+	ctx := context.Background()
+
+	user, err := f.lifecycle.ChangeStatus(ctx, f.actor(), f.user.ID, types.UserStatusSuspended, "abuse")
+	require.NoError(t, err)
+	require.Equal(t, types.UserStatusSuspended, user.Status)
+
+	history, err := f.lifecycle.History(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, types.UserStatusActive, history[0].FromStatus)
+
+	entries, err := f.audit.ForUser(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, AuditActionStatusChange, entries[0].Action)
+	require.Equal(t, AuditOutcomeSuccess, entries[0].Outcome)
+}
+
+func TestSweepPurgesAndAuditsExpiredAccounts(t *testing.T) {
+	f := newLifecycleFixture(t)
+	ctx := context.Background()
+	_, err := f.lifecycle.ChangeStatus(ctx, Actor{ID: &f.user.ID}, f.user.ID, types.UserStatusPendingDeletion, "leaving")
+	require.NoError(t, err)
+
+	// Past both the deletion grace and the retention of the soft delete.
+	require.NoError(t, f.lifecycle.Sweep(ctx, time.Now().Add(3*time.Hour)))
+
+	_, err = f.store.Repositories().Users.FindAnyByID(ctx, f.user.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	entries, err := f.audit.ForUser(ctx, f.user.ID)
+	require.NoError(t, err)
+	purged := entries[len(entries)-1]
+	require.Equal(t, AuditActionUserPurged, purged.Action)
+	require.Equal(t, AuditOutcomeSuccess, purged.Outcome)
+	require.Nil(t, purged.ActorID)
+	require.JSONEq(t, `{"reason":"grace period elapsed"}`, string(purged.Details))
+}
+
+// failingAudit makes every audit append in a unit of work fail.
+type failingAudit struct {
+	repository.AuditRepository
+}
+
+func (failingAudit) Append(context.Context, *types.AuditEntry, func(*types.AuditEntry) (string, error)) error {
+	return errors.New("audit unavailable")
+}
+
+type failingAuditUoW struct {
+	store *memory.Store
+}
+
+func (u failingAuditUoW) Do(ctx context.Context, fn func(r repository.Repositories) error) error {
+	return u.store.Do(ctx, func(r repository.Repositories) error {
+		r.Audit = failingAudit{r.Audit}
+		return fn(r)
+	})
+}
+
+func TestChangeStatusRollsBackWhenAuditFails(t *testing.T) {
+	f := newLifecycleFixture(t)
+	f.lifecycle.uow = failingAuditUoW{store: f.store}
+	ctx := context.Background()
+
+	_, err := f.lifecycle.ChangeStatus(ctx, f.actor(), f.user.ID, types.UserStatusSuspended, "abuse")
+	require.Error(t, err)
+
+	user, err := f.store.Repositories().Users.FindAnyByID(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Equal(t, types.UserStatusActive, user.Status)
+	history, err := f.lifecycle.History(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	entries, err := f.audit.ForUser(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, AuditOutcomeFailure, entries[0].Outcome)
+}
+
+func TestSuspendedUserCannotCompleteMFA(t *testing.T) {
+	f := newLifecycleFixture(t)
+	ctx := context.Background()
+	challenge, err := f.phone.StartMFAChallenge(ctx, &f.user)
+	require.NoError(t, err)
+
+	_, err = f.lifecycle.ChangeStatus(ctx, f.actor(), f.user.ID, types.UserStatusSuspended, "abuse")
+	require.NoError(t, err)
+
+	_, err = f.phone.VerifyMFA(ctx, challenge.Session, f.sms.code(t))
+	require.ErrorIs(t, err, apperror.ErrAccountInactive)
+}
+
+func TestPhoneRecoveryRevokesSessions(t *testing.T) {
+	f := newLifecycleFixture(t)
+	ctx := context.Background()
+	f.setPassword(t, "Secret123!")
+	login, err := f.auth.Login(ctx, Actor{}, f.user.Email, "Secret123!")
+	require.NoError(t, err)
+
+	require.NoError(t, f.phone.StartRecovery(ctx, f.user.Email))
+	require.NoError(t, f.phone.CompleteRecovery(ctx, f.user.Email, f.sms.code(t), "NewSecret123!"))
+
+	_, err = f.auth.Refresh(ctx, Actor{}, login.RefreshToken)
+	require.ErrorIs(t, err, apperror.ErrInvalidToken)
+	_, err = f.auth.Login(ctx, Actor{}, f.user.Email, "NewSecret123!")
+	require.NoError(t, err)
+}
+
+func TestSuspendedUserCannotRecoverByPhone(t *testing.T) {
+	f := newLifecycleFixture(t)
+	ctx := context.Background()
+	require.NoError(t, f.phone.StartRecovery(ctx, f.user.Email))
+	require.Len(t, f.sms.sent, 1)
+	code := f.sms.code(t)
+
+	_, err := f.lifecycle.ChangeStatus(ctx, f.actor(), f.user.ID, types.UserStatusSuspended, "abuse")
+	require.NoError(t, err)
+
+	err = f.phone.CompleteRecovery(ctx, f.user.Email, code, "NewSecret123!")
+	require.ErrorIs(t, err, apperror.ErrAccountInactive)
+	user, err := f.store.Repositories().Users.FindAnyByID(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Equal(t, f.user.Password, user.Password)
+
+	require.NoError(t, f.phone.StartRecovery(ctx, f.user.Email))
+	require.Len(t, f.sms.sent, 1, "no recovery code is sent to a suspended account")
+}
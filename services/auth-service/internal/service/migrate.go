@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/migrations"
+	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/content-management-system/auth-service/pkg/health"
+	"github.com/content-management-system/auth-service/pkg/migrate"
+	"github.com/sirupsen/logrus"
+)
+
+const schemaCheckTimeout = 5 * time.Minute
+
+func NewMigrator(db *db.DB, logger *logrus.Logger) (*migrate.Migrator, error) {
+	sqlDB, err := db.Conn.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, migrations.FS, logger)
+}
+
+// RequireSchema refuses to start the service while migrations are pending.
+// With DB_AUTO_MIGRATE=true they are applied instead, which is meant for
+// local development; deployments run `server migrate up` first.
+func RequireSchema(m *migrate.Migrator, cfg *config.Config, logger *logrus.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), schemaCheckTimeout)
+	defer cancel()
+
+	if cfg.DB.AutoMigrate {
+		logger.Info("DB_AUTO_MIGRATE is set, applying pending migrations")
+		if err := m.Up(ctx, 0); err != nil {
+			return err
+		}
+	}
+	return m.EnsureCurrent(ctx)
+}
+
+// NewSchemaCheck fails readiness while the database schema is behind the
+// embedded migrations or has drifted from them, e.g. after a rollback
+// behind the running version. A schema already migrated by a newer
+// release is ready.
+func NewSchemaCheck(m *migrate.Migrator) health.Check {
+	return health.Check{
+		Name:        "migrations",
+		Criticality: health.Critical,
+		CacheFor:    30 * time.Second,
+		Run:         m.Ready,
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/sms"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	otpDigits      = 6
+	otpTTL         = 5 * time.Minute
+	otpMaxAttempts = 5
+	otpResendDelay = time.Minute
+)
+
+// OTPService issues and checks SMS one-time codes. Codes are hashed with
+// their record ID as salt, expire after otpTTL and lock after
+// otpMaxAttempts wrong guesses.
+type OTPService struct {
+	repos  repository.Repositories
+	uow    repository.UnitOfWork
+	logger *logrus.Logger
+	sender sms.SMSSender
+}
+
+func NewOTPService(repos repository.Repositories, uow repository.UnitOfWork, logger *logrus.Logger, sender sms.SMSSender) *OTPService {
+	return &OTPService{
+		repos:  repos,
+		uow:    uow,
+		logger: logger,
+		sender: sender,
+	}
+}
+
+// Send issues a new code, unless one was issued within otpResendDelay.
+func (s *OTPService) Send(ctx context.Context, userID uuid.UUID, phoneNumber, purpose string) error {
+	last, err := s.latest(ctx, userID, purpose)
+	if err != nil {
+		return err
+	}
+	if last != nil && time.Since(last.CreatedAt) < otpResendDelay {
+		return apperror.ErrOTPRateLimited
+	}
+	return s.issue(ctx, userID, phoneNumber, purpose)
+}
+
+// Challenge makes sure the user has a code to answer a login challenge
+// with. Within otpResendDelay of the last code, a repeated challenge reuses
+// that code if it can still be answered instead of being rate limited, so
+// retrying a login does not fail while the code is on its way.
+func (s *OTPService) Challenge(ctx context.Context, userID uuid.UUID, phoneNumber, purpose string) error {
+	last, err := s.latest(ctx, userID, purpose)
+	if err != nil {
+		return err
+	}
+	if last != nil && time.Since(last.CreatedAt) < otpResendDelay && last.PhoneNumber == phoneNumber && last.ConsumedAt == nil {
+		if last.Attempts >= otpMaxAttempts {
+			return apperror.ErrOTPRateLimited
+		}
+		return nil
+	}
+	return s.issue(ctx, userID, phoneNumber, purpose)
+}
+
+func (s *OTPService) latest(ctx context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error) {
+	last, err := s.repos.OTPs.Latest(ctx, userID, purpose)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return last, err
+}
+
+func (s *OTPService) issue(ctx context.Context, userID uuid.UUID, phoneNumber, purpose string) error {
+	code, err := generateOTPCode()
+	if err != nil {
+		return err
+	}
+	otp := types.PhoneOTP{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Purpose:     purpose,
+		PhoneNumber: phoneNumber,
+		ExpiresAt:   time.Now().Add(otpTTL),
+	}
+	otp.CodeHash = hashOTP(otp.ID, code)
+
+	return s.uow.Do(ctx, func(r repository.Repositories) error {
+		// A new code supersedes any outstanding one for the same purpose.
+		if err := r.OTPs.ConsumeAllForUser(ctx, userID, purpose, time.Now()); err != nil {
+			return err
+		}
+		if err := r.OTPs.Create(ctx, &otp); err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to store OTP")
+			return err
+		}
+		message := fmt.Sprintf("Your CMS verification code is %s. It expires in %d minutes.", code, int(otpTTL.Minutes()))
+		if err := s.sender.Send(phoneNumber, message); err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to send OTP")
+			return apperror.Wrap(apperror.CodeInternal, "failed to send verification code", err)
+		}
+		return nil
+	})
+}
+
+// Verify consumes the outstanding code for userID and purpose if code
+// matches it. It returns the phone number the code was sent to.
+func (s *OTPService) Verify(ctx context.Context, userID uuid.UUID, purpose, code string) (string, error) {
+	var phoneNumber string
+	var verifyErr error
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		otp, err := r.OTPs.Outstanding(ctx, userID, purpose)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				verifyErr = apperror.ErrInvalidOTP
+				return nil
+			}
+			return err
+		}
+		if time.Now().After(otp.ExpiresAt) {
+			verifyErr = apperror.ErrInvalidOTP
+			return nil
+		}
+		if otp.Attempts >= otpMaxAttempts {
+			verifyErr = apperror.ErrOTPLocked
+			return nil
+		}
+
+		if subtle.ConstantTimeCompare([]byte(hashOTP(otp.ID, code)), []byte(otp.CodeHash)) != 1 {
+			// The failed attempt must be committed, so it is reported
+			// through verifyErr rather than rolling the transaction back.
+			verifyErr = apperror.ErrInvalidOTP
+			return r.OTPs.CountAttempt(ctx, otp.ID)
+		}
+
+		consumed, err := r.OTPs.Consume(ctx, otp.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			verifyErr = apperror.ErrInvalidOTP
+			return nil
+		}
+		phoneNumber = otp.PhoneNumber
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return phoneNumber, verifyErr
+}
+
+func generateOTPCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n), nil
+}
+
+func hashOTP(id uuid.UUID, code string) string {
+	return utils.HashToken(id.String() + ":" + code)
+}
+
+// MaskPhoneNumber keeps only the last four digits visible.
+func MaskPhoneNumber(phone string) string {
+	if len(phone) <= 4 {
+		return phone
+	}
+	masked := []byte(phone)
+	for i := 1; i < len(masked)-4; i++ {
+		masked[i] = '*'
+	}
+	return string(masked)
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/repository/memory"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type smsOutbox struct {
+	sent []string
+}
+
+func (o *smsOutbox) Send(_, message string) error {
+	o.sent = append(o.sent, message)
+	return nil
+}
+
+var otpCode = regexp.MustCompile(`\d{6}`)
+
+// code returns the code from the last message sent.
+func (o *smsOutbox) code(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, o.sent)
+	return otpCode.FindString(o.sent[len(o.sent)-1])
+}
+
+// agedOTPs reports every stored code as issued age ago, so tests can move
+// past the resend delay and expiry without sleeping.
+type agedOTPs struct {
+	repository.OTPRepository
+	age *time.Duration
+}
+
+func (r agedOTPs) shift(otp *types.PhoneOTP, err error) (*types.PhoneOTP, error) {
+	if otp != nil {
+		otp.CreatedAt = otp.CreatedAt.Add(-*r.age)
+		otp.ExpiresAt = otp.ExpiresAt.Add(-*r.age)
+	}
+	return otp, err
+}
+
+func (r agedOTPs) Latest(ctx context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error) {
+	return r.shift(r.OTPRepository.Latest(ctx, userID, purpose))
+}
+
+func (r agedOTPs) Outstanding(ctx context.Context, userID uuid.UUID, purpose string) (*types.PhoneOTP, error) {
+	return r.shift(r.OTPRepository.Outstanding(ctx, userID, purpose))
+}
+
+type agedOTPUoW struct {
+	store *memory.Store
+	age   *time.Duration
+}
+
+func (u agedOTPUoW) Do(ctx context.Context, fn func(r repository.Repositories) error) error {
+	return u.store.Do(ctx, func(r repository.Repositories) error {
+		r.OTPs = agedOTPs{r.OTPs, u.age}
+		return fn(r)
+	})
+}
+
+type otpFixture struct {
+	otp    *OTPService
+	outbox *smsOutbox
+	userID uuid.UUID
+	age    time.Duration
+}
+
+func newOTPFixture(t *testing.T) *otpFixture {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewStore()
+	f := &otpFixture{outbox: &smsOutbox{}, userID: uuid.New()}
+	repos := store.Repositories()
+	repos.OTPs = agedOTPs{repos.OTPs, &f.age}
+	f.otp = NewOTPService(repos, agedOTPUoW{store, &f.age}, logger, f.outbox)
+	return f
+}
+
+const testPhone = "+15550100"
+
+func (f *otpFixture) send(t *testing.T) {
+	t.Helper()
+	require.NoError(t, f.otp.Send(context.Background(), f.userID, testPhone, types.OTPPurposeMFA))
+}
+
+func (f *otpFixture) verify(code string) error {
+	_, err := f.otp.Verify(context.Background(), f.userID, types.OTPPurposeMFA, code)
+	return err
+}
+
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "000001"
+	}
+	return "000000"
+}
+
+func TestOTPVerifyConsumesCode(t *testing.T) {
+	f := newOTPFixture(t)
+	f.send(t)
+	code := f.outbox.code(t)
+
+	phone, err := f.otp.Verify(context.Background(), f.userID, types.OTPPurposeMFA, code)
+	require.NoError(t, err)
+	require.Equal(t, testPhone, phone)
+	require.ErrorIs(t, f.verify(code), apperror.ErrInvalidOTP)
+}
+
+func TestOTPExpires(t *testing.T) {
+	f := newOTPFixture(t)
+	f.send(t)
+	f.age = otpTTL + time.Second
+
+	require.ErrorIs(t, f.verify(f.outbox.code(t)), apperror.ErrInvalidOTP)
+}
+
+func TestOTPLocksAfterMaxAttempts(t *testing.T) {
+	f := newOTPFixture(t)
+	f.send(t)
+	code := f.outbox.code(t)
+
+	for i := 0; i < otpMaxAttempts; i++ {
+		require.ErrorIs(t, f.verify(wrongCode(code)), apperror.ErrInvalidOTP)
+	}
+	require.ErrorIs(t, f.verify(code), apperror.ErrOTPLocked)
+}
+
+func TestOTPSendIsRateLimited(t *testing.T) {
+	f := newOTPFixture(t)
+	f.send(t)
+	first := f.outbox.code(t)
+
+	err := f.otp.Send(context.Background(), f.userID, testPhone, types.OTPPurposeMFA)
+	require.ErrorIs(t, err, apperror.ErrOTPRateLimited)
+	require.Len(t, f.outbox.sent, 1)
+
+	f.age = otpResendDelay
+	f.send(t)
+	require.Len(t, f.outbox.sent, 2)
+	require.ErrorIs(t, f.verify(first), apperror.ErrInvalidOTP, "a new code supersedes the previous one")
+	require.NoError(t, f.verify(f.outbox.code(t)))
+}
+
+func TestOTPChallengeReusesRecentCode(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+	require.NoError(t, f.otp.Challenge(ctx, f.userID, testPhone, types.OTPPurposeMFA))
+	require.NoError(t, f.otp.Challenge(ctx, f.userID, testPhone, types.OTPPurposeMFA))
+	require.Len(t, f.outbox.sent, 1, "a repeated challenge must reuse the outstanding code")
+	code := f.outbox.code(t)
+	require.NoError(t, f.verify(code))
+
+	// Once answered, the next login is challenged with a fresh code.
+	require.NoError(t, f.otp.Challenge(ctx, f.userID, testPhone, types.OTPPurposeMFA))
+	require.Len(t, f.outbox.sent, 2)
+
+	for i := 0; i < otpMaxAttempts; i++ {
+		require.ErrorIs(t, f.verify(wrongCode(f.outbox.code(t))), apperror.ErrInvalidOTP)
+	}
+	err := f.otp.Challenge(ctx, f.userID, testPhone, types.OTPPurposeMFA)
+	require.ErrorIs(t, err, apperror.ErrOTPRateLimited, "a locked code is not replaced within the resend delay")
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/service/cognito"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const ChallengeSMSMFA = "SMS_MFA"
+
+type PhoneParams struct {
+	fx.In
+	Repos   repository.Repositories
+	UoW     repository.UnitOfWork
+	Logger  *logrus.Logger
+	Users   *UserService
+	OTP     *OTPService
+	JWT     *utils.JWT
+	Cognito *cognito.CognitoService `optional:"true"`
+}
+
+// PhoneService verifies phone numbers and uses verified numbers as an MFA
+// factor and for password recovery.
+type PhoneService struct {
+	repos   repository.Repositories
+	uow     repository.UnitOfWork
+	logger  *logrus.Logger
+	users   *UserService
+	otp     *OTPService
+	jwt     *utils.JWT
+	cognito *cognito.CognitoService
+}
+
+func NewPhoneService(p PhoneParams) *PhoneService {
+	return &PhoneService{
+		repos:   p.Repos,
+		uow:     p.UoW,
+		logger:  p.Logger,
+		users:   p.Users,
+		otp:     p.OTP,
+		jwt:     p.JWT,
+		cognito: p.Cognito,
+	}
+}
+
+func (s *PhoneService) StartVerification(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PhoneNumber == "" {
+		return apperror.New(apperror.CodeBadRequest, "no phone number on the profile")
+	}
+	if user.PhoneVerifiedAt != nil {
+		return apperror.New(apperror.CodeBadRequest, "phone number is already verified")
+	}
+	return s.otp.Send(ctx, user.ID, user.PhoneNumber, types.OTPPurposePhoneVerification)
+}
+
+func (s *PhoneService) ConfirmVerification(ctx context.Context, userID uuid.UUID, code string) (*types.User, error) {
+	user, err := s.users.GetUserWithRole(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	phone, err := s.otp.Verify(ctx, user.ID, types.OTPPurposePhoneVerification, code)
+	if err != nil {
+		return nil, err
+	}
+	// The number may have changed since the code was sent.
+	if phone != user.PhoneNumber {
+		return nil, apperror.ErrInvalidOTP
+	}
+
+	now := time.Now()
+	user.PhoneVerifiedAt = &now
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Users.Update(ctx, user, "phone_verified_at"); err != nil {
+			return err
+		}
+		if s.cognito != nil {
+			if err := s.cognito.UpdateUserAttributes(ctx, user.Email, map[string]string{"phone_number_verified": "true"}); err != nil {
+				return apperror.Wrap(apperror.CodeInternal, "failed to update identity provider", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetMFA turns SMS MFA on or off. Both directions require the current
+// password, and enabling requires a verified phone number.
+func (s *PhoneService) SetMFA(ctx context.Context, userID uuid.UUID, enabled bool, password string) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return apperror.ErrInvalidCredentials
+	}
+	if enabled && user.PhoneVerifiedAt == nil {
+		return apperror.ErrPhoneNotVerified
+	}
+	user.MFAEnabled = enabled
+	return s.repos.Users.Update(ctx, user, "mfa_enabled")
+}
+
+// StartMFAChallenge sends a code to the user's verified phone, or reuses
+// the one sent for a challenge moments ago, and returns the challenge the
+// client must answer through VerifyMFA.
+func (s *PhoneService) StartMFAChallenge(ctx context.Context, user *types.User) (*types.AuthResult, error) {
+	if user.PhoneVerifiedAt == nil {
+		return nil, apperror.ErrPhoneNotVerified
+	}
+	if err := s.otp.Challenge(ctx, user.ID, user.PhoneNumber, types.OTPPurposeMFA); err != nil {
+		return nil, err
+	}
+	session, err := s.jwt.GenerateMFASessionToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &types.AuthResult{
+		ChallengeName: ChallengeSMSMFA,
+		Session:       session,
+		ChallengeParameters: map[string]string{
+			"CODE_DELIVERY_DELIVERY_MEDIUM": "SMS",
+			"CODE_DELIVERY_DESTINATION":     MaskPhoneNumber(user.PhoneNumber),
+		},
+	}, nil
+}
+
+func (s *PhoneService) VerifyMFA(ctx context.Context, session, code string) (*types.User, error) {
+	claims, err := s.jwt.ValidateToken(session)
+	if err != nil || claims.TokenUse != utils.TokenUseMFASession {
+		return nil, apperror.ErrInvalidToken
+	}
+	if _, err := s.otp.Verify(ctx, claims.UserID, types.OTPPurposeMFA, code); err != nil {
+		return nil, err
+	}
+	// The account may have been suspended since the password was checked.
+	user, err := s.users.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != types.UserStatusActive {
+		return nil, apperror.ErrAccountInactive
+	}
+	return user, nil
+}
+
+// StartRecovery sends a recovery code to the verified phone of the account
+// with the given email. It reports success for unknown accounts so callers
+// cannot probe which emails exist.
+func (s *PhoneService) StartRecovery(ctx context.Context, email string) error {
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, apperror.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.PhoneVerifiedAt == nil || user.Status != types.UserStatusActive {
+		s.logger.WithContext(ctx).WithField("user_id", user.ID).Info("Phone recovery requested for an account that cannot recover")
+		return nil
+	}
+	err = s.otp.Send(ctx, user.ID, user.PhoneNumber, types.OTPPurposeRecovery)
+	if errors.Is(err, apperror.ErrOTPRateLimited) {
+		return nil
+	}
+	return err
+}
+
+// CompleteRecovery sets a new password once the recovery code checks out
+// and signs the account out everywhere, since whoever held it before may
+// not be its owner.
+func (s *PhoneService) CompleteRecovery(ctx context.Context, email, code, newPassword string) error {
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, apperror.ErrUserNotFound) {
+			return apperror.ErrInvalidOTP
+		}
+		return err
+	}
+	if _, err := s.otp.Verify(ctx, user.ID, types.OTPPurposeRecovery, code); err != nil {
+		return err
+	}
+	if user.Status != types.UserStatusActive {
+		return apperror.ErrAccountInactive
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to hash password")
+		return err
+	}
+	user.Password = string(hashedPassword)
+	return s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Users.Update(ctx, user, "password"); err != nil {
+			return err
+		}
+		if err := r.Sessions.RevokeAllForUser(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+		if s.cognito != nil {
+			if err := s.cognito.SetUserPassword(ctx, user.Email, newPassword); err != nil {
+				return apperror.Wrap(apperror.CodeInternal, "failed to update identity provider", err)
+			}
+		}
+		return nil
+	})
+}
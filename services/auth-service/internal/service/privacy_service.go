@@ -0,0 +1,584 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/service/cognito"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+const (
+	exportPollInterval     = 30 * time.Second
+	erasedEmailDomain      = "erased.invalid"
+	identityDeleteAttempts = 3
+)
+
+// identityProvider removes users from the external identity provider.
+type identityProvider interface {
+	AdminDeleteUser(ctx context.Context, username string) error
+}
+
+// ContentSource contributes a user's authored content to data exports.
+// Modules that store content on behalf of users provide one in the
+// "content_sources" group.
+type ContentSource interface {
+	Name() string
+	ExportContent(ctx context.Context, userID uuid.UUID) (interface{}, error)
+}
+
+type PrivacyConfig struct {
+	ExportDir string
+	ExportTTL time.Duration
+}
+
+func LoadPrivacyConfig(cfg *config.Config) PrivacyConfig {
+	return PrivacyConfig{
+		ExportDir: cfg.Privacy.ExportDir,
+		ExportTTL: cfg.Privacy.ExportTTL,
+	}
+}
+
+type PrivacyParams struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	Repos     repository.Repositories
+	UoW       repository.UnitOfWork
+	Logger    *logrus.Logger
+	Auditor   Auditor
+	Audit     *AuditService
+	Profiles  *ProfileService
+	Config    PrivacyConfig
+	Sources   []ContentSource         `group:"content_sources"`
+	Cognito   *cognito.CognitoService `optional:"true"`
+}
+
+// PrivacyService implements data subject requests: building export
+// archives of a user's data and erasing a user's personal data.
+type PrivacyService struct {
+	repos    repository.Repositories
+	uow      repository.UnitOfWork
+	logger   *logrus.Logger
+	auditor  Auditor
+	audit    *AuditService
+	profiles *ProfileService
+	cfg      PrivacyConfig
+	sources  []ContentSource
+	idp      identityProvider
+	// idpBackoff is the delay before the first retry of an identity
+	// provider deletion; it doubles with each attempt.
+	idpBackoff time.Duration
+	wake       chan struct{}
+}
+
+func NewPrivacyService(p PrivacyParams) *PrivacyService {
+	s := &PrivacyService{
+		repos:      p.Repos,
+		uow:        p.UoW,
+		logger:     p.Logger,
+		auditor:    p.Auditor,
+		audit:      p.Audit,
+		profiles:   p.Profiles,
+		cfg:        p.Config,
+		sources:    p.Sources,
+		idpBackoff: 200 * time.Millisecond,
+		wake:       make(chan struct{}, 1),
+	}
+	if p.Cognito != nil {
+		s.idp = p.Cognito
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			if err := os.MkdirAll(s.cfg.ExportDir, 0o700); err != nil {
+				cancel()
+				return err
+			}
+			go s.runWorker(ctx, done)
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+			case <-stopCtx.Done():
+			}
+			return nil
+		},
+	})
+	return s
+}
+
+// RequestExport queues an archive of everything held about userID. An
+// export that is still pending is returned instead of queueing another.
+func (s *PrivacyService) RequestExport(ctx context.Context, actor Actor, userID uuid.UUID) (*types.DataExport, error) {
+	user, err := s.repos.Users.FindAnyByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperror.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.Status == types.UserStatusErased {
+		return nil, apperror.ErrUserNotFound
+	}
+
+	var export *types.DataExport
+	created := false
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		export, err = r.DataExports.FindPending(ctx, userID)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		export = &types.DataExport{
+			ID:     uuid.New(),
+			UserID: userID,
+			Status: types.DataExportPending,
+		}
+		if actor.ID != nil {
+			export.RequestedByID = *actor.ID
+		}
+		if err := r.DataExports.Create(ctx, export); err != nil {
+			return err
+		}
+		created = true
+		return s.audit.Append(ctx, r, AuditEvent{
+			Actor:    actor,
+			Action:   AuditActionDataExportRequested,
+			TargetID: &userID,
+			Outcome:  AuditOutcomeSuccess,
+			Details:  map[string]interface{}{"export_id": export.ID},
+		})
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create data export")
+		return nil, err
+	}
+
+	if created {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return export, nil
+}
+
+// GetExport loads an export. When ownerID is set the export must belong to
+// that user.
+func (s *PrivacyService) GetExport(ctx context.Context, exportID uuid.UUID, ownerID *uuid.UUID) (*types.DataExport, error) {
+	export, err := s.repos.DataExports.Find(ctx, exportID, ownerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperror.New(apperror.CodeNotFound, "data export not found")
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *PrivacyService) ListExports(ctx context.Context, userID uuid.UUID) ([]types.DataExport, error) {
+	return s.repos.DataExports.ListForUser(ctx, userID)
+}
+
+// OpenExport returns the archive path of a finished export and records
+// the download.
+func (s *PrivacyService) OpenExport(ctx context.Context, actor Actor, exportID uuid.UUID, ownerID *uuid.UUID) (string, error) {
+	export, err := s.GetExport(ctx, exportID, ownerID)
+	if err != nil {
+		return "", err
+	}
+	if export.Status != types.DataExportReady {
+		return "", apperror.New(apperror.CodeConflict, "data export is not ready")
+	}
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		return "", apperror.New(apperror.CodeNotFound, "data export has expired")
+	}
+
+	s.auditor.Record(ctx, AuditEvent{
+		Actor:    actor,
+		Action:   AuditActionDataExportDownloaded,
+		TargetID: &export.UserID,
+		Outcome:  AuditOutcomeSuccess,
+		Details:  map[string]interface{}{"export_id": export.ID},
+	})
+	return export.FilePath, nil
+}
+
+func (s *PrivacyService) runWorker(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(exportPollInterval)
+	defer ticker.Stop()
+	for {
+		s.processPending(ctx)
+		s.removeExpired(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// processPending builds exports one at a time until none are waiting. Each
+// export is claimed inside the unit of work that records its result, so
+// concurrent workers skip it and a crash mid-build leaves it pending.
+func (s *PrivacyService) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed := false
+		err := s.uow.Do(ctx, func(r repository.Repositories) error {
+			export, err := r.DataExports.ClaimPending(ctx)
+			if err != nil {
+				if errors.Is(err, repository.ErrNotFound) {
+					return nil
+				}
+				return err
+			}
+			claimed = true
+			return s.build(ctx, r, export)
+		})
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to process pending data export")
+			return
+		}
+		if !claimed {
+			return
+		}
+	}
+}
+
+// build writes the archive for a claimed export and marks it ready or
+// failed through r, together with the audit entry.
+func (s *PrivacyService) build(ctx context.Context, r repository.Repositories, export *types.DataExport) error {
+	path := filepath.Join(s.cfg.ExportDir, export.ID.String()+".zip")
+	size, err := s.writeArchive(ctx, export.UserID, path)
+	now := time.Now()
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("export_id", export.ID).Error("Failed to build data export")
+		export.Status, export.Error, export.CompletedAt = types.DataExportFailed, err.Error(), &now
+		if err := r.DataExports.Update(ctx, export, "status", "error", "completed_at"); err != nil {
+			return err
+		}
+		return s.audit.Append(ctx, r, AuditEvent{
+			Actor:    SystemActor(),
+			Action:   AuditActionDataExportFailed,
+			TargetID: &export.UserID,
+			Outcome:  AuditOutcomeFailure,
+			Details:  map[string]interface{}{"export_id": export.ID},
+		})
+	}
+
+	expiresAt := now.Add(s.cfg.ExportTTL)
+	export.Status, export.FilePath, export.SizeBytes = types.DataExportReady, path, size
+	export.CompletedAt, export.ExpiresAt = &now, &expiresAt
+	err = r.DataExports.Update(ctx, export, "status", "file_path", "size_bytes", "completed_at", "expires_at")
+	if err == nil {
+		err = s.audit.Append(ctx, r, AuditEvent{
+			Actor:    SystemActor(),
+			Action:   AuditActionDataExportCompleted,
+			TargetID: &export.UserID,
+			Outcome:  AuditOutcomeSuccess,
+			Details:  map[string]interface{}{"export_id": export.ID, "size_bytes": size},
+		})
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+func (s *PrivacyService) writeArchive(ctx context.Context, userID uuid.UUID, path string) (int64, error) {
+	files, err := s.collect(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	zw := zip.NewWriter(f)
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			f.Close()
+			return 0, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			f.Close()
+			return 0, err
+		}
+		names = append(names, file.name)
+	}
+	w, err := zw.Create("manifest.json")
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":      userID,
+		"generated_at": time.Now().UTC(),
+		"files":        names,
+	}); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	return info.Size(), os.Rename(tmp, path)
+}
+
+type exportFile struct {
+	name string
+	data interface{}
+}
+
+func (s *PrivacyService) collect(ctx context.Context, userID uuid.UUID) ([]exportFile, error) {
+	user, err := s.repos.Users.FindAnyByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.repos.Sessions.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := s.repos.Identities.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repos.StatusChanges.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.repos.Invitations.ListAcceptedBy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	auditEntries, err := s.audit.ForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []exportFile{
+		{name: "profile.json", data: user},
+		{name: "sessions.json", data: sessions},
+		{name: "identities.json", data: identities},
+		{name: "status_history.json", data: history},
+		{name: "invitations.json", data: invitations},
+		{name: "audit_log.json", data: auditEntries},
+	}
+	for _, source := range s.sources {
+		content, err := source.ExportContent(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("content source %s: %w", source.Name(), err)
+		}
+		files = append(files, exportFile{name: "content/" + source.Name() + ".json", data: content})
+	}
+	return files, nil
+}
+
+func (s *PrivacyService) removeExpired(ctx context.Context, now time.Time) {
+	expired, err := s.repos.DataExports.ListExpired(ctx, now)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to load expired data exports")
+		return
+	}
+	for _, export := range expired {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			s.logger.WithContext(ctx).WithError(err).WithField("export_id", export.ID).Warn("Failed to remove data export")
+			continue
+		}
+		if err := s.repos.DataExports.Delete(ctx, export.ID); err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithField("export_id", export.ID).Warn("Failed to delete data export")
+		}
+	}
+}
+
+// Erase removes a user's personal data. The user row is kept, with its PII
+// replaced by placeholders, so content and history that reference it stay
+// valid. The user is also removed from Cognito when it is configured.
+// That happens before the transaction, so no database lock, least of all
+// the audit chain's, is held across calls to Cognito: a Cognito failure
+// leaves the user untouched, and repeating an erasure whose commit failed
+// finds the Cognito user already gone.
+func (s *PrivacyService) Erase(ctx context.Context, actor Actor, userID uuid.UUID, reason string) (*types.User, error) {
+	if actor.ID != nil && *actor.ID == userID {
+		return nil, apperror.New(apperror.CodeForbidden, "you cannot erase your own account")
+	}
+
+	user, err := s.repos.Users.FindAnyByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperror.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if !types.CanTransitionUserStatus(user.Status, types.UserStatusErased) {
+		return nil, apperror.ErrInvalidStatusTransition
+	}
+
+	avatarURL := user.AvatarURL
+	var exports []types.DataExport
+	stage := "identity_provider"
+	if s.idp != nil {
+		if err = s.deleteIdentity(ctx, user.Email); err != nil {
+			err = apperror.Wrap(apperror.CodeInternal, "failed to delete user from identity provider", err)
+		}
+	}
+	if err == nil {
+		stage = "database"
+		exports, err = s.eraseLocally(ctx, actor, user, reason)
+	}
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("user_id", user.ID).Error("Failed to erase user")
+		s.auditor.Record(ctx, AuditEvent{
+			Actor:    actor,
+			Action:   AuditActionUserErased,
+			TargetID: &userID,
+			Outcome:  AuditOutcomeFailure,
+			Details:  map[string]interface{}{"stage": stage},
+		})
+		return nil, err
+	}
+
+	for _, export := range exports {
+		if export.FilePath != "" {
+			_ = os.Remove(export.FilePath)
+		}
+	}
+	if strings.HasPrefix(avatarURL, AvatarURLPrefix+"/") {
+		_ = os.Remove(filepath.Join(s.profiles.AvatarDir(), filepath.Base(avatarURL)))
+	}
+	return user, nil
+}
+
+// eraseLocally anonymizes user and deletes what belongs to them in one
+// transaction, returning the exports whose archives are left to remove.
+func (s *PrivacyService) eraseLocally(ctx context.Context, actor Actor, user *types.User, reason string) ([]types.DataExport, error) {
+	from, email := user.Status, user.Email
+	var exports []types.DataExport
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		for _, deleteForUser := range []func(context.Context, uuid.UUID) error{
+			r.Sessions.DeleteForUser,
+			r.Tokens.DeleteForUser,
+			r.OTPs.DeleteForUser,
+			r.Identities.DeleteForUser,
+		} {
+			if err := deleteForUser(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+		var err error
+		if exports, err = r.DataExports.ListForUser(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := r.DataExports.DeleteForUser(ctx, user.ID); err != nil {
+			return err
+		}
+
+		placeholder := "erased-" + user.ID.String()
+		erasedEmail := placeholder + "@" + erasedEmailDomain
+		if err := r.Invitations.ReplaceEmail(ctx, user.ID, email, erasedEmail); err != nil {
+			return err
+		}
+		anonymize(user, placeholder, erasedEmail, reason, time.Now())
+		if err := r.Users.Update(ctx, user, erasedColumns...); err != nil {
+			return err
+		}
+		if err := r.StatusChanges.Create(ctx, &types.UserStatusChange{
+			ID:          uuid.New(),
+			UserID:      user.ID,
+			FromStatus:  from,
+			ToStatus:    types.UserStatusErased,
+			Reason:      reason,
+			ChangedByID: actor.ID,
+		}); err != nil {
+			return err
+		}
+		return s.audit.Append(ctx, r, AuditEvent{
+			Actor:    actor,
+			Action:   AuditActionUserErased,
+			TargetID: &user.ID,
+			Outcome:  AuditOutcomeSuccess,
+			Details:  map[string]interface{}{"reason": reason},
+		})
+	})
+	return exports, err
+}
+
+// deleteIdentity removes email from the identity provider, retrying
+// transient failures. A user that is already gone counts as deleted.
+func (s *PrivacyService) deleteIdentity(ctx context.Context, email string) error {
+	backoff := s.idpBackoff
+	for attempt := 1; ; attempt++ {
+		err := s.idp.AdminDeleteUser(ctx, email)
+		if err == nil || cognito.IsUserNotFound(err) {
+			return nil
+		}
+		if attempt == identityDeleteAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// erasedColumns are the user columns anonymize rewrites.
+var erasedColumns = []string{
+	"username", "email", "password", "name", "address", "phone_number",
+	"phone_verified_at", "mfa_enabled", "avatar_url", "status",
+	"status_reason", "status_changed_at", "purge_after", "deleted_at",
+}
+
+// anonymize replaces the user's personal data with placeholders and marks
+// the account erased.
+func anonymize(user *types.User, placeholder, email, reason string, now time.Time) {
+	user.Username, user.Email, user.Password = placeholder, email, ""
+	user.Name, user.Address, user.PhoneNumber, user.AvatarURL = "", "", "", ""
+	user.PhoneVerifiedAt, user.MFAEnabled = nil, false
+	user.Status, user.StatusReason, user.StatusChangedAt = types.UserStatusErased, reason, &now
+	user.PurgeAfter, user.DeletedAt = nil, gorm.DeletedAt{Time: now, Valid: true}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	cognitoTypes "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx/fxtest"
+)
+
+// fakeIdP fails AdminDeleteUser with errs in turn, then succeeds. It
+// notes whether it was called while a unit of work was open.
+type fakeIdP struct {
+	errs       []error
+	deleted    []string
+	inTx       *bool
+	calledInTx bool
+}
+
+func (p *fakeIdP) AdminDeleteUser(_ context.Context, username string) error {
+	p.deleted = append(p.deleted, username)
+	p.calledInTx = p.calledInTx || *p.inTx
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+// trackingUoW reports whether a unit of work is open.
+type trackingUoW struct {
+	repository.UnitOfWork
+	active *bool
+}
+
+func (u trackingUoW) Do(ctx context.Context, fn func(r repository.Repositories) error) error {
+	*u.active = true
+	defer func() { *u.active = false }()
+	return u.UnitOfWork.Do(ctx, fn)
+}
+
+type privacyFixture struct {
+	*lifecycleFixture
+	privacy *PrivacyService
+	idp     *fakeIdP
+}
+
+func newPrivacyFixture(t *testing.T) *privacyFixture {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	inTx := new(bool)
+	f := &privacyFixture{lifecycleFixture: newLifecycleFixture(t), idp: &fakeIdP{inTx: inTx}}
+	f.privacy = NewPrivacyService(PrivacyParams{
+		Lifecycle: fxtest.NewLifecycle(t),
+		Repos:     f.store.Repositories(),
+		UoW:       trackingUoW{f.store, inTx},
+		Logger:    logger,
+		Auditor:   f.audit,
+		Audit:     f.audit,
+		Config:    PrivacyConfig{ExportDir: t.TempDir(), ExportTTL: time.Hour},
+	})
+	f.privacy.idp = f.idp
+	f.privacy.idpBackoff = 0
+	return f
+}
+
+func (f *privacyFixture) actions(t *testing.T) []string {
+	t.Helper()
+	entries, err := f.audit.ForUser(context.Background(), f.user.ID)
+	require.NoError(t, err)
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action+":"+entry.Outcome)
+	}
+	return actions
+}
+
+func TestDataExportIsBuiltOnce(t *testing.T) {
+	f := newPrivacyFixture(t)
+	ctx := context.Background()
+
+	export, err := f.privacy.RequestExport(ctx, f.actor(), f.user.ID)
+	require.NoError(t, err)
+	again, err := f.privacy.RequestExport(ctx, f.actor(), f.user.ID)
+	require.NoError(t, err)
+	require.Equal(t, export.ID, again.ID, "a pending export is reused")
+
+	f.privacy.processPending(ctx)
+	f.privacy.processPending(ctx)
+
+	path, err := f.privacy.OpenExport(ctx, f.actor(), export.ID, &f.user.ID)
+	require.NoError(t, err)
+	archive, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer archive.Close()
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	require.Contains(t, names, "profile.json")
+	require.Contains(t, names, "manifest.json")
+
+	require.Equal(t, []string{
+		AuditActionDataExportRequested + ":" + AuditOutcomeSuccess,
+		AuditActionDataExportCompleted + ":" + AuditOutcomeSuccess,
+		AuditActionDataExportDownloaded + ":" + AuditOutcomeSuccess,
+	}, f.actions(t))
+}
+
+func TestEraseAnonymizesUserAndRemovesExports(t *testing.T) {
+	f := newPrivacyFixture(t)
+	f.idp.errs = []error{errors.New("throttled")}
+	ctx := context.Background()
+	export, err := f.privacy.RequestExport(ctx, f.actor(), f.user.ID)
+	require.NoError(t, err)
+	f.privacy.processPending(ctx)
+	path, err := f.privacy.OpenExport(ctx, f.actor(), export.ID, &f.user.ID)
+	require.NoError(t, err)
+
+	user, err := f.privacy.Erase(ctx, f.actor(), f.user.ID, "GDPR request")
+	require.NoError(t, err)
+	require.Equal(t, types.UserStatusErased, user.Status)
+	require.Equal(t, "erased-"+f.user.ID.String()+"@"+erasedEmailDomain, user.Email)
+	require.Empty(t, user.PhoneNumber)
+	require.Equal(t, []string{f.user.Email, f.user.Email}, f.idp.deleted, "a transient failure is retried")
+	require.False(t, f.idp.calledInTx, "no transaction is held across calls to the identity provider")
+
+	exports, err := f.privacy.ListExports(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Empty(t, exports)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+	require.Contains(t, f.actions(t), AuditActionUserErased+":"+AuditOutcomeSuccess)
+}
+
+func TestEraseTreatsMissingIdentityAsDeleted(t *testing.T) {
+	f := newPrivacyFixture(t)
+	f.idp.errs = []error{&cognitoTypes.UserNotFoundException{}}
+
+	_, err := f.privacy.Erase(context.Background(), f.actor(), f.user.ID, "GDPR request")
+	require.NoError(t, err)
+	require.Len(t, f.idp.deleted, 1)
+}
+
+func TestEraseLeavesUserWhenIdentityProviderFails(t *testing.T) {
+	f := newPrivacyFixture(t)
+	for i := 0; i < identityDeleteAttempts; i++ {
+		f.idp.errs = append(f.idp.errs, errors.New("unavailable"))
+	}
+	ctx := context.Background()
+
+	_, err := f.privacy.Erase(ctx, f.actor(), f.user.ID, "GDPR request")
+	require.Equal(t, apperror.CodeInternal, apperror.CodeOf(err))
+	require.Len(t, f.idp.deleted, identityDeleteAttempts)
+
+	user, err := f.store.Repositories().Users.FindAnyByID(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Equal(t, types.UserStatusActive, user.Status)
+	require.Equal(t, f.user.Email, user.Email)
+	require.Equal(t, []string{AuditActionUserErased + ":" + AuditOutcomeFailure}, f.actions(t))
+
+	_, err = f.privacy.Erase(ctx, f.actor(), f.user.ID, "GDPR request")
+	require.NoError(t, err, "erasure can be repeated once the provider recovers")
+}
+
+func TestPurgeRemovesDataExports(t *testing.T) {
+	f := newPrivacyFixture(t)
+	ctx := context.Background()
+	export, err := f.privacy.RequestExport(ctx, f.actor(), f.user.ID)
+	require.NoError(t, err)
+	f.privacy.processPending(ctx)
+	path, err := f.privacy.OpenExport(ctx, f.actor(), export.ID, &f.user.ID)
+	require.NoError(t, err)
+
+	_, err = f.lifecycle.ChangeStatus(ctx, Actor{ID: &f.user.ID}, f.user.ID, types.UserStatusPendingDeletion, "leaving")
+	require.NoError(t, err)
+	require.NoError(t, f.lifecycle.Sweep(ctx, time.Now().Add(3*time.Hour)))
+
+	exports, err := f.privacy.ListExports(ctx, f.user.ID)
+	require.NoError(t, err)
+	require.Empty(t, exports)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "the archive holds the purged user's personal data")
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/service/cognito"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/mailer"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	emailChangeTTL  = 24 * time.Hour
+	MaxAvatarSize   = 2 << 20
+	AvatarURLPrefix = "/avatars"
+)
+
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+type ProfileParams struct {
+	fx.In
+	Config  *config.Config
+	Repos   repository.Repositories
+	UoW     repository.UnitOfWork
+	Logger  *logrus.Logger
+	Users   *UserService
+	Mailer  mailer.Mailer
+	Cognito *cognito.CognitoService `optional:"true"`
+}
+
+type ProfileService struct {
+	repos           repository.Repositories
+	uow             repository.UnitOfWork
+	logger          *logrus.Logger
+	users           *UserService
+	mailer          mailer.Mailer
+	cognito         *cognito.CognitoService
+	avatarDir       string
+	emailConfirmURL string
+}
+
+func NewProfileService(p ProfileParams) *ProfileService {
+	return &ProfileService{
+		repos:           p.Repos,
+		uow:             p.UoW,
+		logger:          p.Logger,
+		users:           p.Users,
+		mailer:          p.Mailer,
+		cognito:         p.Cognito,
+		avatarDir:       p.Config.Profile.AvatarDir,
+		emailConfirmURL: p.Config.Profile.EmailConfirmURL,
+	}
+}
+
+func (s *ProfileService) AvatarDir() string {
+	return s.avatarDir
+}
+
+func (s *ProfileService) GetProfile(ctx context.Context, userID uuid.UUID) (*types.User, error) {
+	return s.users.GetUserWithRole(ctx, userID)
+}
+
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID uuid.UUID, req dto.UpdateProfileDto) (*types.User, error) {
+	user, err := s.users.GetUserWithRole(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	attributes := map[string]string{}
+	if req.Username != nil && *req.Username != user.Username {
+		taken, err := s.repos.Users.UsernameTaken(ctx, *req.Username, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, apperror.ErrUsernameTaken
+		}
+		columns = append(columns, "username")
+		attributes["preferred_username"] = *req.Username
+		user.Username = *req.Username
+	}
+	if req.Name != nil {
+		columns = append(columns, "name")
+		attributes["name"] = *req.Name
+		user.Name = *req.Name
+	}
+	if req.Address != nil {
+		columns = append(columns, "address")
+		attributes["address"] = *req.Address
+		user.Address = *req.Address
+	}
+	if req.PhoneNumber != nil && *req.PhoneNumber != user.PhoneNumber {
+		// A new number has to be verified again before it can be used
+		// for MFA or recovery.
+		columns = append(columns, "phone_number", "phone_verified_at", "mfa_enabled")
+		attributes["phone_number"] = *req.PhoneNumber
+		attributes["phone_number_verified"] = "false"
+		user.PhoneNumber = *req.PhoneNumber
+		user.PhoneVerifiedAt = nil
+		user.MFAEnabled = false
+	}
+	if len(columns) == 0 {
+		return user, nil
+	}
+
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Users.Update(ctx, user, columns...); err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to update profile")
+			return err
+		}
+		return s.syncCognito(ctx, user.Email, attributes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RequestEmailChange mails a confirmation token to the new address. The
+// stored email only changes once ConfirmEmailChange is called.
+func (s *ProfileService) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail, password string) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return apperror.ErrInvalidCredentials
+	}
+
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+	if strings.EqualFold(newEmail, user.Email) {
+		return apperror.New(apperror.CodeBadRequest, "new email matches the current email")
+	}
+	if _, err := s.users.GetUserByEmail(ctx, newEmail); err == nil {
+		return apperror.ErrEmailTaken
+	} else if !errors.Is(err, apperror.ErrUserNotFound) {
+		return err
+	}
+
+	token, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	record := types.VerificationToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Purpose:   types.TokenPurposeEmailChange,
+		TokenHash: hash,
+		NewValue:  newEmail,
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+	}
+
+	return s.uow.Do(ctx, func(r repository.Repositories) error {
+		// Only the most recent request stays valid.
+		if err := r.Tokens.ConsumeAllForUser(ctx, user.ID, types.TokenPurposeEmailChange, time.Now()); err != nil {
+			return err
+		}
+		if err := r.Tokens.Create(ctx, &record); err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to store email change token")
+			return err
+		}
+		return s.mailer.Send(mailer.Message{
+			To:      newEmail,
+			Subject: "Confirm your new email address",
+			Body: fmt.Sprintf(
+				"Confirm your new email address before %s:\n\n%s?token=%s",
+				record.ExpiresAt.Format("2006-01-02 15:04 MST"), s.emailConfirmURL, token,
+			),
+		})
+	})
+}
+
+func (s *ProfileService) ConfirmEmailChange(ctx context.Context, token string) (*types.User, error) {
+	var user *types.User
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		record, err := r.Tokens.FindByHash(ctx, types.TokenPurposeEmailChange, utils.HashToken(token))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return apperror.ErrInvalidToken
+			}
+			return err
+		}
+		if record.ConsumedAt != nil || time.Now().After(record.ExpiresAt) {
+			return apperror.ErrInvalidToken
+		}
+
+		taken, err := r.Users.EmailTaken(ctx, record.NewValue, record.UserID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return apperror.ErrEmailTaken
+		}
+
+		current, err := r.Users.FindByID(ctx, record.UserID)
+		if err != nil {
+			return err
+		}
+		if err := r.Tokens.Consume(ctx, record.ID, time.Now()); err != nil {
+			return err
+		}
+		previous := current.Email
+		current.Email = record.NewValue
+		if err := r.Users.Update(ctx, current, "email"); err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to change email")
+			return err
+		}
+		if err := s.syncCognito(ctx, previous, map[string]string{
+			"email":          record.NewValue,
+			"email_verified": "true",
+		}); err != nil {
+			return err
+		}
+		user = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the user's password and revokes every refresh
+// token, so a leaked password stops granting sessions once it is changed.
+func (s *ProfileService) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return apperror.ErrInvalidCredentials
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to hash password")
+		return err
+	}
+
+	return s.uow.Do(ctx, func(r repository.Repositories) error {
+		user.Password = string(hashedPassword)
+		if err := r.Users.Update(ctx, user, "password"); err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to change password")
+			return err
+		}
+		if err := r.Sessions.RevokeAllForUser(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+		if s.cognito != nil {
+			if err := s.cognito.SetUserPassword(ctx, user.Email, newPassword); err != nil {
+				return apperror.Wrap(apperror.CodeInternal, "failed to update identity provider", err)
+			}
+		}
+		return nil
+	})
+}
+
+// UploadAvatar stores an image for the user and returns the updated user.
+// The content type is sniffed from the data rather than trusted from the
+// client.
+func (s *ProfileService) UploadAvatar(ctx context.Context, userID uuid.UUID, data []byte) (*types.User, error) {
+	if len(data) > MaxAvatarSize {
+		return nil, apperror.New(apperror.CodeBadRequest, "avatar exceeds the maximum size of 2 MB")
+	}
+	ext, ok := avatarExtensions[http.DetectContentType(data)]
+	if !ok {
+		return nil, apperror.New(apperror.CodeBadRequest, "avatar must be a PNG, JPEG, WebP or GIF image")
+	}
+
+	user, err := s.users.GetUserWithRole(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.avatarDir, 0o755); err != nil {
+		return nil, err
+	}
+	filename := fmt.Sprintf("%s-%d%s", user.ID, time.Now().Unix(), ext)
+	if err := os.WriteFile(filepath.Join(s.avatarDir, filename), data, 0o644); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to write avatar")
+		return nil, err
+	}
+	previous := user.AvatarURL
+	avatarURL := AvatarURLPrefix + "/" + filename
+
+	user.AvatarURL = avatarURL
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Users.Update(ctx, user, "avatar_url"); err != nil {
+			return err
+		}
+		return s.syncCognito(ctx, user.Email, map[string]string{"picture": avatarURL})
+	})
+	if err != nil {
+		_ = os.Remove(filepath.Join(s.avatarDir, filename))
+		return nil, err
+	}
+
+	if strings.HasPrefix(previous, AvatarURLPrefix+"/") {
+		_ = os.Remove(filepath.Join(s.avatarDir, filepath.Base(previous)))
+	}
+	return user, nil
+}
+
+func (s *ProfileService) syncCognito(ctx context.Context, username string, attributes map[string]string) error {
+	if s.cognito == nil || len(attributes) == 0 {
+		return nil
+	}
+	if err := s.cognito.UpdateUserAttributes(ctx, username, attributes); err != nil {
+		return apperror.Wrap(apperror.CodeInternal, "failed to update identity provider", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setPassword gives the fixture's user a password to log in with.
+func (f *lifecycleFixture) setPassword(t *testing.T, password string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	f.user.Password = string(hash)
+	require.NoError(t, f.store.Repositories().Users.Update(context.Background(), &f.user, "password"))
+}
+
+func TestChangePasswordRevokesSessions(t *testing.T) {
+	f := newLifecycleFixture(t)
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repos := f.store.Repositories()
+	profile := NewProfileService(ProfileParams{
+		Config: &config.Config{},
+		Repos:  repos,
+		UoW:    f.store,
+		Logger: logger,
+		Users:  NewUserService(repos, f.store, logger, nil),
+	})
+	f.setPassword(t, "Secret123!")
+
+	login, err := f.auth.Login(ctx, Actor{}, f.user.Email, "Secret123!")
+	require.NoError(t, err)
+
+	require.NoError(t, profile.ChangePassword(ctx, f.user.ID, "Secret123!", "Changed123!"))
+	_, err = f.auth.Refresh(ctx, Actor{}, login.RefreshToken)
+	require.ErrorIs(t, err, apperror.ErrInvalidToken)
+
+	_, err = f.auth.Login(ctx, Actor{}, f.user.Email, "Changed123!")
+	require.NoError(t, err)
+}
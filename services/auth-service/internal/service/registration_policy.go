@@ -0,0 +1,44 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/content-management-system/auth-service/internal/config"
+)
+
+// RegistrationPolicy decides which role a self-registered user receives
+// when no invite code is supplied.
+type RegistrationPolicy struct {
+	DefaultRole string
+	DomainRoles map[string]string
+}
+
+// LoadRegistrationPolicy builds the policy from the registration settings.
+func LoadRegistrationPolicy(cfg *config.Config) RegistrationPolicy {
+	policy := RegistrationPolicy{
+		DefaultRole: strings.TrimSpace(cfg.Registration.DefaultRole),
+		DomainRoles: map[string]string{},
+	}
+	for _, pair := range strings.Split(cfg.Registration.DomainRoles, ",") {
+		domain, role, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		role = strings.TrimSpace(role)
+		if domain != "" && role != "" {
+			policy.DomainRoles[domain] = role
+		}
+	}
+	return policy
+}
+
+func (p RegistrationPolicy) RoleFor(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at >= 0 {
+		if role, ok := p.DomainRoles[strings.ToLower(email[at+1:])]; ok {
+			return role
+		}
+	}
+	return p.DefaultRole
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/content-management-system/auth-service/internal/config"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRegistrationPolicy(t *testing.T) {
+	policy := LoadRegistrationPolicy(&config.Config{
+		Registration: config.RegistrationConfig{
+			DefaultRole: types.RoleCustomer,
+			DomainRoles: "Acme.com=Editor, broken ,partner.io = Staff",
+		},
+	})
+
+	require.Equal(t, types.RoleCustomer, policy.DefaultRole)
+	require.Equal(t, "Editor", policy.RoleFor("jane@ACME.com"))
+	require.Equal(t, "Staff", policy.RoleFor("bob@partner.io"))
+	require.Equal(t, types.RoleCustomer, policy.RoleFor("eve@acme.com.evil.io"))
+	require.Equal(t, types.RoleCustomer, policy.RoleFor("mallory@example.com"))
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type RoleService struct {
+	repos  repository.Repositories
+	uow    repository.UnitOfWork
+	logger *logrus.Logger
+	policy RegistrationPolicy
+}
+
+func NewRoleService(repos repository.Repositories, uow repository.UnitOfWork, logger *logrus.Logger, policy RegistrationPolicy) *RoleService {
+	return &RoleService{
+		repos:  repos,
+		uow:    uow,
+		logger: logger,
+		policy: policy,
+	}
+}
+
+func (s *RoleService) GetAllRoles(ctx context.Context) ([]types.Role, error) {
+	roles, err := s.repos.Roles.List(ctx)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to get all roles")
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (s *RoleService) GetRoleByID(ctx context.Context, id uuid.UUID) (*types.Role, error) {
+	role, err := s.repos.Roles.FindByID(ctx, id)
+	if err != nil {
+		return nil, s.roleError(ctx, err)
+	}
+	return role, nil
+}
+
+// GetRolesByIDs loads many roles and their permissions in one query. IDs
+// without a role are left out of the result.
+func (s *RoleService) GetRolesByIDs(ctx context.Context, ids []uuid.UUID) ([]types.Role, error) {
+	roles, err := s.repos.Roles.FindByIDs(ctx, ids)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to get roles by ID")
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (s *RoleService) GetRoleByName(ctx context.Context, name string) (*types.Role, error) {
+	role, err := s.repos.Roles.FindByName(ctx, name)
+	if err != nil {
+		return nil, s.roleError(ctx, err)
+	}
+	return role, nil
+}
+
+func (s *RoleService) roleError(ctx context.Context, err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.ErrRoleNotFound
+	}
+	s.logger.WithContext(ctx).WithError(err).Error("Failed to get role")
+	return err
+}
+
+// AssignRole moves a user to another role and returns the role they held
+// before.
+func (s *RoleService) AssignRole(ctx context.Context, userID, roleID uuid.UUID) (*types.User, uuid.UUID, error) {
+	var user *types.User
+	var previous uuid.UUID
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		role, err := r.Roles.FindByID(ctx, roleID)
+		if err != nil {
+			return s.roleError(ctx, err)
+		}
+		user, err = r.Users.FindByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return apperror.ErrUserNotFound
+			}
+			return err
+		}
+		previous = user.RoleID
+		user.RoleID = role.ID
+		if err := r.Users.Update(ctx, user, "role_id"); err != nil {
+			return err
+		}
+		user.Role = *role
+		return nil
+	})
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	return user, previous, nil
+}
+
+// ResolveRegistrationRole picks the role for a self-registration. A valid
+// invite code wins over the domain rules, which win over the default role.
+// The invite code is redeemed through r, so callers must run this inside a
+// unit of work that rolls back if the user cannot be created.
+func (s *RoleService) ResolveRegistrationRole(ctx context.Context, r repository.Repositories, email, inviteCode string) (*types.Role, error) {
+	if inviteCode != "" {
+		roleID, err := s.redeemInviteCode(ctx, r, email, inviteCode)
+		if err != nil {
+			return nil, err
+		}
+		role, err := r.Roles.FindByID(ctx, roleID)
+		if err != nil {
+			return nil, s.roleError(ctx, err)
+		}
+		return role, nil
+	}
+
+	name := s.policy.RoleFor(email)
+	role, err := r.Roles.FindByName(ctx, name)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.logger.WithContext(ctx).WithField("role", name).Error("Registration role is not configured in the database")
+		return nil, apperror.Wrap(apperror.CodeInternal, "registration role is not configured", apperror.ErrRoleNotFound)
+	}
+	if err != nil {
+		return nil, s.roleError(ctx, err)
+	}
+	return role, nil
+}
+
+func (s *RoleService) redeemInviteCode(ctx context.Context, r repository.Repositories, email, code string) (uuid.UUID, error) {
+	invite, err := r.InviteCodes.FindByHash(ctx, utils.HashToken(code))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return uuid.Nil, apperror.ErrInvalidInviteCode
+		}
+		return uuid.Nil, err
+	}
+	if invite.Email != "" && !strings.EqualFold(invite.Email, email) {
+		return uuid.Nil, apperror.ErrInvalidInviteCode
+	}
+
+	redeemed, err := r.InviteCodes.Redeem(ctx, invite.ID, time.Now())
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !redeemed {
+		return uuid.Nil, apperror.ErrInvalidInviteCode
+	}
+	return invite.RoleID, nil
+}
+
+// CreateInviteCode issues a code that grants roleID on registration. The
+// plain code is only returned here; the database stores its hash.
+func (s *RoleService) CreateInviteCode(ctx context.Context, createdBy, roleID uuid.UUID, email string, maxUses int, ttl time.Duration) (*types.InviteCodeResponse, error) {
+	if _, err := s.GetRoleByID(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	code, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := types.InviteCode{
+		ID:          uuid.New(),
+		CodeHash:    hash,
+		RoleID:      roleID,
+		Email:       strings.ToLower(email),
+		MaxUses:     maxUses,
+		CreatedByID: createdBy,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repos.InviteCodes.Create(ctx, &invite); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create invite code")
+		return nil, err
+	}
+	return &types.InviteCodeResponse{InviteCode: invite, Code: code}, nil
+}
+
+func (s *RoleService) ListInviteCodes(ctx context.Context) ([]types.InviteCode, error) {
+	invites, err := s.repos.InviteCodes.List(ctx)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list invite codes")
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (s *RoleService) RevokeInviteCode(ctx context.Context, id uuid.UUID) error {
+	revoked, err := s.repos.InviteCodes.Revoke(ctx, id, time.Now())
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to revoke invite code")
+		return err
+	}
+	if !revoked {
+		return apperror.New(apperror.CodeNotFound, "invite code not found")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type SessionService struct {
+	repos  repository.Repositories
+	logger *logrus.Logger
+	jwt    *utils.JWT
+}
+
+func NewSessionService(repos repository.Repositories, logger *logrus.Logger, jwt *utils.JWT) *SessionService {
+	return &SessionService{
+		repos:  repos,
+		logger: logger,
+		jwt:    jwt,
+	}
+}
+
+// Create starts a session and returns the refresh token bound to it.
+func (s *SessionService) Create(ctx context.Context, userID uuid.UUID, userAgent, ip string) (string, error) {
+	session := types.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		UserAgent: truncate(userAgent, 512),
+		IPAddress: truncate(ip, 64),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	}
+	if err := s.repos.Sessions.Create(ctx, &session); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create session")
+		return "", err
+	}
+	return s.jwt.GenerateRefreshToken(userID, session.ID)
+}
+
+// Validate checks a refresh token against its session and records its use.
+func (s *SessionService) Validate(ctx context.Context, refreshToken string) (*types.Session, error) {
+	claims, err := s.jwt.ValidateToken(refreshToken)
+	if err != nil || claims.TokenUse != utils.TokenUseRefresh {
+		return nil, apperror.ErrInvalidToken
+	}
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, apperror.ErrInvalidToken
+	}
+
+	session, err := s.repos.Sessions.Find(ctx, sessionID, claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperror.ErrInvalidToken
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, apperror.ErrInvalidToken
+	}
+	if err := s.repos.Sessions.Touch(ctx, session.ID, now); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Warn("Failed to record session use")
+	}
+	session.LastUsedAt = &now
+	return session, nil
+}
+
+func (s *SessionService) Revoke(ctx context.Context, refreshToken string) (*types.Session, error) {
+	session, err := s.Validate(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := s.repos.Sessions.Revoke(ctx, session.ID, now); err != nil {
+		return nil, err
+	}
+	session.RevokedAt = &now
+	return session, nil
+}
+
+func (s *SessionService) ListForUser(ctx context.Context, userID uuid.UUID) ([]types.Session, error) {
+	return s.repos.Sessions.ListForUser(ctx, userID)
+}
+
+// truncate limits s to at most n bytes without splitting a rune, and drops
+// invalid UTF-8, which Postgres refuses to store in text columns.
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
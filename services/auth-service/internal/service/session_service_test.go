@@ -0,0 +1,26 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	require.Equal(t, "abc", truncate("abc", 5))
+	require.Equal(t, "ab", truncate("abc", 2))
+
+	// "é" is two bytes; cutting at 2 would split it.
+	require.Equal(t, "a", truncate("aé", 2))
+	require.Equal(t, "aé", truncate("aé", 3))
+
+	agent := strings.Repeat("日", 200)
+	got := truncate(agent, 512)
+	require.True(t, utf8.ValidString(got))
+	require.LessOrEqual(t, len(got), 512)
+	require.Equal(t, 510, len(got))
+
+	require.Equal(t, "ab", truncate("a\xffb", 10), "invalid bytes are dropped")
+}
@@ -1,74 +1,151 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"go.uber.org/fx"
 	"time"
 
 	"github.com/content-management-system/auth-service/internal/model/types"
-	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/health"
+	"github.com/content-management-system/auth-service/pkg/logger"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
-var Module = fx.Module("service", fx.Provide(NewUserService))
+var Module = fx.Module("service",
+	fx.Provide(
+		LoadRegistrationPolicy,
+		NewRoleService,
+		NewUserService,
+		NewInvitationService,
+		NewProfileService,
+		NewOTPService,
+		NewPhoneService,
+		NewSessionService,
+		NewAuthService,
+		LoadLifecycleConfig,
+		NewLifecycleService,
+		NewAuditService,
+		NewAuditor,
+		LoadPrivacyConfig,
+		NewPrivacyService,
+		NewMigrator,
+		health.AsCheck(NewSchemaCheck),
+	),
+	fx.Invoke(RequireSchema),
+	fx.Decorate(logger.Named("service")),
+)
 
 type UserService struct {
-	db     *db.DB
+	repos  repository.Repositories
+	uow    repository.UnitOfWork
 	logger *logrus.Logger
+	roles  *RoleService
 }
 
-func NewUserService(db *db.DB, logger *logrus.Logger) *UserService {
+func NewUserService(repos repository.Repositories, uow repository.UnitOfWork, logger *logrus.Logger, roles *RoleService) *UserService {
 	return &UserService{
-		db:     db,
+		repos:  repos,
+		uow:    uow,
 		logger: logger,
+		roles:  roles,
 	}
 }
 
-func (s *UserService) GetAllUsers() ([]types.User, error) {
-	var users []types.User
-	if err := s.db.Conn.Find(&users).Error; err != nil {
-		s.logger.WithError(err).Error("Failed to get all users")
+func (s *UserService) GetAllUsers(ctx context.Context) ([]types.User, error) {
+	users, err := s.repos.Users.List(ctx)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to get all users")
 		return nil, err
 	}
 	return users, nil
 }
 
-func (s *UserService) GetUserByID(id uuid.UUID) (*types.User, error) {
-	var user types.User
-	if err := s.db.Conn.Where("id = ?", id).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
-		}
-		s.logger.WithError(err).Error("Failed to get user by ID")
+// UserPage is one page of a user listing.
+type UserPage struct {
+	Users []types.User
+	// HasMore reports whether further users follow the last one.
+	HasMore bool
+}
+
+// ListUsers returns up to first users matching filter, oldest first,
+// continuing after the cursor when one is given.
+func (s *UserService) ListUsers(ctx context.Context, filter repository.UserFilter, after *repository.UserCursor, first int) (*UserPage, error) {
+	// One extra row tells whether another page exists without a count.
+	users, err := s.repos.Users.Page(ctx, filter, after, first+1)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list users")
 		return nil, err
 	}
-	return &user, nil
+	page := &UserPage{Users: users}
+	if len(users) > first {
+		page.Users, page.HasMore = users[:first], true
+	}
+	return page, nil
 }
 
-func (s *UserService) GetUserByEmail(email string) (*types.User, error) {
-	var user types.User
-	if err := s.db.Conn.Where("email = ?", email).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
-		}
-		s.logger.WithError(err).Error("Failed to get user by email")
+func (s *UserService) CountUsers(ctx context.Context, filter repository.UserFilter) (int64, error) {
+	count, err := s.repos.Users.Count(ctx, filter)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to count users")
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *UserService) GetUserByID(ctx context.Context, id uuid.UUID) (*types.User, error) {
+	user, err := s.repos.Users.FindByID(ctx, id)
+	if err != nil {
+		return nil, s.userError(ctx, err, "Failed to get user by ID")
+	}
+	return user, nil
+}
+
+// GetUsersByIDs loads many users in one query. IDs without a user are left
+// out of the result.
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]types.User, error) {
+	users, err := s.repos.Users.FindByIDs(ctx, ids)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to get users by ID")
 		return nil, err
 	}
-	return &user, nil
+	return users, nil
 }
 
-func (s *UserService) CreateUser(username, email, password string) (*types.User, error) {
+func (s *UserService) GetUserWithRole(ctx context.Context, id uuid.UUID) (*types.User, error) {
+	user, err := s.repos.Users.FindByIDWithRole(ctx, id)
+	if err != nil {
+		return nil, s.userError(ctx, err, "Failed to get user with role")
+	}
+	return user, nil
+}
 
-	var existingUser types.User
-	if err := s.db.Conn.Where("email = ?", email).First(&existingUser).Error; err == nil {
-		return nil, errors.New("user with this email already exists")
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	user, err := s.repos.Users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, s.userError(ctx, err, "Failed to get user by email")
 	}
+	return user, nil
+}
+
+func (s *UserService) userError(ctx context.Context, err error, message string) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.ErrUserNotFound
+	}
+	s.logger.WithContext(ctx).WithError(err).Error(message)
+	return err
+}
+
+// CreateUser relies on the unique indexes rather than a prior lookup, so
+// concurrent calls for the same email or username cannot both succeed.
+func (s *UserService) CreateUser(ctx context.Context, username, email, password string) (*types.User, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to hash password")
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to hash password")
 		return nil, err
 	}
 
@@ -77,38 +154,48 @@ func (s *UserService) CreateUser(username, email, password string) (*types.User,
 		Username: username,
 		Email:    email,
 		Password: string(hashedPassword),
+		Status:   types.UserStatusActive,
 	}
 
-	if err := s.db.Conn.Create(&user).Error; err != nil {
-		s.logger.WithError(err).Error("Failed to create user")
+	if err := s.repos.Users.Create(ctx, &user); err != nil {
+		s.logCreateError(ctx, err)
 		return nil, err
 	}
 
 	return &user, nil
 }
 
-func (s *UserService) ValidatePassword(email, password string) (*types.User, error) {
-	user, err := s.GetUserByEmail(email)
+// logCreateError logs failed inserts except for the expected conflicts.
+func (s *UserService) logCreateError(ctx context.Context, err error) {
+	if apperror.CodeOf(err) == apperror.CodeInternal {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create user")
+	}
+}
+
+func (s *UserService) ValidatePassword(ctx context.Context, email, password string) (*types.User, error) {
+	user, err := s.GetUserByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid password")
+		return nil, apperror.ErrInvalidCredentials
+	}
+	if user.Status != types.UserStatusActive {
+		return nil, apperror.ErrAccountInactive
 	}
 
 	return user, nil
 }
 
-func (s *UserService) Register(username, email, password string, roleID uint64) (*types.User, error) {
-	_, err := s.GetUserByEmail(email)
-	if err == nil {
-		return nil, errors.New("user with this email already exists")
-	}
-
+// Register creates a self-service account. The role is never taken from
+// the caller; it is resolved from the invite code or the registration
+// policy. A duplicate email or username surfaces as a conflict from the
+// insert, which also rolls back any invite code redemption.
+func (s *UserService) Register(ctx context.Context, username, email, password, inviteCode string) (*types.User, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to hash password")
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to hash password")
 		return nil, err
 	}
 
@@ -116,25 +203,43 @@ func (s *UserService) Register(username, email, password string, roleID uint64)
 		Username:         username,
 		Email:            email,
 		Password:         string(hashedPassword),
-		RoleID:           roleID,
 		RegistrationDate: time.Now(),
+		Status:           types.UserStatusActive,
 	}
 
-	if err := s.db.Conn.Create(user).Error; err != nil {
-		s.logger.WithError(err).Error("Failed to create user")
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		role, err := s.roles.ResolveRegistrationRole(ctx, r, email, inviteCode)
+		if err != nil {
+			return err
+		}
+		user.RoleID = role.ID
+		if err := r.Users.Create(ctx, user); err != nil {
+			s.logCreateError(ctx, err)
+			return err
+		}
+		user.Role = *role
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *UserService) Login(email, password string) (*types.User, error) {
-	user, err := s.GetUserByEmail(email)
+func (s *UserService) Login(ctx context.Context, email, password string) (*types.User, error) {
+	user, err := s.GetUserByEmail(ctx, email)
 	if err != nil {
+		if errors.Is(err, apperror.ErrUserNotFound) {
+			return nil, apperror.ErrInvalidCredentials
+		}
 		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, apperror.ErrInvalidCredentials
+	}
+	if user.Status != types.UserStatusActive {
+		return nil, apperror.ErrAccountInactive
 	}
 
 	return user, nil
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/repository"
+	"github.com/content-management-system/auth-service/internal/repository/memory"
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type userFixture struct {
+	store    *memory.Store
+	users    *UserService
+	roles    *RoleService
+	customer types.Role
+	admin    types.Role
+}
+
+func newUserFixture(t *testing.T) *userFixture {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := memory.NewStore()
+	repos := store.Repositories()
+	roles := NewRoleService(repos, store, logger, RegistrationPolicy{DefaultRole: types.RoleCustomer})
+	return &userFixture{
+		store:    store,
+		users:    NewUserService(repos, store, logger, roles),
+		roles:    roles,
+		customer: store.AddRole(types.Role{Name: types.RoleCustomer}),
+		admin:    store.AddRole(types.Role{Name: types.RoleAdministrator}),
+	}
+}
+
+func TestRegisterAssignsPolicyRole(t *testing.T) {
+	f := newUserFixture(t)
+
+	user, err := f.users.Register(context.Background(), "jane", "jane@example.com", "Secret123!", "")
+	require.NoError(t, err)
+	require.Equal(t, f.customer.ID, user.RoleID)
+	require.Equal(t, types.UserStatusActive, user.Status)
+
+	_, err = f.users.Login(context.Background(), "jane@example.com", "Secret123!")
+	require.NoError(t, err)
+
+	_, err = f.users.Register(context.Background(), "jane2", "jane@example.com", "Secret123!", "")
+	require.ErrorIs(t, err, apperror.ErrEmailTaken)
+}
+
+func TestRegisterRedeemsInviteCode(t *testing.T) {
+	f := newUserFixture(t)
+	invite, err := f.roles.CreateInviteCode(context.Background(), f.admin.ID, f.admin.ID, "", 1, time.Hour)
+	require.NoError(t, err)
+
+	user, err := f.users.Register(context.Background(), "ops", "ops@example.com", "Secret123!", invite.Code)
+	require.NoError(t, err)
+	require.Equal(t, f.admin.ID, user.RoleID)
+
+	_, err = f.users.Register(context.Background(), "ops2", "ops2@example.com", "Secret123!", invite.Code)
+	require.ErrorIs(t, err, apperror.ErrInvalidInviteCode)
+}
+
+func TestRegisterRollsBackInviteWhenUserCannotBeCreated(t *testing.T) {
+	f := newUserFixture(t)
+	_, err := f.users.Register(context.Background(), "taken", "first@example.com", "Secret123!", "")
+	require.NoError(t, err)
+	invite, err := f.roles.CreateInviteCode(context.Background(), f.admin.ID, f.admin.ID, "", 1, time.Hour)
+	require.NoError(t, err)
+
+	_, err = f.users.Register(context.Background(), "taken", "second@example.com", "Secret123!", invite.Code)
+	require.ErrorIs(t, err, apperror.ErrUsernameTaken)
+
+	invites, err := f.roles.ListInviteCodes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, invites, 1)
+	require.Zero(t, invites[0].Uses)
+
+	_, err = f.users.Register(context.Background(), "fresh", "second@example.com", "Secret123!", invite.Code)
+	require.NoError(t, err)
+}
+
+func TestAssignRoleReturnsPreviousRole(t *testing.T) {
+	f := newUserFixture(t)
+	user, err := f.users.Register(context.Background(), "jane", "jane@example.com", "Secret123!", "")
+	require.NoError(t, err)
+
+	updated, previous, err := f.roles.AssignRole(context.Background(), user.ID, f.admin.ID)
+	require.NoError(t, err)
+	require.Equal(t, f.customer.ID, previous)
+	require.Equal(t, f.admin.Name, updated.Role.Name)
+
+	stored, err := f.users.GetUserWithRole(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, f.admin.ID, stored.RoleID)
+	require.Equal(t, f.admin.Name, stored.Role.Name)
+}
+
+func TestListUsersPagesByCursor(t *testing.T) {
+	f := newUserFixture(t)
+	for i := 0; i < 5; i++ {
+		_, err := f.users.Register(context.Background(), fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i), "Secret123!", "")
+		require.NoError(t, err)
+	}
+	_, err := f.users.Register(context.Background(), "other", "other@example.org", "Secret123!", "")
+	require.NoError(t, err)
+
+	filter := repository.UserFilter{Search: "EXAMPLE.COM"}
+	var seen []string
+	var after *repository.UserCursor
+	for {
+		page, err := f.users.ListUsers(context.Background(), filter, after, 2)
+		require.NoError(t, err)
+		for _, u := range page.Users {
+			seen = append(seen, u.Username)
+		}
+		if !page.HasMore {
+			break
+		}
+		last := page.Users[len(page.Users)-1]
+		after = &repository.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	require.ElementsMatch(t, []string{"user0", "user1", "user2", "user3", "user4"}, seen)
+
+	count, err := f.users.CountUsers(context.Background(), filter)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, count)
+}
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS audit_entries;
+DROP FUNCTION IF EXISTS audit_entries_immutable();
+DROP TABLE IF EXISTS data_exports;
+DROP TABLE IF EXISTS user_status_changes;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS phone_otps;
+DROP TABLE IF EXISTS verification_tokens;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS invite_codes;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,184 @@
+-- Baseline schema. Every statement is idempotent so databases created by
+-- the former GORM AutoMigrate can adopt the migration history in place.
+
+CREATE TABLE IF NOT EXISTS roles (
+    id         bigserial PRIMARY KEY,
+    name       varchar(255) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_name ON roles (name);
+
+CREATE TABLE IF NOT EXISTS users (
+    id                uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    username          varchar(255) NOT NULL,
+    password          varchar(255) NOT NULL,
+    email             varchar(255) NOT NULL,
+    name              varchar(255),
+    role_id           bigint NOT NULL,
+    registration_date timestamptz,
+    address           text,
+    phone_number      varchar(255),
+    phone_verified_at timestamptz,
+    mfa_enabled       boolean NOT NULL DEFAULT false,
+    avatar_url        varchar(512),
+    status            varchar(32) NOT NULL DEFAULT 'active',
+    status_reason     text,
+    status_changed_at timestamptz,
+    purge_after       timestamptz,
+    created_at        timestamptz,
+    updated_at        timestamptz,
+    deleted_at        timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_roles_users') THEN
+        ALTER TABLE users ADD CONSTRAINT fk_roles_users FOREIGN KEY (role_id) REFERENCES roles (id);
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS invite_codes (
+    id            uuid PRIMARY KEY,
+    code_hash     char(64) NOT NULL,
+    role_id       bigint NOT NULL,
+    email         varchar(255),
+    max_uses      bigint NOT NULL DEFAULT 1,
+    uses          bigint NOT NULL DEFAULT 0,
+    expires_at    timestamptz,
+    revoked_at    timestamptz,
+    created_by_id uuid NOT NULL,
+    created_at    timestamptz,
+    updated_at    timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invite_codes_code_hash ON invite_codes (code_hash);
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id               uuid PRIMARY KEY,
+    email            varchar(255) NOT NULL,
+    role_id          bigint NOT NULL,
+    invited_by_id    uuid NOT NULL,
+    token_hash       char(64),
+    expires_at       timestamptz NOT NULL,
+    sent_count       bigint NOT NULL DEFAULT 0,
+    last_sent_at     timestamptz,
+    accepted_at      timestamptz,
+    accepted_user_id uuid,
+    revoked_at       timestamptz,
+    created_at       timestamptz,
+    updated_at       timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations (email);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id         uuid PRIMARY KEY,
+    user_id    uuid NOT NULL,
+    provider   varchar(64) NOT NULL,
+    subject    varchar(255) NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_identity_provider_subject ON user_identities (provider, subject);
+
+CREATE TABLE IF NOT EXISTS verification_tokens (
+    id          uuid PRIMARY KEY,
+    user_id     uuid NOT NULL,
+    purpose     varchar(32) NOT NULL,
+    token_hash  char(64) NOT NULL,
+    new_value   varchar(255),
+    expires_at  timestamptz NOT NULL,
+    consumed_at timestamptz,
+    created_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_verification_tokens_user_id ON verification_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_verification_tokens_token_hash ON verification_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS phone_otps (
+    id           uuid PRIMARY KEY,
+    user_id      uuid NOT NULL,
+    purpose      varchar(32) NOT NULL,
+    phone_number varchar(255) NOT NULL,
+    code_hash    char(64) NOT NULL,
+    attempts     bigint NOT NULL DEFAULT 0,
+    expires_at   timestamptz NOT NULL,
+    consumed_at  timestamptz,
+    created_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_phone_otp_user_purpose ON phone_otps (user_id, purpose);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id           uuid PRIMARY KEY,
+    user_id      uuid NOT NULL,
+    user_agent   varchar(512),
+    ip_address   varchar(64),
+    expires_at   timestamptz NOT NULL,
+    last_used_at timestamptz,
+    revoked_at   timestamptz,
+    created_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS user_status_changes (
+    id            uuid PRIMARY KEY,
+    user_id       uuid NOT NULL,
+    from_status   varchar(32) NOT NULL,
+    to_status     varchar(32) NOT NULL,
+    reason        text NOT NULL,
+    changed_by_id uuid,
+    created_at    timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_user_status_changes_user_id ON user_status_changes (user_id);
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id              uuid PRIMARY KEY,
+    user_id         uuid NOT NULL,
+    requested_by_id uuid NOT NULL,
+    status          varchar(16) NOT NULL,
+    file_path       varchar(512),
+    size_bytes      bigint,
+    error           text,
+    completed_at    timestamptz,
+    expires_at      timestamptz,
+    created_at      timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports (status);
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id         bigserial PRIMARY KEY,
+    actor_id   uuid,
+    action     varchar(64) NOT NULL,
+    target_id  uuid,
+    ip_address varchar(64),
+    user_agent varchar(512),
+    outcome    varchar(16) NOT NULL,
+    details    jsonb,
+    prev_hash  char(64) NOT NULL,
+    hash       char(64) NOT NULL,
+    created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_actor_id ON audit_entries (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_action ON audit_entries (action);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_target_id ON audit_entries (target_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_entries_hash ON audit_entries (hash);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_created_at ON audit_entries (created_at);
+
+-- audit_entries is append-only.
+CREATE OR REPLACE FUNCTION audit_entries_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_entries_no_update ON audit_entries;
+CREATE TRIGGER audit_entries_no_update
+    BEFORE UPDATE OR DELETE ON audit_entries
+    FOR EACH ROW EXECUTE FUNCTION audit_entries_immutable();
+
+DROP TRIGGER IF EXISTS audit_entries_no_truncate ON audit_entries;
+CREATE TRIGGER audit_entries_no_truncate
+    BEFORE TRUNCATE ON audit_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_entries_immutable();
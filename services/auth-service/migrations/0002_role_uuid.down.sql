@@ -0,0 +1,32 @@
+DROP INDEX IF EXISTS idx_users_role_id;
+ALTER TABLE invitations DROP CONSTRAINT IF EXISTS fk_invitations_role;
+ALTER TABLE invite_codes DROP CONSTRAINT IF EXISTS fk_invite_codes_role;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_roles_users;
+
+ALTER TABLE roles ADD COLUMN int_id bigserial;
+
+ALTER TABLE users ADD COLUMN role_int bigint;
+UPDATE users u SET role_int = r.int_id FROM roles r WHERE u.role_id = r.id;
+ALTER TABLE users DROP COLUMN role_id;
+ALTER TABLE users RENAME COLUMN role_int TO role_id;
+ALTER TABLE users ALTER COLUMN role_id SET NOT NULL;
+
+ALTER TABLE invite_codes ADD COLUMN role_int bigint;
+UPDATE invite_codes i SET role_int = r.int_id FROM roles r WHERE i.role_id = r.id;
+ALTER TABLE invite_codes DROP COLUMN role_id;
+ALTER TABLE invite_codes RENAME COLUMN role_int TO role_id;
+ALTER TABLE invite_codes ALTER COLUMN role_id SET NOT NULL;
+
+ALTER TABLE invitations ADD COLUMN role_int bigint;
+UPDATE invitations i SET role_int = r.int_id FROM roles r WHERE i.role_id = r.id;
+ALTER TABLE invitations DROP COLUMN role_id;
+ALTER TABLE invitations RENAME COLUMN role_int TO role_id;
+ALTER TABLE invitations ALTER COLUMN role_id SET NOT NULL;
+
+ALTER TABLE roles DROP CONSTRAINT roles_pkey;
+ALTER TABLE roles DROP COLUMN id;
+ALTER TABLE roles RENAME COLUMN int_id TO id;
+ALTER TABLE roles ADD PRIMARY KEY (id);
+ALTER SEQUENCE roles_int_id_seq RENAME TO roles_id_seq;
+
+ALTER TABLE users ADD CONSTRAINT fk_roles_users FOREIGN KEY (role_id) REFERENCES roles (id);
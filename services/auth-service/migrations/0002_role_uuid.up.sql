@@ -0,0 +1,34 @@
+-- Role IDs become UUIDs so they match the shared model and the seeding
+-- tool. Existing rows keep their relationships through a mapping column.
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_roles_users;
+
+ALTER TABLE roles ADD COLUMN uuid_id uuid NOT NULL DEFAULT gen_random_uuid();
+
+ALTER TABLE users ADD COLUMN role_uuid uuid;
+UPDATE users u SET role_uuid = r.uuid_id FROM roles r WHERE u.role_id = r.id;
+ALTER TABLE users DROP COLUMN role_id;
+ALTER TABLE users RENAME COLUMN role_uuid TO role_id;
+ALTER TABLE users ALTER COLUMN role_id SET NOT NULL;
+
+ALTER TABLE invite_codes ADD COLUMN role_uuid uuid;
+UPDATE invite_codes i SET role_uuid = r.uuid_id FROM roles r WHERE i.role_id = r.id;
+ALTER TABLE invite_codes DROP COLUMN role_id;
+ALTER TABLE invite_codes RENAME COLUMN role_uuid TO role_id;
+ALTER TABLE invite_codes ALTER COLUMN role_id SET NOT NULL;
+
+ALTER TABLE invitations ADD COLUMN role_uuid uuid;
+UPDATE invitations i SET role_uuid = r.uuid_id FROM roles r WHERE i.role_id = r.id;
+ALTER TABLE invitations DROP COLUMN role_id;
+ALTER TABLE invitations RENAME COLUMN role_uuid TO role_id;
+ALTER TABLE invitations ALTER COLUMN role_id SET NOT NULL;
+
+ALTER TABLE roles DROP CONSTRAINT roles_pkey;
+ALTER TABLE roles DROP COLUMN id;
+ALTER TABLE roles RENAME COLUMN uuid_id TO id;
+ALTER TABLE roles ADD PRIMARY KEY (id);
+
+ALTER TABLE users ADD CONSTRAINT fk_roles_users FOREIGN KEY (role_id) REFERENCES roles (id);
+ALTER TABLE invite_codes ADD CONSTRAINT fk_invite_codes_role FOREIGN KEY (role_id) REFERENCES roles (id);
+ALTER TABLE invitations ADD CONSTRAINT fk_invitations_role FOREIGN KEY (role_id) REFERENCES roles (id);
+CREATE INDEX idx_users_role_id ON users (role_id);
@@ -0,0 +1,14 @@
+CREATE TABLE permissions (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        varchar(255) NOT NULL,
+    description text,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+CREATE UNIQUE INDEX idx_permissions_name ON permissions (name);
+
+CREATE TABLE role_permissions (
+    role_id       uuid NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission_id uuid NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
@@ -0,0 +1,7 @@
+-- Emails are unique regardless of case. Rows that differ only in case must
+-- be merged or renamed before this migration can apply.
+ALTER TABLE users
+    ADD COLUMN email_normalized varchar(255) GENERATED ALWAYS AS (lower(email)) STORED;
+
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email_normalized ON users (email_normalized);
@@ -0,0 +1,2 @@
+-- Grants of these permissions to other roles go with them.
+DELETE FROM permissions WHERE name IN ('users.read', 'users.manage');
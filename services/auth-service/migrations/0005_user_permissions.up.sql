@@ -0,0 +1,15 @@
+-- The GraphQL API authorizes user queries by permission while the REST
+-- admin routes check the Administrator role, so the role must grant the
+-- permissions even where the seeding fixtures were never loaded.
+INSERT INTO permissions (name, description, created_at, updated_at) VALUES
+    ('users.read', 'View user accounts and profiles', now(), now()),
+    ('users.manage', 'Change user status, roles and delete or restore accounts', now(), now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'Administrator'
+  AND p.name IN ('users.read', 'users.manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+ALTER TABLE audit_entries
+    ALTER COLUMN prev_hash TYPE char(64),
+    ALTER COLUMN hash TYPE char(64);
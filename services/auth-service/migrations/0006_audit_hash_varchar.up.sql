@@ -0,0 +1,6 @@
+-- char(64) pads the genesis entry's empty prev_hash with spaces, which
+-- breaks chain verification at the first entry. Casting to varchar strips
+-- the padding from existing rows.
+ALTER TABLE audit_entries
+    ALTER COLUMN prev_hash TYPE varchar(64),
+    ALTER COLUMN hash TYPE varchar(64);
@@ -0,0 +1,9 @@
+// Package migrations embeds the versioned SQL migrations for the auth
+// service schema. Files are named <version>_<name>.up.sql and
+// <version>_<name>.down.sql and must never be edited once released.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
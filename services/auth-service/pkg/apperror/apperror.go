@@ -0,0 +1,96 @@
+package apperror
+
+import "errors"
+
+// Code is a stable, machine-readable error identifier that clients can
+// rely on across releases.
+type Code string
+
+const (
+	CodeBadRequest         Code = "BAD_REQUEST"
+	CodeMalformedRequest   Code = "MALFORMED_REQUEST"
+	CodeValidationFailed   Code = "VALIDATION_FAILED"
+	CodeUnauthorized       Code = "UNAUTHORIZED"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeConflict           Code = "CONFLICT"
+	CodeInternal           Code = "INTERNAL_ERROR"
+	CodeUserNotFound       Code = "USER_NOT_FOUND"
+	CodeEmailTaken         Code = "EMAIL_TAKEN"
+	CodeUsernameTaken      Code = "USERNAME_TAKEN"
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeInvalidToken       Code = "INVALID_TOKEN"
+	CodeRoleNotFound       Code = "ROLE_NOT_FOUND"
+	CodeInvalidInviteCode  Code = "INVALID_INVITE_CODE"
+	CodeInvalidInvitation  Code = "INVALID_INVITATION"
+	CodeInvitationExpired  Code = "INVITATION_EXPIRED"
+	CodeInvitationPending  Code = "INVITATION_PENDING"
+	CodeInvalidOTP         Code = "INVALID_OTP"
+	CodeOTPLocked          Code = "OTP_ATTEMPTS_EXCEEDED"
+	CodeOTPRateLimited     Code = "OTP_RATE_LIMITED"
+	CodePhoneNotVerified   Code = "PHONE_NOT_VERIFIED"
+	CodeAccountInactive    Code = "ACCOUNT_INACTIVE"
+	CodeInvalidTransition  Code = "INVALID_STATUS_TRANSITION"
+)
+
+var (
+	ErrUserNotFound       = New(CodeUserNotFound, "user not found")
+	ErrEmailTaken         = New(CodeEmailTaken, "a user with this email already exists")
+	ErrUsernameTaken      = New(CodeUsernameTaken, "a user with this username already exists")
+	ErrInvalidCredentials = New(CodeInvalidCredentials, "invalid email or password")
+	ErrInvalidToken       = New(CodeInvalidToken, "token is invalid or expired")
+	ErrRoleNotFound       = New(CodeRoleNotFound, "role not found")
+	ErrInvalidInviteCode  = New(CodeInvalidInviteCode, "invite code is invalid, expired or already used")
+	ErrInvalidInvitation  = New(CodeInvalidInvitation, "invitation is invalid or no longer pending")
+	ErrInvitationExpired  = New(CodeInvitationExpired, "invitation has expired")
+	ErrInvitationPending  = New(CodeInvitationPending, "an invitation for this email is already pending")
+	ErrInvalidOTP         = New(CodeInvalidOTP, "verification code is invalid or expired")
+	ErrOTPLocked          = New(CodeOTPLocked, "too many incorrect attempts, request a new code")
+	ErrOTPRateLimited     = New(CodeOTPRateLimited, "a code was sent recently, try again shortly")
+	ErrPhoneNotVerified   = New(CodePhoneNotVerified, "a verified phone number is required")
+	ErrAccountInactive    = New(CodeAccountInactive, "account is not active")
+
+	ErrInvalidStatusTransition = New(CodeInvalidTransition, "the account cannot move to the requested status")
+)
+
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the same code, so wrapped
+// errors still match the package sentinels via errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// CodeOf returns the code of the first *Error in err's chain, or
+// CodeInternal when there is none.
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
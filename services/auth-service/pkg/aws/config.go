@@ -3,37 +3,73 @@ package aws
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"go.uber.org/fx"
 )
 
-func NewConfigWithStaticCredentials() (*aws.Config, error) {
-	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	region := os.Getenv("AWS_DEFAULT_REGION")
+var Module = fx.Provide(NewConfig)
 
-	if accessKey == "" || secretKey == "" {
-		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
-	}
+// Options narrows the SDK's default credential chain. Everything is
+// optional: with no settings the chain and region are resolved the way the
+// AWS CLI resolves them.
+type Options struct {
+	Region                string
+	Profile               string
+	AccessKeyID           string
+	SecretAccessKey       string
+	SessionToken          string
+	AssumeRoleARN         string
+	AssumeRoleExternalID  string
+	AssumeRoleSessionName string
+	EndpointURL           string
+}
 
-	if region == "" {
-		region = "us-east-1"
+// NewConfig builds the AWS config shared by every client. Credentials come
+// from the SDK's default chain (environment, shared config and SSO
+// profiles, web identity tokens, container and instance roles) unless
+// static keys are configured, optionally narrowed to AWS_PROFILE, and are
+// then exchanged for AWS_ASSUME_ROLE_ARN when set. AWS_ENDPOINT_URL points
+// all clients at an emulator such as LocalStack.
+func NewConfig(c Options) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{}
+	if c.Region != "" {
+		opts = append(opts, config.WithRegion(c.Region))
+	}
+	if c.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(c.Profile))
+	}
+	if c.AccessKeyID != "" {
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			c.AccessKeyID,
+			c.SecretAccessKey,
+			c.SessionToken,
+		)))
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			accessKey,
-			secretKey,
-			os.Getenv("AWS_SESSION_TOKEN"),
-		)),
-	)
+	cfg, err := config.LoadDefaultConfig(context.TODO(), opts...)
 	if err != nil {
-		return nil, err
+		return aws.Config{}, fmt.Errorf("load AWS config: %w", err)
+	}
+	if cfg.Region == "" {
+		return aws.Config{}, fmt.Errorf("AWS region is not set; set AWS_REGION or a region in the AWS profile")
+	}
+	if c.EndpointURL != "" {
+		cfg.BaseEndpoint = aws.String(c.EndpointURL)
 	}
 
-	return &cfg, nil
+	if c.AssumeRoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), c.AssumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = c.AssumeRoleSessionName
+			if c.AssumeRoleExternalID != "" {
+				o.ExternalID = aws.String(c.AssumeRoleExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
 }
@@ -0,0 +1,60 @@
+package aws
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/stretchr/testify/require"
+)
+
+// isolate hides the environment's and home directory's AWS settings.
+func isolate(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION", "AWS_PROFILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_ENDPOINT_URL", "AWS_ROLE_ARN"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestNewConfigRequiresRegion(t *testing.T) {
+	isolate(t)
+	_, err := NewConfig(Options{})
+	require.ErrorContains(t, err, "AWS region is not set")
+}
+
+func TestNewConfigUsesStaticCredentialsAndEndpoint(t *testing.T) {
+	isolate(t)
+	cfg, err := NewConfig(Options{
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		EndpointURL:     "http://localhost:4566",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "eu-west-1", cfg.Region)
+	require.Equal(t, "http://localhost:4566", aws.ToString(cfg.BaseEndpoint))
+
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "AKID", creds.AccessKeyID)
+	require.Equal(t, "secret", creds.SecretAccessKey)
+}
+
+func TestNewConfigAssumesRole(t *testing.T) {
+	isolate(t)
+	cfg, err := NewConfig(Options{
+		Region:                "eu-west-1",
+		AccessKeyID:           "AKID",
+		SecretAccessKey:       "secret",
+		AssumeRoleARN:         "arn:aws:iam::123456789012:role/auth-service",
+		AssumeRoleSessionName: "auth-service",
+	})
+	require.NoError(t, err)
+	cache, ok := cfg.Credentials.(*aws.CredentialsCache)
+	require.True(t, ok)
+	require.True(t, cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}))
+}
@@ -2,97 +2,114 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
-
+	"time"
+
+	"github.com/content-management-system/auth-service/pkg/health"
+	"github.com/content-management-system/auth-service/pkg/logger"
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus"
 	"go.uber.org/fx"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 type DB struct {
 	Conn *gorm.DB
 }
 
-type Config struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+type Options struct {
+	// DSN is the libpq connection string, without the password.
+	DSN string
+	// SlowQueryThreshold logs statements that take longer as warnings.
+	SlowQueryThreshold time.Duration
 }
 
-var Module = fx.Provide(NewDBProvider)
+var Module = fx.Module("db",
+	fx.Provide(NewDBProvider, health.AsCheck(newHealthCheck)),
+	fx.Decorate(logger.Named("db")),
+)
 
-func NewDBProvider(lc fx.Lifecycle) (*DB, error) {
-	cfg := LoadConfig()
+const (
+	maxConns = 25
+	// connMaxLifetime bounds how long a connection opened with a rotated
+	// password stays in use.
+	connMaxLifetime = 30 * time.Minute
+)
 
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
-	)
+// NewDBProvider opens the pool. The password is not part of the DSN: each
+// new connection asks the secrets store for DB_PASSWORD, and idle
+// connections are dropped when it rotates, so the pool reconnects with the
+// new credentials without a restart.
+func NewDBProvider(lc fx.Lifecycle, opts Options, store *secrets.Store, log *logrus.Logger) (*DB, error) {
+	connConfig, err := pgx.ParseConfig(opts.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database config: %w", err)
+	}
+	sqlDB := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(func(ctx context.Context, c *pgx.ConnConfig) error {
+		password, err := store.Get(ctx, secrets.DBPassword)
+		if err != nil && !errors.Is(err, secrets.ErrSecretNotFound) {
+			return fmt.Errorf("failed to load database password: %w", err)
+		}
+		c.Password = password
+		return nil
+	}))
 
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: newGormLogger(log, opts.SlowQueryThreshold),
 	}
 
-	conn, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	conn, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), gormConfig)
 	if err != nil {
+		_ = sqlDB.Close()
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	sqlDB, err := conn.DB()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
-	}
-
-	sqlDB.SetMaxOpenConns(25)
-	sqlDB.SetMaxIdleConns(25)
-	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetMaxOpenConns(maxConns)
+	sqlDB.SetMaxIdleConns(maxConns)
+	sqlDB.SetConnMaxLifetime(connMaxLifetime)
 
 	if err := sqlDB.Ping(); err != nil {
-		err := sqlDB.Close()
-		if err != nil {
-			return nil, err
-		}
+		_ = sqlDB.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	store.Watch(secrets.DBPassword, func(string) {
+		log.Info("Database password rotated, closing idle connections")
+		sqlDB.SetMaxIdleConns(0)
+		sqlDB.SetMaxIdleConns(maxConns)
+	})
+
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			if err := sqlDB.PingContext(ctx); err != nil {
 				return fmt.Errorf("failed to ping database on start: %w", err)
 			}
-			log.Println("Database connection verified")
+			log.Info("Database connection verified")
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
-			log.Println("Closing database connection")
+			log.Info("Closing database connection")
 			return sqlDB.Close()
 		},
 	})
 
-	log.Println("GORM database connection established")
+	log.Info("GORM database connection established")
 	return &DB{Conn: conn}, nil
 }
 
-func LoadConfig() Config {
-	return Config{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", ""),
-		DBName:   getEnv("DB_NAME", "mydb"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
-	}
-}
-
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// newHealthCheck pings the pool.
+func newHealthCheck(d *DB) (health.Check, error) {
+	sqlDB, err := d.Conn.DB()
+	if err != nil {
+		return health.Check{}, err
 	}
-	return defaultValue
+	return health.Check{
+		Name:        "database",
+		Criticality: health.Critical,
+		Run:         sqlDB.PingContext,
+	}, nil
 }
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// gormLogger sends GORM's output to logrus. Statements are logged with
+// their placeholders only, never the bound values, since those include
+// password hashes, tokens and personal data. Failed statements are logged
+// as errors, statements slower than the threshold as warnings, and every
+// other statement at debug level.
+type gormLogger struct {
+	log           *logrus.Logger
+	slowThreshold time.Duration
+}
+
+func newGormLogger(log *logrus.Logger, slowThreshold time.Duration) *gormLogger {
+	return &gormLogger{log: log, slowThreshold: slowThreshold}
+}
+
+// LogMode is a no-op: the level is the logrus logger's.
+func (l *gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.log.WithContext(ctx).Infof(msg, args...)
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.log.WithContext(ctx).Warnf(msg, args...)
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.log.WithContext(ctx).Errorf(msg, args...)
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+	if !failed && !slow && !l.log.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+
+	sql, rows := fc()
+	entry := l.log.WithContext(ctx).WithFields(logrus.Fields{
+		"sql":         sql,
+		"rows":        rows,
+		"duration_ms": float64(elapsed.Microseconds()) / 1000,
+	})
+	switch {
+	case failed:
+		entry.WithError(err).Error("Query failed")
+	case slow:
+		entry.WithField("threshold", l.slowThreshold.String()).Warn("Slow query")
+	default:
+		entry.Debug("Query")
+	}
+}
+
+// ParamsFilter drops the bound values before GORM renders the statement.
+func (l *gormLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type account struct {
+	ID    uint
+	Email string
+}
+
+func TestGormLoggerRedactsValuesAndFlagsSlowQueries(t *testing.T) {
+	var out bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&out)
+	log.SetLevel(logrus.DebugLevel)
+	gl := newGormLogger(log, time.Millisecond)
+
+	conn, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               gl,
+	})
+	require.NoError(t, err)
+
+	var accounts []account
+	require.NoError(t, conn.Where("email = ?", "jane@example.com").Find(&accounts).Error)
+	require.Contains(t, out.String(), "$1")
+	require.NotContains(t, out.String(), "jane@example.com")
+
+	// Above debug, only slow and failed statements are logged.
+	out.Reset()
+	log.SetLevel(logrus.WarnLevel)
+	require.NoError(t, conn.Where("email = ?", "jane@example.com").Find(&accounts).Error)
+	require.Empty(t, out.String())
+	gl.Trace(context.Background(), time.Now().Add(-time.Second), func() (string, int64) { return "SELECT 1", 0 }, nil)
+	require.Contains(t, out.String(), "Slow query")
+
+	out.Reset()
+	gl.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 0 }, gorm.ErrRecordNotFound)
+	require.Empty(t, out.String(), "a missing record is not an error")
+	gl.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 0 }, errors.New("boom"))
+	require.Contains(t, out.String(), "Query failed")
+}
@@ -3,64 +3,155 @@ package fiber_app
 import (
 	"context"
 	"fmt"
-	"os"
+	"net"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
-	graph2 "github.com/content-management-system/auth-service/internal/handler/graph"
+	"github.com/content-management-system/auth-service/internal/handler/graph"
 	h "github.com/content-management-system/auth-service/internal/handler/rest/handler"
-	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/content-management-system/auth-service/internal/handler/rest/middleware"
+	"github.com/content-management-system/auth-service/internal/model/types"
+	"github.com/content-management-system/auth-service/internal/service"
+	"github.com/content-management-system/auth-service/pkg/health"
+	"github.com/content-management-system/auth-service/pkg/logger"
+	"github.com/content-management-system/auth-service/pkg/metrics"
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/content-management-system/auth-service/pkg/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 )
 
-var Module = fx.Provide(NewFiberApp)
+var Module = fx.Module("http",
+	fx.Provide(NewFiberApp),
+	fx.Decorate(logger.Named("http")),
+)
+
+// Options are the server's listen port, the shutdown timings described on
+// NewFiberApp's stop hook, and whether GraphQL runs in production mode.
+type Options struct {
+	Port              int
+	ShutdownDelay     time.Duration
+	DrainTimeout      time.Duration
+	GraphQLProduction bool
+}
 
 type FiberApp struct {
 	App      *fiber.App
 	logger   *logrus.Logger
 	handlers *h.AuthHandler
-	db       *db.DB
+	admin    *h.AdminHandler
+	invites  *h.InvitationHandler
+	profile  *h.ProfileHandler
+	phone    *h.PhoneHandler
+	users    *h.UserAdminHandler
+	privacy  *h.PrivacyHandler
+	audit    *h.AuditHandler
+	logLevel *h.LogLevelHandler
+	authMw   *middleware.AuthMiddleware
+	graphQL  *handler.Server
+	health   *health.Registry
+	registry *prometheus.Registry
 }
 
 func NewFiberApp(
 	lifeCycle fx.Lifecycle,
 	handlers *h.AuthHandler,
-	log *logrus.Logger,
-	db *db.DB) *FiberApp {
+	admin *h.AdminHandler,
+	invites *h.InvitationHandler,
+	profile *h.ProfileHandler,
+	phone *h.PhoneHandler,
+	users *h.UserAdminHandler,
+	privacy *h.PrivacyHandler,
+	audit *h.AuditHandler,
+	logLevel *h.LogLevelHandler,
+	authMw *middleware.AuthMiddleware,
+	graphQL *handler.Server,
+	opts Options,
+	httpMetrics *metrics.HTTPMetrics,
+	registry *prometheus.Registry,
+	tp trace.TracerProvider,
+	healthChecks *health.Registry,
+	shutdowner fx.Shutdowner,
+	log *logrus.Logger) *FiberApp {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
+		ErrorHandler:          problem.ErrorHandler(log),
 	})
 
 	fiberApp := &FiberApp{
 		App:      app,
 		logger:   log,
 		handlers: handlers,
-		db:       db,
+		admin:    admin,
+		invites:  invites,
+		profile:  profile,
+		phone:    phone,
+		users:    users,
+		privacy:  privacy,
+		audit:    audit,
+		logLevel: logLevel,
+		authMw:   authMw,
+		graphQL:  graphQL,
+		health:   healthChecks,
+		registry: registry,
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	port := opts.Port
+
+	app.Use(requestid.New(requestid.Config{
+		Header:     problem.RequestIDHeader,
+		ContextKey: problem.RequestIDLocal,
+	}))
+	app.Use(tracing.Middleware(tp))
+	app.Use(logger.Middleware(log))
+	app.Use(httpMetrics.Middleware())
 
 	fiberApp.setupRoutes()
+	fiberApp.setupGraphQL(opts.GraphQLProduction)
 
 	lifeCycle.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			fiberApp.logger.Info(fmt.Sprintf("Starting Fiber server on :%s", port))
+			// Binding here rather than in the goroutine makes a busy port
+			// fail startup instead of killing the process later.
+			ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+			if err != nil {
+				return fmt.Errorf("listen on :%d: %w", port, err)
+			}
+			fiberApp.logger.Info(fmt.Sprintf("Starting Fiber server on :%d", port))
 			go func() {
-				if err := app.Listen(fmt.Sprintf(":%s", port)); err != nil {
-					fiberApp.logger.WithError(err).Fatal("Failed to start Fiber server")
+				if err := app.Listener(ln); err != nil {
+					fiberApp.logger.WithError(err).Error("Fiber server stopped unexpectedly")
+					_ = shutdowner.Shutdown(fx.ExitCode(1))
 				}
 			}()
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
+			fiberApp.logger.Info("Draining, readiness now failing")
+			healthChecks.Drain()
+			select {
+			case <-time.After(opts.ShutdownDelay):
+			case <-ctx.Done():
+			}
+
+			// Stop accepting connections and wait for in-flight requests,
+			// giving up on any still running when HTTP_DRAIN_TIMEOUT or the
+			// stop deadline passes.
 			fiberApp.logger.Info("Shutting down Fiber server")
-			return app.Shutdown()
+			drainCtx, cancel := context.WithTimeout(ctx, opts.DrainTimeout)
+			defer cancel()
+			if err := app.ShutdownWithContext(drainCtx); err != nil {
+				return fmt.Errorf("drain HTTP server: %w", err)
+			}
+			fiberApp.logger.Info("Fiber server stopped")
+			return nil
 		},
 	})
 
@@ -72,33 +163,72 @@ func (app *FiberApp) setupRoutes() {
 		return c.SendString("Hello, World!")
 	})
 
-	app.App.Get("/health", func(c *fiber.Ctx) error {
-		var result int
-		if err := app.db.Conn.Raw("SELECT 1").Scan(&result).Error; err != nil {
-			app.logger.WithError(err).Error("Database health check failed")
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"status": "error",
-				"error":  "Database connection failed",
-			})
-		}
-		return c.JSON(fiber.Map{
-			"status":  "ok",
-			"message": "Application is healthy",
-		})
-	})
+	app.App.Get("/metrics", adaptor.HTTPHandler(promhttp.HandlerFor(app.registry, promhttp.HandlerOpts{})))
+
+	app.App.Get("/livez", app.health.LivezHandler)
+	app.App.Get("/readyz", app.health.ReadyzHandler)
+	app.App.Get("/health", app.health.ReadyzHandler)
 
 	auth := app.App.Group("/auth")
 	auth.Post("/register", app.handlers.Register)
 	auth.Post("/login", app.handlers.Login)
 	auth.Post("/refresh", app.handlers.RefreshToken)
 	auth.Post("/logout", app.handlers.Logout)
-
+	auth.Post("/email/confirm", app.profile.ConfirmEmail)
+	auth.Post("/mfa/verify", app.handlers.VerifyMFA)
+	auth.Post("/recover/phone", app.phone.StartRecovery)
+	auth.Post("/recover/phone/confirm", app.phone.CompleteRecovery)
+
+	me := app.App.Group("/me", app.authMw.RequireAuth())
+	me.Get("/", app.profile.Get)
+	me.Patch("/", app.profile.Update)
+	me.Delete("/", app.users.DeleteSelf)
+	me.Post("/email", app.profile.ChangeEmail)
+	me.Post("/password", app.profile.ChangePassword)
+	me.Post("/avatar", app.profile.UploadAvatar)
+	me.Post("/phone/verify", app.phone.StartVerification)
+	me.Post("/phone/verify/confirm", app.phone.ConfirmVerification)
+	me.Put("/mfa", app.phone.UpdateMFA)
+	me.Post("/exports", app.privacy.RequestOwnExport)
+	me.Get("/exports", app.privacy.ListOwnExports)
+	me.Get("/exports/:id", app.privacy.GetOwnExport)
+	me.Get("/exports/:id/download", app.privacy.DownloadOwnExport)
+	app.App.Static(service.AvatarURLPrefix, app.profile.AvatarDir())
+
+	admin := app.App.Group("/admin", app.authMw.RequireAuth(), app.authMw.RequireRole(types.RoleAdministrator))
+	admin.Get("/roles", app.admin.ListRoles)
+	admin.Get("/invite-codes", app.admin.ListInviteCodes)
+	admin.Post("/invite-codes", app.admin.CreateInviteCode)
+	admin.Delete("/invite-codes/:id", app.admin.RevokeInviteCode)
+	admin.Get("/users", app.users.List)
+	admin.Post("/users/:id/status", app.users.ChangeStatus)
+	admin.Put("/users/:id/role", app.users.ChangeRole)
+	admin.Delete("/users/:id", app.users.Delete)
+	admin.Post("/users/:id/restore", app.users.Restore)
+	admin.Get("/users/:id/status-history", app.users.History)
+	admin.Post("/users/:id/exports", app.privacy.RequestExport)
+	admin.Post("/users/:id/erase", app.privacy.Erase)
+	admin.Get("/exports/:id", app.privacy.GetExport)
+	admin.Get("/exports/:id/download", app.privacy.DownloadExport)
+	admin.Get("/audit", app.audit.List)
+	admin.Get("/audit/export", app.audit.Export)
+	admin.Get("/audit/verify", app.audit.Verify)
+	admin.Get("/log-levels", app.logLevel.List)
+	admin.Put("/log-levels/:module", app.logLevel.Set)
+	admin.Get("/invitations", app.invites.List)
+	admin.Post("/invitations", app.invites.Create)
+	admin.Post("/invitations/:id/resend", app.invites.Resend)
+	admin.Delete("/invitations/:id", app.invites.Revoke)
+
+	app.App.Post("/invitations/accept", app.invites.Accept)
 }
 
-func (app *FiberApp) setupGraphQL(resolver *graph2.Resolver) {
-	srv := handler.NewDefaultServer(graph2.NewExecutableSchema(graph2.Config{Resolvers: resolver}))
+func (app *FiberApp) setupGraphQL(production bool) {
+	// Anonymous callers may register and log in; the schema's @auth and
+	// @hasPermission directives guard everything else.
+	app.App.All("/graph", app.authMw.OptionalAuth(), graph.Handler(app.graphQL))
 
-	app.App.All("/graph", adaptor.HTTPHandler(srv))
-
-	app.App.Get("/playground", adaptor.HTTPHandler(playground.Handler("GraphQL playground", "/graph")))
+	if !production {
+		app.App.Get("/playground", adaptor.HTTPHandler(playground.Handler("GraphQL playground", "/graph")))
+	}
 }
@@ -0,0 +1,230 @@
+// Package health runs the named dependency checks behind /livez and
+// /readyz. Modules contribute checks by providing a Check in the
+// "health_checks" group.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+const defaultTimeout = 2 * time.Second
+
+var Module = fx.Provide(NewRegistry)
+
+// Criticality decides whether a failing check takes the service out of
+// rotation.
+type Criticality string
+
+const (
+	// Critical checks fail readiness.
+	Critical Criticality = "critical"
+	// NonCritical checks are reported, and mark the service degraded, but
+	// never fail readiness.
+	NonCritical Criticality = "non_critical"
+)
+
+// Check is one named probe. Liveness checks also run on /livez; only
+// failures a restart would fix belong there, since a failing liveness
+// probe gets the process killed. A check with CacheFor set answers from
+// its last result until that is older than CacheFor, so probes do not
+// load the dependency it talks to.
+type Check struct {
+	Name        string
+	Criticality Criticality
+	Liveness    bool
+	Timeout     time.Duration
+	CacheFor    time.Duration
+	Run         func(ctx context.Context) error
+}
+
+// AsCheck annotates a constructor returning a Check for the
+// "health_checks" group.
+func AsCheck(constructor interface{}) interface{} {
+	return fx.Annotate(constructor, fx.ResultTags(`group:"health_checks"`))
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name        string      `json:"name"`
+	Status      string      `json:"status"`
+	Criticality Criticality `json:"criticality"`
+	LatencyMS   float64     `json:"latency_ms"`
+}
+
+// Report is the body of /livez and /readyz.
+type Report struct {
+	Status       string   `json:"status"`
+	ShuttingDown bool     `json:"shutting_down,omitempty"`
+	Checks       []Result `json:"checks"`
+}
+
+const (
+	StatusPass     = "pass"
+	StatusFail     = "fail"
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+)
+
+type Registry struct {
+	log      *logrus.Logger
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks []*entry
+}
+
+// entry is a registered check with its cached result.
+type entry struct {
+	Check
+
+	mu   sync.Mutex
+	last Result
+	at   time.Time
+}
+
+type Params struct {
+	fx.In
+	Log    *logrus.Logger
+	Checks []Check `group:"health_checks"`
+}
+
+func NewRegistry(p Params) *Registry {
+	r := &Registry{log: p.Log}
+	for _, c := range p.Checks {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register adds a check. Checks without a criticality are critical.
+func (r *Registry) Register(c Check) {
+	if c.Criticality == "" {
+		c.Criticality = Critical
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, &entry{Check: c})
+}
+
+// Drain makes readiness fail from now on, so load balancers stop sending
+// traffic before the server stops accepting it.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Live runs the liveness checks.
+func (r *Registry) Live(ctx context.Context) Report {
+	return r.run(ctx, func(c Check) bool { return c.Liveness })
+}
+
+// Ready runs every check. It fails while draining.
+func (r *Registry) Ready(ctx context.Context) Report {
+	report := r.run(ctx, func(Check) bool { return true })
+	if r.draining.Load() {
+		report.Status = StatusFail
+		report.ShuttingDown = true
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, include func(Check) bool) Report {
+	r.mu.RLock()
+	checks := make([]*entry, 0, len(r.checks))
+	for _, e := range r.checks {
+		if include(e.Check) {
+			checks = append(checks, e)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, e := range checks {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			results[i] = r.cached(ctx, e)
+		}(i, e)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+	for _, res := range results {
+		if res.Status == StatusPass {
+			continue
+		}
+		if res.Criticality == Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// cached runs a check unless its last result is still fresh. Concurrent
+// probes wait for the run in flight instead of starting their own.
+func (r *Registry) cached(ctx context.Context, e *entry) Result {
+	if e.CacheFor <= 0 {
+		return r.runOne(ctx, e.Check)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.at.IsZero() && time.Since(e.at) < e.CacheFor {
+		return e.last
+	}
+	e.last = r.runOne(ctx, e.Check)
+	e.at = time.Now()
+	return e.last
+}
+
+func (r *Registry) runOne(ctx context.Context, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Run(ctx)
+	result := Result{
+		Name:        c.Name,
+		Status:      StatusPass,
+		Criticality: c.Criticality,
+		LatencyMS:   float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		// The reason is logged rather than returned: the probes are
+		// unauthenticated.
+		r.log.WithContext(ctx).WithError(err).WithField("check", c.Name).Warn("Health check failed")
+		result.Status = StatusFail
+	}
+	return result
+}
+
+// LivezHandler serves Live; it answers 503 when a liveness check fails.
+func (r *Registry) LivezHandler(c *fiber.Ctx) error {
+	return send(c, r.Live(c.UserContext()))
+}
+
+// ReadyzHandler serves Ready; it answers 503 when a critical check fails
+// or the service is shutting down.
+func (r *Registry) ReadyzHandler(c *fiber.Ctx) error {
+	return send(c, r.Ready(c.UserContext()))
+}
+
+func send(c *fiber.Ctx, report Report) error {
+	status := fiber.StatusOK
+	if report.Status == StatusFail {
+		status = fiber.StatusServiceUnavailable
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(report)
+}
@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(checks ...Check) *Registry {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewRegistry(Params{Log: log, Checks: checks})
+}
+
+func pass(context.Context) error { return nil }
+
+func fail(context.Context) error { return errors.New("down") }
+
+func get(t *testing.T, r *Registry, path string) (int, Report) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/livez", r.LivezHandler)
+	app.Get("/readyz", r.ReadyzHandler)
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var report Report
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	return resp.StatusCode, report
+}
+
+func TestReadinessFollowsCriticality(t *testing.T) {
+	r := newTestRegistry(
+		Check{Name: "database", Run: pass},
+		Check{Name: "cognito", Criticality: NonCritical, Run: fail},
+	)
+	status, report := get(t, r, "/readyz")
+	require.Equal(t, fiber.StatusOK, status)
+	require.Equal(t, StatusDegraded, report.Status)
+	require.Equal(t, []string{StatusPass, StatusFail}, []string{report.Checks[0].Status, report.Checks[1].Status})
+	require.Equal(t, Critical, report.Checks[0].Criticality)
+
+	r.Register(Check{Name: "migrations", Run: fail})
+	status, report = get(t, r, "/readyz")
+	require.Equal(t, fiber.StatusServiceUnavailable, status)
+	require.Equal(t, StatusFail, report.Status)
+}
+
+func TestLivenessOnlyRunsLivenessChecks(t *testing.T) {
+	r := newTestRegistry(
+		Check{Name: "database", Run: fail},
+		Check{Name: "signing_keys", Liveness: true, Run: pass},
+	)
+	status, report := get(t, r, "/livez")
+	require.Equal(t, fiber.StatusOK, status)
+	require.Len(t, report.Checks, 1)
+	require.Equal(t, "signing_keys", report.Checks[0].Name)
+}
+
+func TestChecksAreBoundedByTheirTimeout(t *testing.T) {
+	r := newTestRegistry(Check{Name: "slow", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	report := r.Ready(context.Background())
+	require.Equal(t, StatusFail, report.Status)
+	require.Less(t, report.Checks[0].LatencyMS, 1000.0)
+}
+
+func TestDrainFailsReadinessButNotLiveness(t *testing.T) {
+	r := newTestRegistry(Check{Name: "database", Liveness: true, Run: pass})
+	r.Drain()
+
+	status, report := get(t, r, "/readyz")
+	require.Equal(t, fiber.StatusServiceUnavailable, status)
+	require.True(t, report.ShuttingDown)
+
+	status, _ = get(t, r, "/livez")
+	require.Equal(t, fiber.StatusOK, status)
+}
+
+func TestCachedCheckRunsOncePerWindow(t *testing.T) {
+	var runs int
+	r := newTestRegistry(Check{Name: "cognito", CacheFor: time.Hour, Run: func(context.Context) error {
+		runs++
+		return nil
+	}})
+	for i := 0; i < 3; i++ {
+		require.Equal(t, StatusOK, r.Ready(context.Background()).Status)
+	}
+	require.Equal(t, 1, runs)
+
+	r.checks[0].at = time.Now().Add(-2 * time.Hour)
+	r.Ready(context.Background())
+	require.Equal(t, 2, runs)
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx carrying fields in addition to any it
+// already has. ContextHook adds them to every entry logged WithContext.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	merged := logrus.Fields{}
+	for k, v := range FieldsFrom(ctx) {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FieldsFrom returns the fields attached to ctx.
+func FieldsFrom(ctx context.Context) logrus.Fields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	return fields
+}
+
+// ContextHook copies the fields attached to an entry's context onto the
+// entry. Fields set on the entry itself win.
+type ContextHook struct{}
+
+func (ContextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (ContextHook) Fire(entry *logrus.Entry) error {
+	for k, v := range FieldsFrom(entry.Context) {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
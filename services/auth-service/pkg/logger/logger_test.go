@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry() (*Registry, *bytes.Buffer) {
+	var out bytes.Buffer
+	root := logrus.New()
+	root.SetOutput(&out)
+	root.SetFormatter(&logrus.JSONFormatter{})
+	root.AddHook(ContextHook{})
+	return NewRegistryFor(root), &out
+}
+
+func entries(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var result []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		result = append(result, entry)
+	}
+	return result
+}
+
+func TestRegistryLevelsArePerModule(t *testing.T) {
+	r, out := newTestRegistry()
+	db := r.Named("db")
+	cognito := r.Named("cognito")
+
+	require.NoError(t, r.SetLevel("db", "warn"))
+	db.Info("hidden")
+	db.Warn("shown")
+	cognito.Info("shown")
+
+	require.NoError(t, r.SetLevel(RootModule, "error"))
+	cognito.Warn("hidden")
+	db.Warn("shown")
+
+	require.Error(t, r.SetLevel("db", "loud"))
+	require.Equal(t, map[string]string{"root": "error", "db": "warning", "cognito": "error"}, r.Levels())
+
+	logged := entries(t, out)
+	require.Len(t, logged, 3)
+	require.Equal(t, "db", logged[0]["module"])
+	require.Equal(t, "cognito", logged[1]["module"])
+}
+
+func TestMiddlewareCorrelatesRequestLogs(t *testing.T) {
+	r, out := newTestRegistry()
+	log := r.Named("http")
+	service := r.Named("service")
+
+	app := fiber.New()
+	app.Use(requestid.New(requestid.Config{Header: problem.RequestIDHeader, ContextKey: problem.RequestIDLocal}))
+	app.Use(Middleware(log))
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		ctx := WithFields(c.UserContext(), logrus.Fields{"user_id": "u-1"})
+		service.WithContext(ctx).Info("loaded user")
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set(problem.RequestIDHeader, "req-1")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	logged := entries(t, out)
+	require.Len(t, logged, 2)
+	inHandler, access := logged[0], logged[1]
+	require.Equal(t, "req-1", inHandler["request_id"])
+	require.Equal(t, "u-1", inHandler["user_id"])
+	require.Equal(t, "/users/42", inHandler["path"])
+	require.Equal(t, "service", inHandler["module"])
+
+	require.Equal(t, "req-1", access["request_id"])
+	require.Equal(t, "/users/:id", access["route"])
+	require.Equal(t, float64(200), access["status"])
+}
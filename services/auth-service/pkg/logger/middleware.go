@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// Middleware attaches the request ID, method and path to the request
+// context so service code logging WithContext(ctx) is correlated with the
+// request, and writes one access log entry per request with the matched
+// route. It must run after the request ID middleware.
+func Middleware(log *logrus.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		c.SetUserContext(WithFields(c.UserContext(), logrus.Fields{
+			"request_id": problem.RequestID(c),
+			"method":     c.Method(),
+			"path":       c.Path(),
+		}))
+
+		problem.Render(c, c.Next())
+
+		status := c.Response().StatusCode()
+		entry := log.WithContext(c.UserContext()).WithFields(logrus.Fields{
+			"route":       problem.Route(c),
+			"status":      status,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+		if status >= fiber.StatusInternalServerError {
+			entry.Warn("Request completed")
+		} else {
+			entry.Info("Request completed")
+		}
+		return nil
+	}
+}
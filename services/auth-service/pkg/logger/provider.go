@@ -1,40 +1,92 @@
+// Package logger provides the service's logrus loggers. Every module logs
+// through its own named logger so its level can be changed at runtime,
+// and request-scoped fields travel with the context.
 package logger
 
 import (
 	"context"
+	"io"
 	"log"
+	"os"
 
 	"github.com/sirupsen/logrus"
 	"go.uber.org/fx"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+var Module = fx.Provide(NewRegistry, NewLogger)
+
+// Options selects where logs go and how verbose they are. ModuleLevels
+// overrides Level for individual modules.
+type Options struct {
+	Level        string
+	ModuleLevels map[string]string
+	Output       string
+	File         string
+}
+
 type Params struct {
 	fx.In
 	Lifecycle fx.Lifecycle
+	Options   Options
 }
 
-func NewLogger(params Params) *logrus.Logger {
-	logger := logrus.New()
+// NewRegistry creates the root logger writing JSON to LOG_OUTPUT: stdout,
+// the rotated LOG_FILE, or both.
+func NewRegistry(params Params) (*Registry, error) {
+	cfg := params.Options
+	var out io.Writer = os.Stdout
+	if cfg.Output == "file" || cfg.Output == "both" {
+		lumberjackLogger := &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    100,
+			MaxBackups: 3,
+			MaxAge:     28,
+			Compress:   true,
+		}
+		params.Lifecycle.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error {
+				log.Println("Closing log file")
+				return lumberjackLogger.Close()
+			},
+		})
+		out = lumberjackLogger
+		if cfg.Output == "both" {
+			out = io.MultiWriter(os.Stdout, lumberjackLogger)
+		}
+	}
+
+	root := logrus.New()
+	root.SetOutput(out)
+	root.SetFormatter(&logrus.JSONFormatter{})
+	root.AddHook(ContextHook{})
 
-	lumberjackLogger := &lumberjack.Logger{
-		Filename:   "logs/app.log",
-		MaxSize:    100,
-		MaxBackups: 3,
-		MaxAge:     28,
-		Compress:   true,
+	level, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
 	}
+	root.SetLevel(level)
 
-	logger.SetOutput(lumberjackLogger)
-	logger.SetFormatter(&logrus.JSONFormatter{})
-	logger.SetLevel(logrus.InfoLevel)
+	r := NewRegistryFor(root)
+	for module, level := range cfg.ModuleLevels {
+		if err := r.SetLevel(module, level); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
 
-	params.Lifecycle.Append(fx.Hook{
-		OnStop: func(ctx context.Context) error {
-			log.Println("Closing log file")
-			return lumberjackLogger.Close()
-		},
-	})
+// NewLogger is the root logger, used by code outside a named module.
+func NewLogger(r *Registry) *logrus.Logger {
+	return r.Root()
+}
 
-	return logger
+// Named replaces *logrus.Logger with the named module logger inside an
+// fx.Module:
+//
+//	fx.Module("db", fx.Provide(NewDBProvider), fx.Decorate(logger.Named("db")))
+func Named(module string) func(*Registry) *logrus.Logger {
+	return func(r *Registry) *logrus.Logger {
+		return r.Named(module)
+	}
 }
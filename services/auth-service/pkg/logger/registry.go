@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RootModule names the root logger in level listings and updates.
+const RootModule = "root"
+
+// Registry hands out one logger per module. Module loggers share the
+// root's output, formatter and hooks (so hooks added to the root later
+// apply to them too) but keep their own level; a module without an
+// explicit level follows the root.
+type Registry struct {
+	root *logrus.Logger
+
+	mu       sync.Mutex
+	modules  map[string]*logrus.Logger
+	explicit map[string]bool
+}
+
+// NewRegistryFor builds a registry around an existing root logger.
+func NewRegistryFor(root *logrus.Logger) *Registry {
+	return &Registry{
+		root:     root,
+		modules:  map[string]*logrus.Logger{},
+		explicit: map[string]bool{},
+	}
+}
+
+func (r *Registry) Root() *logrus.Logger {
+	return r.root
+}
+
+// Named returns the logger for module, creating it on first use.
+func (r *Registry) Named(module string) *logrus.Logger {
+	if module == RootModule {
+		return r.root
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.named(module)
+}
+
+func (r *Registry) named(module string) *logrus.Logger {
+	if l, ok := r.modules[module]; ok {
+		return l
+	}
+	l := &logrus.Logger{
+		Out:          r.root.Out,
+		Formatter:    moduleFormatter{module: module, next: r.root.Formatter},
+		Hooks:        r.root.Hooks,
+		Level:        r.root.GetLevel(),
+		ExitFunc:     r.root.ExitFunc,
+		ReportCaller: r.root.ReportCaller,
+	}
+	r.modules[module] = l
+	return l
+}
+
+// SetLevel changes the level of one module, or of the root and every
+// module without an explicit level when module is RootModule.
+func (r *Registry) SetLevel(module, level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if module == RootModule {
+		r.root.SetLevel(lvl)
+		for name, l := range r.modules {
+			if !r.explicit[name] {
+				l.SetLevel(lvl)
+			}
+		}
+		return nil
+	}
+	r.named(module).SetLevel(lvl)
+	r.explicit[module] = true
+	return nil
+}
+
+// Levels lists the current level of the root and every module.
+func (r *Registry) Levels() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	levels := map[string]string{RootModule: r.root.GetLevel().String()}
+	for name, l := range r.modules {
+		levels[name] = l.GetLevel().String()
+	}
+	return levels
+}
+
+// moduleFormatter tags each entry with the module that logged it. The
+// root's hooks are shared rather than copied, so a per-module field has
+// to be added here.
+type moduleFormatter struct {
+	module string
+	next   logrus.Formatter
+}
+
+func (f moduleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry.Data["module"] = f.module
+	return f.next.Format(entry)
+}
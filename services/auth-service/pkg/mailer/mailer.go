@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Provide(NewLogMailer)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// LogMailer writes outgoing mail to the application log instead of
+// delivering it. It is the default until a real transport is configured.
+type LogMailer struct {
+	log *logrus.Logger
+}
+
+func NewLogMailer(log *logrus.Logger) Mailer {
+	return &LogMailer{log: log}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	m.log.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	}).Info("Outgoing email")
+	return nil
+}
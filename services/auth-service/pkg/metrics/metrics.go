@@ -0,0 +1,158 @@
+// Package metrics owns the Prometheus registry served on /metrics and the
+// service's own collectors. Other modules register collectors either by
+// depending on prometheus.Registerer or by providing a prometheus.Collector
+// in the "metrics_collectors" group.
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/middleware"
+	smithymiddleware "github.com/aws/smithy-go/middleware"
+	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.uber.org/fx"
+)
+
+const namespace = "auth"
+
+var Module = fx.Module("metrics",
+	fx.Provide(
+		fx.Annotate(NewRegistry, fx.As(fx.Self()), fx.As(new(prometheus.Registerer))),
+		NewHTTPMetrics,
+		NewAuthMetrics,
+		NewCognitoMetrics,
+		fx.Annotate(newDBStatsCollector, fx.ResultTags(`group:"metrics_collectors"`)),
+	),
+)
+
+type RegistryParams struct {
+	fx.In
+	Collectors []prometheus.Collector `group:"metrics_collectors"`
+}
+
+func NewRegistry(p RegistryParams) (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	for _, c := range p.Collectors {
+		if err := registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// newDBStatsCollector exports sql.DB.Stats() of the pool as go_sql_* metrics.
+func newDBStatsCollector(db *db.DB) (prometheus.Collector, error) {
+	sqlDB, err := db.Conn.DB()
+	if err != nil {
+		return nil, err
+	}
+	return collectors.NewDBStatsCollector(sqlDB, "auth"), nil
+}
+
+// HTTPMetrics records request rate, errors and duration per route.
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewHTTPMetrics(r prometheus.Registerer) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+	r.MustRegister(m.requests, m.duration)
+	return m
+}
+
+// Middleware observes every request. Errors are rendered here through the
+// app's error handler so the recorded status matches the response.
+func (m *HTTPMetrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		problem.Render(c, c.Next())
+
+		route := problem.Route(c)
+		method := c.Method()
+		m.requests.WithLabelValues(method, route, strconv.Itoa(c.Response().StatusCode())).Inc()
+		m.duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
+
+// AuthMetrics counts authentication outcomes.
+type AuthMetrics struct {
+	events *prometheus.CounterVec
+}
+
+func NewAuthMetrics(r prometheus.Registerer) *AuthMetrics {
+	m := &AuthMetrics{
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_total",
+			Help:      "Authentication attempts by event, outcome and failure reason.",
+		}, []string{"event", "outcome", "reason"}),
+	}
+	r.MustRegister(m.events)
+	return m
+}
+
+// Observe counts one attempt. reason is empty for successes.
+func (m *AuthMetrics) Observe(event, outcome, reason string) {
+	if reason == "" {
+		reason = "none"
+	}
+	m.events.WithLabelValues(event, outcome, reason).Inc()
+}
+
+// CognitoMetrics times calls made through an AWS SDK client.
+type CognitoMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+func NewCognitoMetrics(r prometheus.Registerer) *CognitoMetrics {
+	m := &CognitoMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "cognito_request_duration_seconds",
+			Help:      "Cognito API latency by operation and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "outcome"}),
+	}
+	r.MustRegister(m.duration)
+	return m
+}
+
+// AddMiddleware is an entry for a client's APIOptions. It measures the
+// whole operation, including retries.
+func (m *CognitoMetrics) AddMiddleware(stack *smithymiddleware.Stack) error {
+	return stack.Initialize.Add(smithymiddleware.InitializeMiddlewareFunc("CognitoMetrics",
+		func(ctx context.Context, in smithymiddleware.InitializeInput, next smithymiddleware.InitializeHandler) (smithymiddleware.InitializeOutput, smithymiddleware.Metadata, error) {
+			start := time.Now()
+			out, md, err := next.HandleInitialize(ctx, in)
+			outcome := "success"
+			if err != nil {
+				outcome = "error"
+			}
+			m.duration.WithLabelValues(middleware.GetOperationName(ctx), outcome).Observe(time.Since(start).Seconds())
+			return out, md, err
+		}), smithymiddleware.Before)
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMiddlewareLabelsByRouteTemplate(t *testing.T) {
+	m := NewHTTPMetrics(prometheus.NewRegistry())
+	app := fiber.New()
+	app.Use(m.Middleware())
+	app.Get("/users/:id", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/boom", func(c *fiber.Ctx) error { return fiber.ErrBadRequest })
+
+	for _, path := range []string{"/users/1", "/users/2", "/boom", "/nope/1", "/nope/2"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/users/:id", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/boom", "400")))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "unmatched", "404")))
+	require.Equal(t, 3, testutil.CollectAndCount(m.requests))
+}
@@ -0,0 +1,392 @@
+// Package migrate applies versioned, checksummed SQL migrations to
+// Postgres. Migrations run one per transaction while the migrator holds a
+// session advisory lock, so concurrent instances never migrate at once.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// lockName names the advisory lock held while migrating. The key is
+	// derived from it rather than numbered by hand, so it stays the same
+	// across releases and is unlikely to collide with other locks taken on
+	// the same database. Changing the name lets an old and a new binary
+	// migrate at the same time.
+	lockName = "auth-service:migrations"
+
+	createTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    bigint PRIMARY KEY,
+	name       text NOT NULL,
+	checksum   char(64) NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+)
+
+var (
+	ErrSchemaBehind     = errors.New("database schema is behind the embedded migrations")
+	ErrChecksumMismatch = errors.New("applied migration does not match its embedded file")
+	ErrUnknownVersion   = errors.New("database has a migration this binary does not know")
+
+	fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+	lockKey = advisoryKey(lockName)
+)
+
+// advisoryKey maps name onto Postgres' bigint advisory lock keys using the
+// first eight bytes of its SHA-256.
+func advisoryKey(name string) int64 {
+	sum := sha256.Sum256([]byte(name))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified,omitempty"`
+}
+
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Load reads <version>_<name>.up.sql and .down.sql pairs from fsys. The
+// checksum covers the up script only.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := fileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+			sum := sha256.Sum256(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", mig.Version, mig.Name)
+		}
+		if mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *logrus.Logger
+}
+
+func New(db *sql.DB, fsys fs.FS, logger *logrus.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// Latest is the highest embedded version, or 0 when there are none.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		statuses = m.status(done)
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) status(done map[int64]applied) []Status {
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if a, ok := done[mig.Version]; ok {
+			at := a.appliedAt
+			s.Applied = true
+			s.AppliedAt = &at
+			s.Modified = a.checksum != mig.Checksum
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// Up applies pending migrations up to and including target. A target of 0
+// means the latest version.
+func (m *Migrator) Up(ctx context.Context, target int64) error {
+	if target == 0 {
+		target = m.Latest()
+	}
+	return m.withLock(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		if err := m.verify(done); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > target {
+				break
+			}
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back applied migrations newer than target, newest first.
+func (m *Migrator) Down(ctx context.Context, target int64) error {
+	return m.withLock(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		if err := m.verify(done); err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if mig.Version <= target {
+				break
+			}
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Previous returns the version below the newest applied one, for rolling
+// back a single step.
+func (m *Migrator) Previous(ctx context.Context) (int64, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var current, previous int64
+	for _, s := range statuses {
+		if s.Applied {
+			previous, current = current, s.Version
+		}
+	}
+	if current == 0 {
+		return 0, errors.New("no migrations are applied")
+	}
+	return previous, nil
+}
+
+// EnsureCurrent fails unless every embedded migration is applied unchanged.
+func (m *Migrator) EnsureCurrent(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		if err := m.verify(done); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; !ok {
+				return fmt.Errorf("%w: %d_%s is pending", ErrSchemaBehind, mig.Version, mig.Name)
+			}
+		}
+		return nil
+	})
+}
+
+// Ready is the read-only counterpart of EnsureCurrent for readiness
+// probes: it takes no lock and creates nothing. Versions newer than any
+// embedded migration are accepted, since during a rolling deploy the new
+// release migrates while the old one is still serving.
+func (m *Migrator) Ready(ctx context.Context) error {
+	done, err := readApplied(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	return m.ready(done)
+}
+
+func (m *Migrator) ready(done map[int64]applied) error {
+	latest := m.Latest()
+	for version := range done {
+		if version > latest {
+			delete(done, version)
+		}
+	}
+	if err := m.verify(done); err != nil {
+		return err
+	}
+	for _, mig := range m.migrations {
+		if _, ok := done[mig.Version]; !ok {
+			return fmt.Errorf("%w: %d_%s is pending", ErrSchemaBehind, mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) verify(done map[int64]applied) error {
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+	for version, a := range done {
+		mig, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: %d_%s", ErrUnknownVersion, version, a.name)
+		}
+		if a.checksum != mig.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, mig.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool) error {
+	direction, script := "up", mig.Up
+	if !up {
+		direction, script = "down", mig.Down
+	}
+	start := time.Now()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+	if up {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+			mig.Version, mig.Name, mig.Checksum)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"version":   mig.Version,
+		"name":      mig.Name,
+		"direction": direction,
+		"duration":  time.Since(start).String(),
+	}).Info("Migration applied")
+	return nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, done map[int64]applied) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			m.logger.WithError(err).Warn("Failed to release migration lock")
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createTableSQL); err != nil {
+		return err
+	}
+	done, err := readApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func readApplied(ctx context.Context, q querier) (map[int64]applied, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := map[int64]applied{}
+	for rows.Next() {
+		var version int64
+		var a applied
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = a
+	}
+	return done, rows.Err()
+}
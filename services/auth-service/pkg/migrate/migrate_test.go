@@ -0,0 +1,181 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/content-management-system/auth-service/migrations"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrdersAndChecksumsMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("CREATE INDEX idx ON t (c);")},
+		"0002_add_index.down.sql": {Data: []byte("DROP INDEX idx;")},
+		"0001_baseline.up.sql":    {Data: []byte("CREATE TABLE t (c int);")},
+		"0001_baseline.down.sql":  {Data: []byte("DROP TABLE t;")},
+		"README.md":               {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	require.Equal(t, int64(1), migrations[0].Version)
+	require.Equal(t, "baseline", migrations[0].Name)
+	require.Equal(t, "DROP TABLE t;", migrations[0].Down)
+	require.Equal(t, int64(2), migrations[1].Version)
+	require.Len(t, migrations[0].Checksum, 64)
+	require.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+}
+
+func TestLoadRejectsIncompleteMigrations(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"0001_baseline.up.sql": {Data: []byte("CREATE TABLE t (c int);")},
+	})
+	require.ErrorContains(t, err, "no down script")
+
+	_, err = Load(fstest.MapFS{
+		"0001_baseline.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+		"0001_other.down.sql":    {Data: []byte("DROP TABLE t;")},
+		"0001_baseline.down.sql": {Data: []byte("DROP TABLE t;")},
+	})
+	require.ErrorContains(t, err, "conflicting names")
+}
+
+func TestVerifyDetectsDrift(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0001_baseline.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+		"0001_baseline.down.sql": {Data: []byte("DROP TABLE t;")},
+	})
+	require.NoError(t, err)
+	m := &Migrator{migrations: migrations}
+
+	require.NoError(t, m.verify(map[int64]applied{1: {name: "baseline", checksum: migrations[0].Checksum}}))
+	require.ErrorIs(t, m.verify(map[int64]applied{1: {name: "baseline", checksum: "edited"}}), ErrChecksumMismatch)
+	require.ErrorIs(t, m.verify(map[int64]applied{9: {name: "future"}}), ErrUnknownVersion)
+}
+
+func TestReadyAcceptsNewerSchema(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0001_baseline.up.sql":    {Data: []byte("CREATE TABLE t (c int);")},
+		"0001_baseline.down.sql":  {Data: []byte("DROP TABLE t;")},
+		"0003_add_index.up.sql":   {Data: []byte("CREATE INDEX idx ON t (c);")},
+		"0003_add_index.down.sql": {Data: []byte("DROP INDEX idx;")},
+	})
+	require.NoError(t, err)
+	m := &Migrator{migrations: migrations}
+	baseline := applied{name: "baseline", checksum: migrations[0].Checksum}
+	index := applied{name: "add_index", checksum: migrations[1].Checksum}
+
+	require.NoError(t, m.ready(map[int64]applied{1: baseline, 3: index}))
+	require.NoError(t, m.ready(map[int64]applied{1: baseline, 3: index, 4: {name: "next_release"}}))
+	require.ErrorIs(t, m.ready(map[int64]applied{1: baseline}), ErrSchemaBehind)
+	require.ErrorIs(t, m.ready(map[int64]applied{1: baseline, 2: {name: "stray"}, 3: index}), ErrUnknownVersion)
+	require.ErrorIs(t, m.ready(map[int64]applied{1: {name: "baseline", checksum: "edited"}, 3: index}), ErrChecksumMismatch)
+}
+
+func TestLockKeyIsStable(t *testing.T) {
+	// Instances of different releases must agree on the key, so it may only
+	// change together with lockName.
+	require.Equal(t, int64(-1787689507935883803), lockKey)
+}
+
+// testDB connects to TEST_DATABASE_DSN with a fresh schema first on the
+// search path, so migrating up and down leaves other tests' tables alone.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	admin, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = admin.Close() })
+
+	schema := "migrate_" + uuid.NewString()[:8]
+	_, err = admin.Exec(fmt.Sprintf("CREATE SCHEMA %q", schema))
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = admin.Exec(fmt.Sprintf("DROP SCHEMA %q CASCADE", schema)) })
+
+	cfg, err := pgx.ParseConfig(dsn)
+	require.NoError(t, err)
+	cfg.RuntimeParams["search_path"] = schema
+	db := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestUpDownRoundTrip(t *testing.T) {
+	db := testDB(t)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	m, err := New(db, migrations.FS, log)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	applied := func() (n int) {
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		for _, s := range statuses {
+			require.False(t, s.Modified)
+			if s.Applied {
+				n++
+			}
+		}
+		return n
+	}
+
+	require.NoError(t, m.Up(ctx, 0))
+	require.NoError(t, m.EnsureCurrent(ctx))
+	require.Equal(t, len(m.migrations), applied())
+
+	require.NoError(t, m.Down(ctx, 0))
+	require.Zero(t, applied())
+	require.ErrorIs(t, m.EnsureCurrent(ctx), ErrSchemaBehind)
+
+	// Every down script must leave the schema clean enough to go up again.
+	require.NoError(t, m.Up(ctx, 0))
+	require.Equal(t, len(m.migrations), applied())
+}
+
+func TestUserPermissionsAreGrantedToAdministrators(t *testing.T) {
+	db := testDB(t)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	m, err := New(db, migrations.FS, log)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, m.Up(ctx, 4))
+	_, err = db.ExecContext(ctx, "INSERT INTO roles (name) VALUES ('Administrator'), ('Customer')")
+	require.NoError(t, err)
+	require.NoError(t, m.Up(ctx, 5))
+
+	granted := func(role string) (names []string) {
+		rows, err := db.QueryContext(ctx, `
+			SELECT p.name FROM role_permissions rp
+			JOIN roles r ON r.id = rp.role_id
+			JOIN permissions p ON p.id = rp.permission_id
+			WHERE r.name = $1 ORDER BY p.name`, role)
+		require.NoError(t, err)
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		require.NoError(t, rows.Err())
+		return names
+	}
+	require.Equal(t, []string{"users.manage", "users.read"}, granted("Administrator"))
+	require.Empty(t, granted("Customer"))
+}
@@ -0,0 +1,103 @@
+// Package model holds the persistence models shared by the auth service
+// and the seeding tool, so both agree on keys, defaults and constraints.
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	RoleAdministrator = "Administrator"
+	RoleCustomer      = "Customer"
+
+	// Permissions checked by the service. Roles are granted them by the
+	// seeding fixtures.
+	PermissionUsersRead   = "users.read"
+	PermissionUsersManage = "users.manage"
+)
+
+type Role struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Name      string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Users       []User       `gorm:"foreignKey:RoleID" json:"users,omitempty"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions,omitempty"`
+}
+
+// HasPermission reports whether the role grants name. Permissions must have
+// been loaded with the role.
+func (r Role) HasPermission(name string) bool {
+	for _, p := range r.Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Role) BeforeCreate(*gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+type Permission struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Name        string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (p *Permission) BeforeCreate(*gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// User is unique by username and, regardless of case, by email:
+// EmailNormalized is generated by the database as lower(email) and carries
+// the unique index.
+type User struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	Username         string         `gorm:"type:varchar(255);not null;uniqueIndex" json:"username"`
+	Password         string         `gorm:"type:varchar(255);not null" json:"-"`
+	Email            string         `gorm:"type:varchar(255);not null" json:"email"`
+	EmailNormalized  string         `gorm:"->;type:varchar(255);uniqueIndex" json:"-"`
+	Name             string         `gorm:"type:varchar(255)" json:"name"`
+	RoleID           uuid.UUID      `gorm:"type:uuid;not null" json:"role_id"`
+	RegistrationDate time.Time      `json:"registration_date"`
+	Address          string         `gorm:"type:text" json:"address"`
+	PhoneNumber      string         `gorm:"type:varchar(255)" json:"phone_number"`
+	PhoneVerifiedAt  *time.Time     `json:"phone_verified_at,omitempty"`
+	MFAEnabled       bool           `gorm:"not null;default:false" json:"mfa_enabled"`
+	AvatarURL        string         `gorm:"type:varchar(512)" json:"avatar_url"`
+	Status           string         `gorm:"type:varchar(32);not null;default:active;index" json:"status"`
+	StatusReason     string         `gorm:"type:text" json:"status_reason,omitempty"`
+	StatusChangedAt  *time.Time     `json:"status_changed_at,omitempty"`
+	PurgeAfter       *time.Time     `json:"purge_after,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	Role Role `gorm:"foreignKey:RoleID" json:"role"`
+}
+
+// BeforeCreate assigns an ID and fills the registration date, so every
+// code path that inserts a user gets the same defaults.
+func (u *User) BeforeCreate(*gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	if u.RegistrationDate.IsZero() {
+		u.RegistrationDate = time.Now()
+	}
+	return nil
+}
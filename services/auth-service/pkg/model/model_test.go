@@ -0,0 +1,30 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeforeCreateAssignsIDs(t *testing.T) {
+	role := Role{Name: RoleCustomer}
+	require.NoError(t, role.BeforeCreate(nil))
+	require.NotEqual(t, uuid.Nil, role.ID)
+
+	user := User{Username: "jane"}
+	require.NoError(t, user.BeforeCreate(nil))
+	require.NotEqual(t, uuid.Nil, user.ID)
+	require.False(t, user.RegistrationDate.IsZero())
+
+	id := uuid.New()
+	preset := User{ID: id}
+	require.NoError(t, preset.BeforeCreate(nil))
+	require.Equal(t, id, preset.ID)
+}
+
+func TestRoleHasPermission(t *testing.T) {
+	role := Role{Permissions: []Permission{{Name: PermissionUsersRead}}}
+	require.True(t, role.HasPermission(PermissionUsersRead))
+	require.False(t, role.HasPermission(PermissionUsersManage))
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserResponse struct {
+	Id       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+}
+
+func (u *User) ToResponse() *UserResponse {
+	return &UserResponse{
+		Id:       u.ID,
+		Username: u.Username,
+		Email:    u.Email,
+	}
+}
+
+type ProfileResponse struct {
+	*UserResponse
+	Name             string    `json:"name"`
+	Address          string    `json:"address"`
+	PhoneNumber      string    `json:"phone_number"`
+	PhoneVerified    bool      `json:"phone_verified"`
+	MFAEnabled       bool      `json:"mfa_enabled"`
+	AvatarURL        string    `json:"avatar_url"`
+	Role             string    `json:"role"`
+	RegistrationDate time.Time `json:"registration_date"`
+}
+
+func (u *User) ToProfileResponse() *ProfileResponse {
+	return &ProfileResponse{
+		UserResponse:     u.ToResponse(),
+		Name:             u.Name,
+		Address:          u.Address,
+		PhoneNumber:      u.PhoneNumber,
+		PhoneVerified:    u.PhoneVerifiedAt != nil,
+		MFAEnabled:       u.MFAEnabled,
+		AvatarURL:        u.AvatarURL,
+		Role:             u.Role.Name,
+		RegistrationDate: u.RegistrationDate,
+	}
+}
@@ -0,0 +1,165 @@
+package problem
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ContentType     = "application/problem+json"
+	RequestIDHeader = "X-Request-ID"
+	RequestIDLocal  = "requestid"
+)
+
+var statusByCode = map[apperror.Code]int{
+	apperror.CodeBadRequest:         fiber.StatusBadRequest,
+	apperror.CodeMalformedRequest:   fiber.StatusBadRequest,
+	apperror.CodeValidationFailed:   fiber.StatusUnprocessableEntity,
+	apperror.CodeUnauthorized:       fiber.StatusUnauthorized,
+	apperror.CodeForbidden:          fiber.StatusForbidden,
+	apperror.CodeNotFound:           fiber.StatusNotFound,
+	apperror.CodeConflict:           fiber.StatusConflict,
+	apperror.CodeInternal:           fiber.StatusInternalServerError,
+	apperror.CodeUserNotFound:       fiber.StatusNotFound,
+	apperror.CodeEmailTaken:         fiber.StatusConflict,
+	apperror.CodeUsernameTaken:      fiber.StatusConflict,
+	apperror.CodeInvalidCredentials: fiber.StatusUnauthorized,
+	apperror.CodeInvalidToken:       fiber.StatusUnauthorized,
+	apperror.CodeRoleNotFound:       fiber.StatusNotFound,
+	apperror.CodeInvalidInviteCode:  fiber.StatusBadRequest,
+	apperror.CodeInvalidInvitation:  fiber.StatusBadRequest,
+	apperror.CodeInvitationExpired:  fiber.StatusGone,
+	apperror.CodeInvitationPending:  fiber.StatusConflict,
+	apperror.CodeInvalidOTP:         fiber.StatusBadRequest,
+	apperror.CodeOTPLocked:          fiber.StatusTooManyRequests,
+	apperror.CodeOTPRateLimited:     fiber.StatusTooManyRequests,
+	apperror.CodePhoneNotVerified:   fiber.StatusBadRequest,
+	apperror.CodeAccountInactive:    fiber.StatusForbidden,
+	apperror.CodeInvalidTransition:  fiber.StatusConflict,
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object, extended with a stable
+// error code and the ID of the request that produced it.
+type Problem struct {
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Status    int           `json:"status"`
+	Code      apperror.Code `json:"code"`
+	Detail    string        `json:"detail,omitempty"`
+	Instance  string        `json:"instance,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Errors    []FieldError  `json:"errors,omitempty"`
+}
+
+func New(code apperror.Code, detail string) *Problem {
+	status, ok := statusByCode[code]
+	if !ok {
+		status = fiber.StatusInternalServerError
+	}
+	return &Problem{
+		Type:   "about:blank",
+		Title:  utils.StatusMessage(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) Send(c *fiber.Ctx) error {
+	if p.Instance == "" {
+		p.Instance = c.Path()
+	}
+	if p.RequestID == "" {
+		p.RequestID = RequestID(c)
+	}
+	return c.Status(p.Status).JSON(p, ContentType)
+}
+
+func RequestID(c *fiber.Ctx) string {
+	if id, ok := c.Locals(RequestIDLocal).(string); ok {
+		return id
+	}
+	return ""
+}
+
+func ErrorHandler(log *logrus.Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		var p *Problem
+		var appErr *apperror.Error
+		var fiberErr *fiber.Error
+		switch {
+		case errors.As(err, &p):
+		case errors.As(err, &appErr):
+			p = New(appErr.Code, appErr.Message)
+			if p.Status >= fiber.StatusInternalServerError {
+				log.WithContext(c.UserContext()).WithError(err).WithField("request_id", RequestID(c)).Error("Request failed")
+				p.Detail = ""
+			}
+		case errors.As(err, &fiberErr):
+			p = fromStatus(fiberErr.Code, fiberErr.Message)
+		default:
+			log.WithContext(c.UserContext()).WithError(err).WithField("request_id", RequestID(c)).Error("Unhandled request error")
+			p = New(apperror.CodeInternal, "")
+		}
+		return p.Send(c)
+	}
+}
+
+func fromStatus(status int, detail string) *Problem {
+	code := apperror.Code(strings.ToUpper(strings.ReplaceAll(utils.StatusMessage(status), " ", "_")))
+	if code == "" {
+		code = apperror.CodeInternal
+	}
+	return &Problem{
+		Type:   "about:blank",
+		Title:  utils.StatusMessage(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// UnmatchedRoute labels requests that matched no route, so that arbitrary
+// paths do not each become a metric label or span name.
+const UnmatchedRoute = "unmatched"
+
+// Render sends err, if any, through the app's error handler. Middleware
+// that reports on the response calls it so the status it records is the
+// one the client receives.
+func Render(c *fiber.Ctx, err error) {
+	if err == nil {
+		return
+	}
+	if err := c.App().Config().ErrorHandler(c, err); err != nil {
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+	}
+}
+
+// Route returns the template of the route that handled c, or
+// UnmatchedRoute. Fiber reports unmatched requests as the root path, so a
+// 404 on "/" is taken to mean no route matched.
+func Route(c *fiber.Ctx) string {
+	route := c.Route().Path
+	if c.Response().StatusCode() == fiber.StatusNotFound && route == "/" {
+		return UnmatchedRoute
+	}
+	return route
+}
@@ -0,0 +1,67 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandlerMapsErrors(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(logrus.New())})
+	app.Use(requestid.New(requestid.Config{Header: RequestIDHeader, ContextKey: RequestIDLocal}))
+	app.Get("/missing", func(c *fiber.Ctx) error {
+		return apperror.Wrap(apperror.CodeUserNotFound, "user not found", errors.New("record not found"))
+	})
+	app.Get("/boom", func(c *fiber.Ctx) error {
+		return errors.New("pq: password authentication failed for user \"postgres\"")
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		code   apperror.Code
+		detail string
+	}{
+		{"/missing", fiber.StatusNotFound, apperror.CodeUserNotFound, "user not found"},
+		{"/boom", fiber.StatusInternalServerError, apperror.CodeInternal, ""},
+		{"/nope", fiber.StatusNotFound, apperror.Code("NOT_FOUND"), "Cannot GET /nope"},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		require.NoError(t, err)
+		require.Equal(t, tt.status, resp.StatusCode)
+
+		var p Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+		require.Equal(t, tt.code, p.Code)
+		require.Equal(t, tt.detail, p.Detail)
+		require.NotEmpty(t, p.RequestID)
+		require.Equal(t, resp.Header.Get(RequestIDHeader), p.RequestID)
+	}
+}
+
+func TestRouteLabelsUnmatchedRequests(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(logrus.New())})
+	var routes []string
+	app.Use(func(c *fiber.Ctx) error {
+		Render(c, c.Next())
+		routes = append(routes, Route(c))
+		return nil
+	})
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/users/:id", func(c *fiber.Ctx) error { return apperror.ErrUserNotFound })
+
+	for _, path := range []string{"/", "/users/42", "/nope"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	require.Equal(t, []string{"/", "/users/:id", UnmatchedRoute}, routes)
+}
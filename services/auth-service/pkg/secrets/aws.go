@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smTypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// SecretsManagerProvider reads plain-text secrets from AWS Secrets Manager,
+// stored under prefix followed by the secret name.
+type SecretsManagerProvider struct {
+	client *secretsmanager.Client
+	prefix string
+}
+
+func NewSecretsManagerProvider(cfg aws.Config, prefix string) *SecretsManagerProvider {
+	return &SecretsManagerProvider{client: secretsmanager.NewFromConfig(cfg), prefix: prefix}
+}
+
+func (p *SecretsManagerProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	out, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(p.prefix + name),
+	})
+	var notFound *smTypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, p.prefix+name)
+	}
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("secret %s is binary, expected a string", p.prefix+name)
+	}
+	return *out.SecretString, nil
+}
+
+// SSMProvider reads SecureString parameters from the SSM Parameter Store,
+// stored under prefix followed by the secret name.
+type SSMProvider struct {
+	client *ssm.Client
+	prefix string
+}
+
+func NewSSMProvider(cfg aws.Config, prefix string) *SSMProvider {
+	return &SSMProvider{client: ssm.NewFromConfig(cfg), prefix: prefix}
+}
+
+func (p *SSMProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	out, err := p.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(p.prefix + name),
+		WithDecryption: aws.Bool(true),
+	})
+	var notFound *ssmTypes.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, p.prefix+name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.Parameter.Value), nil
+}
@@ -0,0 +1,231 @@
+// Package secrets fetches credentials such as the database password and
+// token signing key from a pluggable backend and caches them, so rotated
+// values are picked up without a restart.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+// Names of the secrets the service reads.
+const (
+	DBPassword          = "DB_PASSWORD"
+	JWTSigningKey       = "JWT_SECRET"
+	CognitoClientSecret = "COGNITO_CLIENT_SECRET"
+)
+
+// ErrSecretNotFound is returned when the backend has no secret by that name.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Module provides the Store backed by the named provider (SECRETS_PROVIDER).
+// Only the AWS backends depend on the shared aws.Config, so an AWS region
+// is only required when one of them is selected.
+func Module(provider string) fx.Option {
+	return fx.Options(fx.Provide(newManagedStore), providerFor(provider))
+}
+
+func providerFor(name string) fx.Option {
+	switch name {
+	case "", "env":
+		return fx.Provide(func() SecretsProvider { return EnvProvider{} })
+	case "file":
+		return fx.Provide(func(opts Options) SecretsProvider { return NewFileProvider(opts.Dir) })
+	case "secretsmanager":
+		return fx.Provide(func(cfg aws.Config, opts Options) SecretsProvider {
+			return NewSecretsManagerProvider(cfg, opts.Prefix)
+		})
+	case "ssm":
+		return fx.Provide(func(cfg aws.Config, opts Options) SecretsProvider {
+			return NewSSMProvider(cfg, opts.Prefix)
+		})
+	default:
+		return fx.Error(fmt.Errorf("unknown SECRETS_PROVIDER %q", name))
+	}
+}
+
+// Options configure the providers: the file provider reads from Dir and
+// the AWS ones look names up under Prefix. Fetched values are cached for
+// TTL.
+type Options struct {
+	Dir    string
+	Prefix string
+	TTL    time.Duration
+}
+
+type SecretsProvider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// EnvProvider reads each secret from the environment variable of the same
+// name, including values loaded from .env.
+type EnvProvider struct{}
+
+func (EnvProvider) GetSecret(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// FileProvider reads each secret from a file named after it, which is how
+// Docker and Kubernetes mount secrets.
+type FileProvider struct {
+	dir string
+}
+
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir}
+}
+
+func (p *FileProvider) GetSecret(_ context.Context, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(p.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+type entry struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// Store caches secrets for a TTL. Values that change on refresh are passed
+// to the watchers registered for them, which is how long-lived clients
+// such as the database pool learn about a rotation.
+type Store struct {
+	provider SecretsProvider
+	ttl      time.Duration
+	log      *logrus.Logger
+	now      func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]entry
+	watchers map[string][]func(string)
+}
+
+func NewStore(provider SecretsProvider, ttl time.Duration, log *logrus.Logger) *Store {
+	return &Store{
+		provider: provider,
+		ttl:      ttl,
+		log:      log,
+		now:      time.Now,
+		entries:  map[string]entry{},
+		watchers: map[string][]func(string){},
+	}
+}
+
+// newManagedStore refreshes watched secrets every TTL while the app runs.
+func newManagedStore(lc fx.Lifecycle, opts Options, provider SecretsProvider, log *logrus.Logger) *Store {
+	s := NewStore(provider, opts.TTL, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(s.ttl)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						s.Refresh(ctx)
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+	return s
+}
+
+// Get returns the cached value while it is fresh and fetches it otherwise.
+// When a refetch fails the stale value is kept, so a backend outage does
+// not take down callers that already had the secret.
+func (s *Store) Get(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	cached, ok := s.entries[name]
+	s.mu.Unlock()
+	if ok && s.now().Sub(cached.fetchedAt) < s.ttl {
+		return cached.value, nil
+	}
+
+	value, err := s.fetch(ctx, name)
+	if err != nil {
+		if ok && !errors.Is(err, ErrSecretNotFound) {
+			s.log.WithError(err).WithField("secret", name).Warn("Failed to refresh secret, using cached value")
+			return cached.value, nil
+		}
+		return "", err
+	}
+	return value, nil
+}
+
+// Watch calls fn with the new value whenever the secret changes.
+func (s *Store) Watch(name string, fn func(value string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchers[name] = append(s.watchers[name], fn)
+}
+
+// Refresh refetches every watched secret regardless of its age.
+func (s *Store) Refresh(ctx context.Context) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.watchers))
+	for name := range s.watchers {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	for _, name := range names {
+		if _, err := s.fetch(ctx, name); err != nil {
+			s.log.WithError(err).WithField("secret", name).Warn("Failed to refresh secret")
+		}
+	}
+}
+
+func (s *Store) fetch(ctx context.Context, name string) (string, error) {
+	value, err := s.provider.GetSecret(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	previous, seen := s.entries[name]
+	s.entries[name] = entry{value: value, fetchedAt: s.now()}
+	var notify []func(string)
+	if seen && previous.value != value {
+		notify = append(notify, s.watchers[name]...)
+	}
+	s.mu.Unlock()
+
+	if len(notify) > 0 {
+		s.log.WithField("secret", name).Info("Secret rotated")
+	}
+	for _, fn := range notify {
+		fn(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,130 @@
+package secrets
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+)
+
+func writeSecret(t *testing.T, dir, name, value string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(value), 0o600))
+}
+
+func newTestStore(t *testing.T, dir string, ttl time.Duration) (*Store, *time.Time) {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	store := NewStore(NewFileProvider(dir), ttl, log)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestFileProviderReadsMountedSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret(t, dir, DBPassword, "s3cret\n")
+	provider := NewFileProvider(dir)
+
+	value, err := provider.GetSecret(context.Background(), DBPassword)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", value)
+
+	_, err = provider.GetSecret(context.Background(), JWTSigningKey)
+	require.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestStoreRefreshesAfterTTLAndNotifiesWatchers(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret(t, dir, DBPassword, "first")
+	store, now := newTestStore(t, dir, time.Minute)
+
+	var rotated []string
+	store.Watch(DBPassword, func(value string) { rotated = append(rotated, value) })
+
+	value, err := store.Get(context.Background(), DBPassword)
+	require.NoError(t, err)
+	require.Equal(t, "first", value)
+
+	writeSecret(t, dir, DBPassword, "second")
+	value, err = store.Get(context.Background(), DBPassword)
+	require.NoError(t, err)
+	require.Equal(t, "first", value, "cached value is served until the TTL expires")
+	require.Empty(t, rotated)
+
+	*now = now.Add(time.Minute)
+	value, err = store.Get(context.Background(), DBPassword)
+	require.NoError(t, err)
+	require.Equal(t, "second", value)
+	require.Equal(t, []string{"second"}, rotated)
+
+	writeSecret(t, dir, DBPassword, "third")
+	store.Refresh(context.Background())
+	require.Equal(t, []string{"second", "third"}, rotated)
+}
+
+func TestStoreServesStaleValueWhenRefreshFails(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret(t, dir, JWTSigningKey, "signing-key")
+	store, now := newTestStore(t, dir, time.Minute)
+
+	_, err := store.Get(context.Background(), JWTSigningKey)
+	require.NoError(t, err)
+
+	// A directory in place of the file makes the read fail.
+	require.NoError(t, os.Remove(filepath.Join(dir, JWTSigningKey)))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, JWTSigningKey), 0o700))
+	*now = now.Add(time.Hour)
+
+	value, err := store.Get(context.Background(), JWTSigningKey)
+	require.NoError(t, err)
+	require.Equal(t, "signing-key", value)
+}
+
+func TestModuleSelectsProvider(t *testing.T) {
+	cases := []struct {
+		name string
+		want SecretsProvider
+	}{
+		{"", EnvProvider{}},
+		{"env", EnvProvider{}},
+		{"file", &FileProvider{}},
+		{"secretsmanager", &SecretsManagerProvider{}},
+		{"ssm", &SSMProvider{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got SecretsProvider
+			app := fx.New(
+				fx.NopLogger,
+				fx.Supply(Options{Dir: t.TempDir(), TTL: time.Minute}, logrus.New(), aws.Config{Region: "eu-west-1"}),
+				Module(tc.name),
+				fx.Populate(&got),
+			)
+			require.NoError(t, app.Err())
+			require.IsType(t, tc.want, got)
+		})
+	}
+}
+
+func TestModuleOnlyNeedsAWSConfigForAWSProviders(t *testing.T) {
+	build := func(provider string) error {
+		return fx.New(
+			fx.NopLogger,
+			fx.Supply(Options{TTL: time.Minute}, logrus.New()),
+			Module(provider),
+			fx.Invoke(func(*Store) {}),
+		).Err()
+	}
+	require.NoError(t, build("env"))
+	require.ErrorContains(t, build("ssm"), "aws.Config")
+	require.ErrorContains(t, build("vault"), `unknown SECRETS_PROVIDER "vault"`)
+}
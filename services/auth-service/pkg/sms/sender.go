@@ -0,0 +1,76 @@
+package sms
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Provide(NewSMSSender)
+
+type Options struct {
+	Sender   string
+	FilePath string
+}
+
+type SMSSender interface {
+	Send(to, message string) error
+}
+
+// NewSMSSender picks the sender from SMS_SENDER ("log" or "file"). Neither
+// delivers a real SMS; a gateway-backed sender can be added behind the same
+// interface.
+func NewSMSSender(opts Options, log *logrus.Logger) (SMSSender, error) {
+	switch opts.Sender {
+	case "", "log":
+		return &LogSender{log: log}, nil
+	case "file":
+		return &FileSender{path: opts.FilePath}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS_SENDER %q", opts.Sender)
+	}
+}
+
+type LogSender struct {
+	log *logrus.Logger
+}
+
+func (s *LogSender) Send(to, message string) error {
+	s.log.WithFields(logrus.Fields{
+		"to":      to,
+		"message": message,
+	}).Info("Outgoing SMS")
+	return nil
+}
+
+// FileSender appends every message to a file, which is handy for local
+// development and end-to-end tests that need to read the code back.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(to, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), to, message)
+	return err
+}
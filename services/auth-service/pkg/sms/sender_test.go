@@ -0,0 +1,31 @@
+package sms
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSenderAppendsMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "sms.log")
+	sender := NewFileSender(path)
+
+	require.NoError(t, sender.Send("+14155550100", "code 123456"))
+	require.NoError(t, sender.Send("+14155550101", "code 654321"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasSuffix(lines[0], "\t+14155550100\tcode 123456"))
+	require.True(t, strings.HasSuffix(lines[1], "\t+14155550101\tcode 654321"))
+}
+
+func TestNewSMSSenderRejectsUnknownSender(t *testing.T) {
+	_, err := NewSMSSender(Options{Sender: "carrier-pigeon"}, logrus.New())
+	require.Error(t, err)
+}
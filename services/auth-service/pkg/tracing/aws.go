@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	smithymiddleware "github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AWSMiddleware returns an entry for an AWS SDK client's APIOptions that
+// wraps each operation, retries included, in a client span named
+// Service.Operation.
+func AWSMiddleware(tp trace.TracerProvider) func(*smithymiddleware.Stack) error {
+	tracer := tp.Tracer(instrumentationName)
+	return func(stack *smithymiddleware.Stack) error {
+		return stack.Initialize.Add(smithymiddleware.InitializeMiddlewareFunc("Tracing",
+			func(ctx context.Context, in smithymiddleware.InitializeInput, next smithymiddleware.InitializeHandler) (smithymiddleware.InitializeOutput, smithymiddleware.Metadata, error) {
+				service := awsmiddleware.GetServiceID(ctx)
+				operation := awsmiddleware.GetOperationName(ctx)
+				ctx, span := tracer.Start(ctx, service+"."+operation,
+					trace.WithSpanKind(trace.SpanKindClient),
+					trace.WithAttributes(
+						semconv.RPCSystemKey.String("aws-api"),
+						semconv.RPCService(service),
+						semconv.RPCMethod(operation),
+					),
+				)
+				defer span.End()
+
+				out, md, err := next.HandleInitialize(ctx, in)
+				if requestID, ok := awsmiddleware.GetRequestIDMetadata(md); ok {
+					span.SetAttributes(attribute.String("aws.request_id", requestID))
+				}
+				if err != nil {
+					span.RecordError(err)
+					span.SetStatus(codes.Error, err.Error())
+				}
+				return out, md, err
+			}), smithymiddleware.Before)
+	}
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span for every request, continuing the trace
+// from an incoming traceparent header, and hands its context to handlers
+// through c.UserContext(). Errors are rendered here so the recorded
+// status matches the response.
+func Middleware(tp trace.TracerProvider) fiber.Handler {
+	tracer := tp.Tracer(instrumentationName)
+	return func(c *fiber.Ctx) error {
+		ctx := Propagator.Extract(c.UserContext(), headerCarrier{c})
+		method := c.Method()
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(c.Path()),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		problem.Render(c, c.Next())
+
+		status := c.Response().StatusCode()
+		if route := problem.Route(c); route != problem.UnmatchedRoute {
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return nil
+	}
+}
+
+// headerCarrier exposes the request headers to the propagator.
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := []string{}
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,115 @@
+// Package tracing sets up OpenTelemetry for the service: the tracer
+// provider and exporter, W3C trace context propagation, and the Fiber,
+// GORM and AWS SDK instrumentation that spans a request end to end.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/content-management-system/auth-service/pkg/db"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+)
+
+const instrumentationName = "github.com/content-management-system/auth-service/pkg/tracing"
+
+// Propagator reads and writes W3C traceparent, tracestate and baggage.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+var Module = fx.Module("tracing",
+	fx.Provide(
+		fx.Annotate(NewTracerProvider, fx.As(new(trace.TracerProvider))),
+	),
+	fx.Invoke(func(log *logrus.Logger) { log.AddHook(LogHook{}) }),
+)
+
+// Options selects where spans go. With Exporter "none" spans are still
+// created, so trace IDs reach logs and downstream services, but nothing is
+// exported.
+type Options struct {
+	Exporter     string
+	OTLPEndpoint string
+	ServiceName  string
+}
+
+// NewTracerProvider builds the provider for OTEL_TRACES_EXPORTER, installs
+// it globally and instruments the database. Spans still buffered are
+// flushed on stop; depending on the database orders that before the
+// database is closed.
+func NewTracerProvider(lc fx.Lifecycle, opts Options, d *db.DB) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(opts)
+	if err != nil {
+		return nil, err
+	}
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(newResource(opts.ServiceName)),
+	}
+	if exporter != nil {
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(exporter))
+	}
+	tp := sdktrace.NewTracerProvider(tpOpts...)
+	if err := d.Conn.Use(NewGormPlugin(tp)); err != nil {
+		return nil, err
+	}
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(Propagator)
+	lc.Append(fx.Hook{OnStop: tp.Shutdown})
+	return tp, nil
+}
+
+func newExporter(cfg Options) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		return stdouttrace.New()
+	case "otlp":
+		opts := []otlptracehttp.Option{}
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		// The exporter connects lazily, so this does not block on the collector.
+		return otlptracehttp.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", cfg.Exporter)
+	}
+}
+
+func newResource(serviceName string) *resource.Resource {
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return resource.Default()
+	}
+	return res
+}
+
+// LogHook adds trace_id and span_id to entries logged WithContext inside a
+// span, so log lines can be joined to their trace.
+type LogHook struct{}
+
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (LogHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	sc := trace.SpanContextFromContext(entry.Context)
+	if !sc.IsValid() {
+		return nil
+	}
+	entry.Data["trace_id"] = sc.TraceID().String()
+	entry.Data["span_id"] = sc.SpanID().String()
+	return nil
+}
@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func TestMiddlewareContinuesIncomingTraceAndTagsLogs(t *testing.T) {
+	tp, exporter := newTestProvider()
+
+	var out bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&out)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	log.AddHook(LogHook{})
+
+	app := fiber.New()
+	app.Use(Middleware(tp))
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		log.WithContext(c.UserContext()).Info("handled")
+		return c.SendString("ok")
+	})
+	app.Get("/boom", func(c *fiber.Ctx) error { return fiber.ErrServiceUnavailable })
+
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	resp, err = app.Test(httptest.NewRequest("GET", "/boom", nil))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	users := spans[0]
+	require.Equal(t, "GET /users/:id", users.Name)
+	require.Equal(t, trace.SpanKindServer, users.SpanKind)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", users.SpanContext.TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", users.Parent.SpanID().String())
+	require.True(t, users.Parent.IsRemote())
+
+	boom := spans[1]
+	require.Equal(t, "GET /boom", boom.Name)
+	require.False(t, boom.Parent.IsValid())
+	require.Equal(t, codes.Error, boom.Status.Code)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	require.Equal(t, users.SpanContext.TraceID().String(), entry["trace_id"])
+	require.Equal(t, users.SpanContext.SpanID().String(), entry["span_id"])
+}
+
+func TestGormPluginSpansStatementsWithoutBoundValues(t *testing.T) {
+	tp, exporter := newTestProvider()
+	conn, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	require.NoError(t, conn.Use(NewGormPlugin(tp)))
+
+	type User struct {
+		ID    uint
+		Email string
+	}
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	var users []User
+	require.NoError(t, conn.WithContext(ctx).Where("email = ?", "jane@example.com").Find(&users).Error)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	query := spans[0]
+	require.Equal(t, "SELECT users", query.Name)
+	require.Equal(t, parent.SpanContext().SpanID(), query.Parent.SpanID())
+	attrs := attribute.NewSet(query.Attributes...)
+	text, _ := attrs.Value(semconv.DBQueryTextKey)
+	require.Contains(t, text.AsString(), "$1")
+	require.NotContains(t, text.AsString(), "jane@example.com")
+}
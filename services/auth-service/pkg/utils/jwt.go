@@ -1,48 +1,157 @@
 package utils
 
 import (
-	"github.com/google/uuid"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/content-management-system/auth-service/pkg/health"
+	"github.com/content-management-system/auth-service/pkg/secrets"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
 )
 
-var jwtKey = []byte("your-secret-key")
+var Module = fx.Provide(NewJWT, health.AsCheck(newSigningKeyCheck))
+
+// minKeyLength is the smallest HS256 key accepted, matching the hash size.
+const minKeyLength = 32
+
+const (
+	TokenUseAccess     = "access"
+	TokenUseRefresh    = "refresh"
+	TokenUseMFASession = "mfa_session"
+)
 
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	TokenUse string    `json:"token_use"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uuid.UUID) (string, error) {
+// JWT signs and validates the service's tokens with the JWT_SECRET key.
+// After a rotation it signs with the new key and still accepts tokens
+// signed with the previous one, so sessions survive the change.
+type JWT struct {
+	log      *logrus.Logger
+	mu       sync.RWMutex
+	key      []byte
+	previous []byte
+}
+
+func NewJWT(store *secrets.Store, log *logrus.Logger) (*JWT, error) {
+	key, err := store.Get(context.Background(), secrets.JWTSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", secrets.JWTSigningKey, err)
+	}
+	if len(key) < minKeyLength {
+		return nil, fmt.Errorf("%s must be at least %d bytes", secrets.JWTSigningKey, minKeyLength)
+	}
+	j := &JWT{log: log, key: []byte(key)}
+	store.Watch(secrets.JWTSigningKey, j.rotate)
+	return j, nil
+}
+
+func (j *JWT) rotate(key string) {
+	if len(key) < minKeyLength {
+		j.log.Errorf("Ignoring rotated %s shorter than %d bytes", secrets.JWTSigningKey, minKeyLength)
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.previous, j.key = j.key, []byte(key)
+}
+
+// newSigningKeyCheck reports whether the signing key is loaded by
+// round-tripping a short-lived token through it.
+func newSigningKeyCheck(j *JWT) health.Check {
+	return health.Check{
+		Name:        "signing_keys",
+		Criticality: health.Critical,
+		Liveness:    true,
+		Run: func(context.Context) error {
+			token, err := j.GenerateToken(uuid.Nil)
+			if err != nil {
+				return err
+			}
+			_, err = j.ValidateToken(token)
+			return err
+		},
+	}
+}
+
+func (j *JWT) sign(claims *Claims) (string, error) {
+	j.mu.RLock()
+	key := j.key
+	j.mu.RUnlock()
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+func (j *JWT) GenerateToken(userID uuid.UUID) (string, error) {
 	expirationTime := time.Now().Add(15 * time.Minute)
 	claims := &Claims{
-		UserID: userID,
+		UserID:   userID,
+		TokenUse: TokenUseAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
+	}
+	return j.sign(claims)
+}
+
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+// GenerateRefreshToken binds the token to a server-side session through
+// its jti claim, so it can be revoked.
+func (j *JWT) GenerateRefreshToken(userID, sessionID uuid.UUID) (string, error) {
+	expirationTime := time.Now().Add(RefreshTokenTTL)
+	claims := &Claims{
+		UserID:   userID,
+		TokenUse: TokenUseRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID.String(),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	return j.sign(claims)
 }
 
-func GenerateRefreshToken(userID uuid.UUID) (string, error) {
-	expirationTime := time.Now().Add(7 * 24 * time.Hour)
+// GenerateMFASessionToken proves that the first factor succeeded and is
+// exchanged, together with the SMS code, for real tokens.
+func (j *JWT) GenerateMFASessionToken(userID uuid.UUID) (string, error) {
+	expirationTime := time.Now().Add(5 * time.Minute)
 	claims := &Claims{
-		UserID: userID,
+		UserID:   userID,
+		TokenUse: TokenUseMFASession,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	return j.sign(claims)
+}
+
+func (j *JWT) ValidateToken(tokenStr string) (*Claims, error) {
+	j.mu.RLock()
+	key, previous := j.key, j.previous
+	j.mu.RUnlock()
+
+	claims, err := parseClaims(tokenStr, key)
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) && previous != nil {
+		claims, err = parseClaims(tokenStr, previous)
+	}
+	return claims, err
 }
 
-func ValidateToken(tokenStr string) (*Claims, error) {
+// parseClaims only accepts HS256, the one algorithm the service signs
+// with, so a token naming another is rejected before the key is used.
+func parseClaims(tokenStr string, key []byte) (*Claims, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil || !token.Valid {
 		return nil, err
 	}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-management-system/auth-service/pkg/secrets"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTokenPinsHS256(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	key := strings.Repeat("k", 32)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, secrets.JWTSigningKey), []byte(key), 0o600))
+	j, err := NewJWT(secrets.NewStore(secrets.NewFileProvider(dir), time.Minute, log), log)
+	require.NoError(t, err)
+
+	userID := uuid.New()
+	token, err := j.GenerateToken(userID)
+	require.NoError(t, err)
+	claims, err := j.ValidateToken(token)
+	require.NoError(t, err)
+	require.Equal(t, userID, claims.UserID)
+
+	claims = &Claims{
+		UserID:           userID,
+		TokenUse:         TokenUseAccess,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(key))
+	require.NoError(t, err)
+	_, err = j.ValidateToken(forged)
+	require.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
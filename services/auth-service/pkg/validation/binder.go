@@ -0,0 +1,115 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/content-management-system/auth-service/pkg/apperror"
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+)
+
+const validationProblemType = "https://cms.local/problems/validation-error"
+
+var Module = fx.Provide(NewBinder)
+
+type Binder struct {
+	validate *validator.Validate
+}
+
+func NewBinder() *Binder {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return &Binder{validate: v}
+}
+
+// Bind parses the request body into out and validates it against its
+// `validate` struct tags.
+func (b *Binder) Bind(c *fiber.Ctx, out interface{}) error {
+	if err := c.BodyParser(out); err != nil {
+		return problem.New(apperror.CodeMalformedRequest, "request body could not be parsed")
+	}
+	return b.Validate(out)
+}
+
+// BindQuery parses the query string into out, using its `query` struct
+// tags, and validates it.
+func (b *Binder) BindQuery(c *fiber.Ctx, out interface{}) error {
+	if err := c.QueryParser(out); err != nil {
+		return problem.New(apperror.CodeMalformedRequest, "query string could not be parsed")
+	}
+	return b.Validate(out)
+}
+
+func (b *Binder) Validate(v interface{}) error {
+	err := b.validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	p := problem.New(apperror.CodeValidationFailed, "request validation failed")
+	p.Type = validationProblemType
+	for _, fe := range validationErrs {
+		p.Errors = append(p.Errors, problem.FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return p
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "jwt":
+		return "must be a valid token"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters long", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters long", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "numeric":
+		return "must contain only digits"
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters long", fe.Param())
+	case "e164":
+		return "must be a phone number in E.164 format, e.g. +14155550100"
+	case "nefield":
+		return "must differ from the current value"
+	case "uuid":
+		return "must be a valid UUID"
+	case "datetime":
+		return "must be an RFC 3339 timestamp, e.g. 2024-01-02T15:04:05Z"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
@@ -0,0 +1,70 @@
+package validation
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-management-system/auth-service/internal/model/dto"
+	"github.com/content-management-system/auth-service/pkg/problem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func setupApp() *fiber.App {
+	binder := NewBinder()
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler(logrus.New())})
+	app.Post("/register", func(c *fiber.Ctx) error {
+		var req dto.RegisterUserDto
+		if err := binder.Bind(c, &req); err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	return app
+}
+
+func TestBindRejectsInvalidFields(t *testing.T) {
+	app := setupApp()
+	req := httptest.NewRequest("POST", "/register", strings.NewReader(`{"username":"jo","email":"not-an-email","password":"secret123"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	require.Equal(t, problem.ContentType, resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(body, &p))
+
+	fields := map[string]string{}
+	for _, fe := range p.Errors {
+		fields[fe.Field] = fe.Rule
+	}
+	require.Equal(t, map[string]string{"username": "min", "email": "email"}, fields)
+}
+
+func TestBindRejectsMalformedBody(t *testing.T) {
+	app := setupApp()
+	req := httptest.NewRequest("POST", "/register", strings.NewReader(`{"username":`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestBindAcceptsValidBody(t *testing.T) {
+	app := setupApp()
+	req := httptest.NewRequest("POST", "/register", strings.NewReader(`{"username":"jane","email":"jane@example.com","password":"Str0ngPass!"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+}